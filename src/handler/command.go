@@ -0,0 +1,220 @@
+// Package handler section
+package handler
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"caos/model"
+	"caos/service"
+)
+
+// Command - Run a headless subcommand, false when the arguments don't request one
+func Command(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	defer service.SaveSessionIndex()
+
+	switch args[0] {
+	case "search":
+		flags := flag.NewFlagSet("search", flag.ExitOnError)
+		limit := flags.Int("limit", 10, "Maximum amount of results")
+		flags.Parse(args[1:])
+
+		query := strings.Join(flags.Args(), " ")
+		if strings.TrimSpace(query) == "" {
+			fmt.Fprintln(os.Stderr, "usage: caos search [-limit N] <query>")
+			os.Exit(2)
+		}
+
+		fmt.Print(service.FormatSearchResults(service.SearchSessions(query, *limit)))
+		return true
+	case "batch":
+		flags := flag.NewFlagSet("batch", flag.ExitOnError)
+		workers := flags.Int("workers", service.DefaultBatchWorkers, "Maximum amount of prompts sent at the same time")
+		out := flags.String("out", "", "Results file in JSONL format, next to the batch file by default")
+		flags.Parse(args[1:])
+
+		if flags.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "usage: caos batch [-workers N] [-out results.jsonl] <prompts.csv|prompts.jsonl>")
+			os.Exit(2)
+		}
+
+		var failed int
+		total, err := service.RunBatch(flags.Arg(0), *out, *workers, func(result model.BatchResult) {
+			if result.Error != "" {
+				failed++
+				fmt.Fprintf(os.Stderr, "row %v: %v\n", result.Row, result.Error)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "row %v: %v tokens\n", result.Row, result.Usage.TotalTokens)
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(1)
+		}
+
+		fmt.Printf("%v prompts sent, %v failed\n", total, failed)
+		if failed > 0 {
+			exit(1)
+		}
+		return true
+	case "serve":
+		flags := flag.NewFlagSet("serve", flag.ExitOnError)
+		address := flags.String("addr", service.DefaultServerAddress, "Address of the API server")
+		flags.Parse(args[1:])
+
+		fmt.Fprintf(os.Stderr, "caos API listening on http://%v\n", *address)
+		if err := service.Serve(*address); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(1)
+		}
+		return true
+	case "proxy":
+		flags := flag.NewFlagSet("proxy", flag.ExitOnError)
+		address := flags.String("addr", service.DefaultProxyAddress, "Address of the OpenAI compatible proxy")
+		flags.Parse(args[1:])
+
+		fmt.Fprintf(os.Stderr, "caos proxy listening on http://%v/v1\n", *address)
+		if err := service.ServeProxy(*address); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(1)
+		}
+		return true
+	case "rpc":
+		if err := service.ServeRPC(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(1)
+		}
+		return true
+	case "ask", "chat", "edit", "embed", "predict", "models":
+		if err := headless(args[0], args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(1)
+		}
+		return true
+	}
+	return false
+}
+
+// exit - Store the pending sessions of the search index and exit with the code
+func exit(code int) {
+	service.SaveSessionIndex()
+	os.Exit(code)
+}
+
+// headless - Send the request of the subcommand with the arguments or the standard input and write the answer to the standard output
+func headless(name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	engine := flags.String("engine", "", "Engine of the request, the profile engine by default")
+	template := flags.String("template", "", "Template of the request by name")
+	variables := templateVariables{}
+	flags.Var(variables, "var", "Template placeholder as name=value, can be repeated")
+	role := flags.String("role", "", "Role of the prompt: user, assistant or system")
+	flags.Float64("temperature", 0, "Sampling temperature")
+	flags.Float64("topp", 0, "Nucleus sampling probability")
+	flags.Float64("penalty", 0, "Presence penalty")
+	flags.Float64("frequency", 0, "Frequency penalty")
+	results := flags.Int("n", 0, "Amount of choices")
+	tokens := flags.Int("max-tokens", 0, "Maximum amount of tokens of the answer")
+	stream := flags.Bool("stream", true, "Write the answer as it arrives")
+	instruction := flags.String("instruction", "", "Instruction of the edit")
+	resume := flags.String("resume", "", "Log file of the conversation to continue, RESUME_SESSION by default")
+	flags.Parse(args)
+
+	options := service.HeadlessOptions{
+		Engine:      *engine,
+		Template:    *template,
+		Variables:   variables,
+		Role:        *role,
+		Results:     *results,
+		MaxTokens:   *tokens,
+		IsStreaming: *stream,
+		Resume:      *resume,
+	}
+	// Only the sampling parameters set in the command replace the profile ones
+	flags.Visit(func(i *flag.Flag) {
+		value, _ := strconv.ParseFloat(i.Value.String(), 32)
+		parameter := float32(value)
+		switch i.Name {
+		case "temperature":
+			options.Temperature = &parameter
+		case "topp":
+			options.TopP = &parameter
+		case "penalty":
+			options.Penalty = &parameter
+		case "frequency":
+			options.Frequency = &parameter
+		}
+	})
+
+	agent := service.AttachHeadlessProfile()
+	if options.Resume == "" {
+		options.Resume = agent.GetStatus().ResumePath
+	}
+
+	client, err := service.NewHeadless(agent, options, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "models":
+		return client.Models()
+	case "chat":
+		if flags.NArg() > 0 {
+			return client.Chat(strings.NewReader(strings.Join(flags.Args(), " ")))
+		}
+		return client.Chat(os.Stdin)
+	}
+
+	// The arguments are the prompt, the standard input is read when there aren't any
+	input := strings.Join(flags.Args(), " ")
+	if input == "" {
+		raw, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		input = strings.TrimSpace(string(raw))
+	}
+	if input == "" {
+		return fmt.Errorf("usage: caos %v [flags] <prompt>, or the prompt through the standard input", name)
+	}
+
+	switch name {
+	case "edit":
+		return client.Edit(input, *instruction)
+	case "embed":
+		return client.Embed(input)
+	case "predict":
+		return client.Predict(input)
+	}
+	return client.Ask(input)
+}
+
+// templateVariables - Repeated name=value flag of the template placeholders
+type templateVariables map[string]string
+
+// String - Placeholders of the flag
+func (c templateVariables) String() string {
+	var out []string
+	for i, j := range c {
+		out = append(out, fmt.Sprint(i, "=", j))
+	}
+	return strings.Join(out, ",")
+}
+
+// Set - Add the placeholder value of the flag
+func (c templateVariables) Set(value string) error {
+	name, text, isValid := strings.Cut(value, "=")
+	if !isValid || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("use name=value for the template variable: %v", value)
+	}
+	c[strings.TrimSpace(name)] = text
+	return nil
+}
@@ -2,11 +2,17 @@
 package main
 
 import (
+	"os"
+
 	"caos/handler"
 )
 
 // Main
 func main() {
+	// Headless subcommands
+	if handler.Command(os.Args[1:]) {
+		return
+	}
 	// Use the service requester interface to initialize node component
 	var hn handler.ServiceRequester = &handler.Node
 	hn.Init()
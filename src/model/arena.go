@@ -0,0 +1,38 @@
+// Package model section
+package model
+
+// ArenaVote - Vote of an arena match
+type ArenaVote string
+
+// Arena votes
+const (
+	VoteA   ArenaVote = "a"
+	VoteB   ArenaVote = "b"
+	VoteTie ArenaVote = "tie"
+)
+
+// ArenaMatch - Anonymous answers of two engines to the same prompt, template and context
+type ArenaMatch struct {
+	ID        string    `json:"id"`
+	Timestamp string    `json:"timestamp"`
+	Template  string    `json:"template"`
+	Prompt    string    `json:"prompt"`
+	Models    []string  `json:"models"`
+	Answers   []string  `json:"answers"`
+	Vote      ArenaVote `json:"vote,omitempty"`
+}
+
+// ArenaRating - Elo rating of an engine for a template
+type ArenaRating struct {
+	Model  string  `json:"model"`
+	Rating float64 `json:"rating"`
+	Wins   int     `json:"wins"`
+	Losses int     `json:"losses"`
+	Ties   int     `json:"ties"`
+}
+
+// ArenaLeaderboard - Ratings of the engines per template and the voted matches
+type ArenaLeaderboard struct {
+	Ratings map[string][]ArenaRating `json:"ratings"`
+	Matches []ArenaMatch             `json:"matches"`
+}
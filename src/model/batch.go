@@ -0,0 +1,31 @@
+// Package model section
+package model
+
+// BatchRow - Prompt of a batch file with its optional template, engine and temperature
+type BatchRow struct {
+	Row         int      `json:"row"`
+	Prompt      string   `json:"prompt"`
+	Template    string   `json:"template,omitempty"`
+	Engine      string   `json:"engine,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+}
+
+// BatchUsage - Tokens used by the requests of a batch row
+type BatchUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// BatchResult - Completions of a batch row
+type BatchResult struct {
+	Row        int        `json:"row"`
+	ID         string     `json:"id,omitempty"`
+	Prompt     string     `json:"prompt"`
+	Template   string     `json:"template,omitempty"`
+	Engine     string     `json:"engine"`
+	Completion []string   `json:"completion,omitempty"`
+	Usage      BatchUsage `json:"usage"`
+	Error      string     `json:"error,omitempty"`
+	Timestamp  string     `json:"timestamp"`
+}
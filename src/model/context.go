@@ -0,0 +1,14 @@
+// Package model section
+package model
+
+// ContextBudget - Token breakdown of a request fitted in the model context window
+type ContextBudget struct {
+	Limit          int `json:"limit"`
+	Completion     int `json:"completion"`
+	System         int `json:"system"`
+	History        int `json:"history"`
+	Prompt         int `json:"prompt"`
+	Context        int `json:"context"`
+	DroppedTurns   int `json:"dropped_turns"`
+	DroppedContext int `json:"dropped_context"`
+}
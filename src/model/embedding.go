@@ -0,0 +1,19 @@
+// Package model section
+package model
+
+// EmbeddingRecord - Embedded prompt and completion pair of a stored session
+type EmbeddingRecord struct {
+	ID         string    `json:"id"`
+	Path       string    `json:"path"`
+	SessionID  string    `json:"session_id"`
+	Timestamp  string    `json:"timestamp"`
+	Prompt     string    `json:"prompt"`
+	Completion string    `json:"completion"`
+	Vector     []float64 `json:"vector"`
+}
+
+// SimilarResult - Stored session close to a query by cosine similarity
+type SimilarResult struct {
+	Record EmbeddingRecord `json:"record"`
+	Score  float64         `json:"score"`
+}
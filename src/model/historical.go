@@ -11,6 +11,8 @@ type HistoricalPrompt struct {
 
 // HistoricalEvent - Session historical event
 type HistoricalEvent struct {
+	ID        string           `json:"turn_id,omitempty"`
+	ParentID  string           `json:"parent_id,omitempty"`
 	Timestamp string           `json:"timestamp"`
 	Event     HistoricalPrompt `json:"event"`
 }
@@ -19,4 +21,6 @@ type HistoricalEvent struct {
 type HistoricalSession struct {
 	ID      string            `json:"id"`
 	Session []HistoricalEvent `json:"session"`
+	Summary string            `json:"summary,omitempty"`
+	Name    string            `json:"name,omitempty"`
 }
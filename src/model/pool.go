@@ -9,4 +9,7 @@ type PoolProperties struct {
 	Session         []HistoricalSession `json:"sessions"`
 	TrainingEvent   []TrainingEvent     `json:"training_events"`
 	TrainingSession []TrainingSession   `json:"training_sessions"`
+	Turns           []TurnEvent         `json:"turns"`
+	CurrentTurn     string              `json:"current_turn"`
+	Ratings         []ChoiceRating      `json:"ratings,omitempty"`
 }
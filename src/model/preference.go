@@ -0,0 +1,19 @@
+// Package model section
+package model
+
+// ChoiceRating - Best and worst picks between the choices of a prompt, -1 when it isn't picked
+type ChoiceRating struct {
+	ID        string   `json:"id"`
+	Timestamp string   `json:"timestamp"`
+	Prompt    []string `json:"prompt"`
+	Choices   []string `json:"choices"`
+	Best      int      `json:"best"`
+	Worst     int      `json:"worst"`
+}
+
+// PreferencePair - Chosen and rejected completions of a prompt for preference tuning
+type PreferencePair struct {
+	Prompt   string `json:"prompt"`
+	Chosen   string `json:"chosen"`
+	Rejected string `json:"rejected"`
+}
@@ -0,0 +1,30 @@
+// Package model section
+package model
+
+// ResearchStep - Action taken by the research agent
+type ResearchStep struct {
+	Step    int    `json:"step"`
+	Action  string `json:"action"`
+	Thought string `json:"thought,omitempty"`
+	Input   string `json:"input,omitempty"`
+	Result  string `json:"result,omitempty"`
+	Tokens  int    `json:"tokens"`
+}
+
+// ResearchSource - Url found by the research agent
+type ResearchSource struct {
+	ID      int    `json:"id"`
+	URL     string `json:"url"`
+	Read    bool   `json:"read"`
+	Excerpt string `json:"excerpt,omitempty"`
+}
+
+// ResearchReport - Final answer of the research agent with its steps and citations
+type ResearchReport struct {
+	Question string           `json:"question"`
+	Report   string           `json:"report"`
+	Steps    []ResearchStep   `json:"steps"`
+	Sources  []ResearchSource `json:"sources"`
+	Tokens   int              `json:"tokens"`
+	Stopped  string           `json:"stopped,omitempty"`
+}
@@ -0,0 +1,47 @@
+// Package model section
+package model
+
+import "encoding/json"
+
+// RPCRequest - JSON-RPC 2.0 request, requests without id are notifications
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// RPCResponse - JSON-RPC 2.0 response with a result or an error
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCNotification - JSON-RPC 2.0 notification sent while a request runs
+type RPCNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// RPCError - JSON-RPC 2.0 error
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// RPCTemplate - Template available for the prompts with its placeholders
+type RPCTemplate struct {
+	Name      string            `json:"name"`
+	Context   string            `json:"context"`
+	Variables []string          `json:"variables,omitempty"`
+	Defaults  map[string]string `json:"defaults,omitempty"`
+}
+
+// RPCToken - Streamed token of a prompt request
+type RPCToken struct {
+	ID   json.RawMessage `json:"id"`
+	Text string          `json:"text"`
+}
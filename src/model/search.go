@@ -0,0 +1,20 @@
+// Package model section
+package model
+
+// SearchDocument - Indexed prompt and completion of a stored session
+type SearchDocument struct {
+	ID        string `json:"id"`
+	Path      string `json:"path"`
+	SessionID string `json:"session_id"`
+	Timestamp string `json:"timestamp"`
+	Kind      string `json:"kind"`
+	Text      string `json:"text"`
+	Length    int    `json:"length"`
+}
+
+// SearchResult - Ranked document with the matching snippet
+type SearchResult struct {
+	Document SearchDocument `json:"document"`
+	Score    float64        `json:"score"`
+	Snippet  string         `json:"snippet"`
+}
@@ -0,0 +1,32 @@
+// Package model section
+package model
+
+// ServerSession - Conversation opened in the API server
+type ServerSession struct {
+	ID       string `json:"id"`
+	Engine   string `json:"engine"`
+	Template string `json:"template,omitempty"`
+	Turns    int    `json:"turns"`
+}
+
+// PromptRequest - Prompt sent to a session of the API server, the chat mode is used by default
+type PromptRequest struct {
+	Mode        string `json:"mode,omitempty"`
+	Prompt      string `json:"prompt"`
+	Instruction string `json:"instruction,omitempty"`
+	Stream      bool   `json:"stream,omitempty"`
+}
+
+// PromptResponse - Answer of a session of the API server
+type PromptResponse struct {
+	Session   string           `json:"session,omitempty"`
+	Mode      string           `json:"mode"`
+	Choices   []string         `json:"choices,omitempty"`
+	Embedding [][]float64      `json:"embedding,omitempty"`
+	Predict   *PredictResponse `json:"predict,omitempty"`
+}
+
+// ServerError - Error of an API server request
+type ServerError struct {
+	Error string `json:"error"`
+}
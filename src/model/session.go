@@ -0,0 +1,14 @@
+// Package model section
+package model
+
+// StoredSession - Conversation stored in the log directory
+type StoredSession struct {
+	Name      string   `json:"name"`
+	Path      string   `json:"path"`
+	Files     []string `json:"files"`
+	Timestamp string   `json:"timestamp"`
+	Model     string   `json:"model"`
+	Template  string   `json:"template"`
+	Prompt    string   `json:"prompt"`
+	Turns     int      `json:"turns"`
+}
@@ -3,8 +3,14 @@ package model
 
 // TemplateProperties - Contextual template properties
 type TemplateProperties struct {
+	// Template
+	Name string `json:"name,omitempty"`
 	// Input
 	Input []string `json:"input"`
+	// Filled placeholders
+	Variables map[string]string `json:"variables,omitempty"`
 	// Prompt stages
 	PromptValidated ChainPrompt `json:"validation"`
+	// Tool calls
+	Tools []ToolCall `json:"tools,omitempty"`
 }
@@ -0,0 +1,10 @@
+// Package model section
+package model
+
+// ToolCall - Tool requested by the model with its result
+type ToolCall struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+	Result    string            `json:"result"`
+	Error     string            `json:"error,omitempty"`
+}
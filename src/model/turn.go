@@ -0,0 +1,9 @@
+// Package model section
+package model
+
+// TurnEvent - Conversation turn, linked to the previous turn by the parent id of the event
+type TurnEvent struct {
+	SessionID string          `json:"session_id"`
+	Event     HistoricalEvent `json:"event"`
+	Training  TrainingEvent   `json:"training"`
+}
@@ -0,0 +1,21 @@
+// Package model section
+package model
+
+import (
+	"time"
+)
+
+// VectorDocument - Text with its embedding vector and metadata
+type VectorDocument struct {
+	ID       string            `json:"id"`
+	Text     string            `json:"text"`
+	Vector   []float64         `json:"vector"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Date     time.Time         `json:"date"`
+}
+
+// VectorResult - Document ranked by cosine similarity
+type VectorResult struct {
+	Document VectorDocument `json:"document"`
+	Score    float64        `json:"score"`
+}
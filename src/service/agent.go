@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/PullRequestInc/go-gpt3"
 	"github.com/joho/godotenv"
@@ -26,13 +27,16 @@ type Agent struct {
 	// Assistant context
 	templateID  []string
 	templateCtx []string
+	// Template variables
+	variables map[string]string
 	// Chained event
 	transformers []Chain
 	// Context
 	ctx context.Context
 	// Client
-	client   *gpt3.Client
-	exClient *http.Client
+	client    Provider
+	exClient  *http.Client
+	clientURL string
 	// Properties
 	EngineProperties   model.EngineProperties
 	PromptProperties   model.PromptProperties
@@ -42,6 +46,9 @@ type Agent struct {
 	preferences parameters.GlobalPreferences
 	// Temporal cache
 	cachedPrompt string
+	// Conversation history
+	messages []gpt3.ChatCompletionRequestMessage
+	summary  string
 }
 
 // Initialize - Creates context background to be used along with the client
@@ -52,6 +59,21 @@ func (c *Agent) Initialize() Agent {
 	c.key = getKeys()
 	// template
 	c.templateID, c.templateCtx = getTemplateFromLocal()
+	// Backend
+	c.preferences.BaseURL = getVariable("BASE_URL")
+	c.preferences.ReplayPath = getVariable("REPLAY_PATH")
+	c.preferences.ResumePath = getVariable("RESUME_SESSION")
+	c.preferences.KnowledgePath = getVariable("KNOWLEDGE_PATH")
+	c.preferences.OpenAIURL = getEndpoint("OPENAI_BASE_URL", parameters.OpenAIBaseURL)
+	c.preferences.PredictURL = getEndpoint("ZERO_BASE_URL", parameters.PredictBaseURL)
+	c.preferences.SearchURL = getEndpoint("SEARCH_BASE_URL", parameters.ExternalSearchBaseURL)
+	c.preferences.ContextLimit = int(util.ParseInt32(getVariable("CONTEXT_LIMIT")))
+	c.preferences.SummaryThreshold = 6
+	c.preferences.EmbeddingModel = getEndpoint("EMBEDDING_MODEL", parameters.DefaultEmbeddingModel)
+	c.preferences.SchemaPath = getVariable("SCHEMA_PATH")
+	c.preferences.SchemaRetries = int(util.ParseInt32(getEndpoint("SCHEMA_RETRIES", fmt.Sprint(parameters.DefaultSchemaRetries))))
+	c.preferences.ResearchSteps = int(util.ParseInt32(getEndpoint("RESEARCH_STEPS", fmt.Sprint(parameters.DefaultResearchSteps))))
+	c.preferences.ResearchBudget = int(util.ParseInt32(getEndpoint("RESEARCH_BUDGET", fmt.Sprint(parameters.DefaultResearchBudget))))
 	// Background context
 	c.ctx = context.Background()
 	c.client, c.exClient = c.Connect()
@@ -87,7 +109,7 @@ func (c *Agent) Initialize() Agent {
 }
 
 // Connect - Contextualize the API to create a new client
-func (c *Agent) Connect() (*gpt3.Client, *http.Client) {
+func (c *Agent) Connect() (Provider, *http.Client) {
 	godotenv.Load()
 
 	transport := http.Transport{
@@ -100,15 +122,33 @@ func (c *Agent) Connect() (*gpt3.Client, *http.Client) {
 		Transport: &transport,
 	}
 
-	option := gpt3.WithHTTPClient(&externalClient)
-	client := gpt3.NewClient(c.key[0], option)
-
-	c.client = &client
+	if c.preferences.ReplayPath != "" {
+		c.client = NewReplayProvider(c.preferences.ReplayPath)
+	} else if c.preferences.BaseURL != "" {
+		c.client = NewCompatibleProvider(c.preferences.BaseURL, c.key[0], &externalClient)
+	} else {
+		provider := NewOpenAIProvider(c.key[0],
+			gpt3.WithHTTPClient(&externalClient),
+			gpt3.WithBaseURL(c.preferences.OpenAIURL))
+		provider.forwarder = NewCompatibleProvider(c.preferences.OpenAIURL, c.key[0], &externalClient)
+		c.client = provider
+	}
 	c.exClient = &externalClient
+	c.clientURL = c.preferences.BaseURL
 
 	return c.client, c.exClient
 }
 
+// SetProvider - Replace the backend used by the agent
+func (c *Agent) SetProvider(provider Provider) {
+	c.client = provider
+}
+
+// GetProvider - Current backend used by the agent
+func (c *Agent) GetProvider() Provider {
+	return c.client
+}
+
 // GetStatus - Current agent information
 func (c *Agent) GetStatus() parameters.GlobalPreferences {
 	return c.preferences
@@ -130,6 +170,36 @@ func getKeys() []string {
 	return getKeyFromInternal()
 }
 
+// getVariable - Grab a backend variable from the environment or the local .env file
+func getVariable(name string) string {
+	value := os.Getenv(name)
+	if value != "" {
+		return value
+	}
+
+	dir, _ := os.Getwd()
+	path := fmt.Sprintf("%v/.env", dir)
+
+	file, _ := os.Stat(path)
+	if file != nil {
+		viper.SetConfigFile(path)
+		if err := viper.ReadInConfig(); err == nil {
+			value, _ = viper.Get(name).(string)
+		}
+	}
+
+	return value
+}
+
+// getEndpoint - Grab an endpoint override or the default endpoint
+func getEndpoint(name string, endpoint string) string {
+	value := getVariable(name)
+	if value != "" {
+		return value
+	}
+	return endpoint
+}
+
 // getKeyFromEnv - Get environment keys
 func getKeyFromEnv() []string {
 	var keys []string
@@ -254,21 +324,216 @@ func (c *Agent) SetTemplateParameters(promptContext []string) model.TemplateProp
 	properties := model.TemplateProperties{
 		Input: promptContext,
 	}
+
+	if c.preferences.Template < len(c.templateID) {
+		properties.Name = c.templateID[c.preferences.Template]
+	}
+	properties.Variables = c.getTemplateValues()
 	return properties
 }
 
 // SetTemplate - Conversion human-ai roles
 func (c *Agent) SetTemplate(context string, input string) []string {
-	if context == "" && len(c.templateCtx) > 0 {
-		context = c.templateCtx[c.preferences.Template]
+	if context == "" {
+		context = c.getTemplate()
 	}
 
 	prompt := []string{context + input}
 	return prompt
 }
 
+// GetTemplateVariables - Placeholders of the selected template with their defaults
+func (c *Agent) GetTemplateVariables() ([]string, map[string]string) {
+	if c.preferences.Template >= len(c.templateCtx) {
+		return nil, map[string]string{}
+	}
+	return util.GetTemplateVariables(c.templateCtx[c.preferences.Template])
+}
+
+// SetTemplateVariables - Values of the selected template placeholders, the missing ones take the default
+func (c *Agent) SetTemplateVariables(values map[string]string) {
+	// Copies of the agent don't share the values
+	c.variables = make(map[string]string)
+	for i, j := range values {
+		c.variables[i] = j
+	}
+}
+
+// getTemplate - Selected template with its placeholders filled
+func (c *Agent) getTemplate() string {
+	if c.preferences.Template >= len(c.templateCtx) {
+		return ""
+	}
+	return util.FillTemplate(c.templateCtx[c.preferences.Template], c.variables)
+}
+
+// getTemplateValues - Values of the selected template placeholders, nil when it doesn't have any
+func (c *Agent) getTemplateValues() map[string]string {
+	names, values := c.GetTemplateVariables()
+	if len(names) == 0 {
+		return nil
+	}
+
+	for _, i := range names {
+		if value := strings.TrimSpace(c.variables[i]); value != "" {
+			values[i] = value
+		}
+	}
+	return values
+}
+
+// summaryHeader - Introduction of the running summary message
+const summaryHeader = "Summary of the previous conversation:\n"
+
+// SetMessages - Conversation history followed by the current prompt
+func (c *Agent) SetMessages(prompt string) []gpt3.ChatCompletionRequestMessage {
+	messages := c.getHistory()
+	messages = append(messages, gpt3.ChatCompletionRequestMessage{
+		Role:    string(c.preferences.Role),
+		Content: prompt,
+	})
+	return messages
+}
+
+// AppendMessages - Add an exchange to the conversation history
+func (c *Agent) AppendMessages(input string, completion string) {
+	if len(c.messages) == 0 {
+		c.messages = append(c.messages, c.getSystemMessage()...)
+	}
+
+	c.messages = append(c.messages,
+		gpt3.ChatCompletionRequestMessage{
+			Role:    string(c.preferences.Role),
+			Content: input,
+		},
+		gpt3.ChatCompletionRequestMessage{
+			Role:    string(model.Assistant),
+			Content: completion,
+		})
+}
+
+// GetMessages - Current conversation history
+func (c *Agent) GetMessages() []gpt3.ChatCompletionRequestMessage {
+	return c.messages
+}
+
+// ClearMessages - Reset the conversation history
+func (c *Agent) ClearMessages() {
+	c.messages = nil
+	c.summary = ""
+}
+
+// GetSummary - Running summary of the summarized turns
+func (c *Agent) GetSummary() string {
+	return c.summary
+}
+
+// SetSummaryPreferences - Amount of exchanges kept before summarizing and the model used to summarize
+func (c *Agent) SetSummaryPreferences(threshold int, engine string) {
+	c.preferences.SummaryThreshold = threshold
+	c.preferences.SummaryModel = engine
+}
+
+// ExpiredMessages - Oldest turns to summarize once the conversation exceeds the summary threshold
+func (c *Agent) ExpiredMessages() []gpt3.ChatCompletionRequestMessage {
+	threshold := c.preferences.SummaryThreshold
+	turns := c.getTurns()
+	if threshold <= 0 || len(turns) <= threshold*2 {
+		return nil
+	}
+
+	// Half of the exchanges are kept verbatim
+	keep := threshold / 2 * 2
+	return turns[:len(turns)-keep]
+}
+
+// SummarizeMessages - Replace the expired turns with the running summary
+func (c *Agent) SummarizeMessages(summary string, expired int) {
+	turns := c.getTurns()
+	if expired > len(turns) {
+		expired = len(turns)
+	}
+
+	c.summary = summary
+	c.messages = append(c.getSystemMessage(), gpt3.ChatCompletionRequestMessage{
+		Role:    string(model.System),
+		Content: fmt.Sprint(summaryHeader, summary),
+	})
+	c.messages = append(c.messages, turns[expired:]...)
+}
+
+// getTurns - Conversation turns after the system and summary messages
+func (c *Agent) getTurns() []gpt3.ChatCompletionRequestMessage {
+	turns := c.messages
+	for len(turns) > 0 && turns[0].Role == string(model.System) {
+		turns = turns[1:]
+	}
+	return turns
+}
+
+// getHistory - Conversation history starting with the system message
+func (c *Agent) getHistory() []gpt3.ChatCompletionRequestMessage {
+	var messages []gpt3.ChatCompletionRequestMessage
+	if len(c.messages) == 0 {
+		messages = append(messages, c.getSystemMessage()...)
+	}
+	return append(messages, c.messages...)
+}
+
+// getSystemMessage - Selected template as the system message of the conversation
+func (c *Agent) getSystemMessage() []gpt3.ChatCompletionRequestMessage {
+	template := c.getTemplate()
+	if template == "" {
+		return nil
+	}
+
+	return []gpt3.ChatCompletionRequestMessage{
+		{
+			Role:    string(model.System),
+			Content: template,
+		},
+	}
+}
+
+// isolateClient - Own copy of the external client, the chain sets its cookie jar
+func (c *Agent) isolateClient() {
+	if c.exClient != nil {
+		client := *c.exClient
+		c.exClient = &client
+	}
+}
+
+// applyTemplate - Select the template and variables of the source, replacing the system message of the conversation
+func (c *Agent) applyTemplate(source Agent) {
+	turns := c.messages
+	if previous := c.getSystemMessage(); len(previous) > 0 && len(turns) > 0 &&
+		turns[0].Role == previous[0].Role && turns[0].Content == previous[0].Content {
+		turns = turns[1:]
+	}
+
+	c.preferences.Template, c.variables = source.preferences.Template, source.variables
+	if len(c.messages) > 0 {
+		c.messages = append(c.getSystemMessage(), turns...)
+	}
+}
+
 // SetContext - Chained trasformer events
 func (c *Agent) SetContext(prompt *model.PromptProperties) ([]string, []string) {
+	// Replayed sessions run without network
+	if c.preferences.ReplayPath != "" {
+		return nil, nil
+	}
+
+	// Local documents replace the web results
+	if c.preferences.KnowledgePath != "" {
+		sources, context, err := GetKnowledgeContext(*c, prompt.Input[0])
+		if err != nil {
+			var event EventManager
+			event.Errata(err)
+		}
+		return sources, context
+	}
+
 	var chain Chain
 	chain.ExecuteChainJob(*c, prompt)
 	return chain.Transform.Source, chain.Transform.Context
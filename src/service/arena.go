@@ -0,0 +1,291 @@
+// Package service section
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"caos/model"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// arenaPath - Persisted leaderboard of the arena
+var arenaPath = filepath.Join("arena", "leaderboard.json")
+
+// Elo parameters
+const (
+	arenaRating = 1000
+	arenaFactor = 32
+)
+
+// Arena errors
+var (
+	errArenaModels = errors.New("arena: select two different engines")
+	errArenaEmpty  = errors.New("arena: the engine didn't answer")
+	errArenaVote   = errors.New("arena: unknown vote")
+	errArenaVoted  = errors.New("arena: the match was already voted")
+)
+
+// arenaExcluded - Engines that can't answer a prompt
+var arenaExcluded = []string{"embedding", "edit", "search", "similarity", "zero", "whisper", "tts", "dall-e", "moderation"}
+
+// Arena - Elo leaderboard of the engines per template persisted in a JSON file
+type Arena struct {
+	path  string
+	board model.ArenaLeaderboard
+}
+
+// OpenArena - Load the leaderboard, empty when the file doesn't exist
+func OpenArena(path string) (*Arena, error) {
+	arena := &Arena{
+		path:  path,
+		board: model.ArenaLeaderboard{Ratings: make(map[string][]model.ArenaRating)},
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return arena, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(raw, &arena.board); err != nil {
+		return nil, err
+	}
+	if arena.board.Ratings == nil {
+		arena.board.Ratings = make(map[string][]model.ArenaRating)
+	}
+	return arena, nil
+}
+
+// Save - Persist the leaderboard replacing the previous file
+func (c *Arena) Save() error {
+	raw, err := json.MarshalIndent(c.board, "", "\u0009")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// getRating - Rating of the engine for the template, created with the initial rating
+func (c *Arena) getRating(template string, engine string) *model.ArenaRating {
+	ratings := c.board.Ratings[template]
+	for i := range ratings {
+		if ratings[i].Model == engine {
+			return &ratings[i]
+		}
+	}
+
+	c.board.Ratings[template] = append(ratings, model.ArenaRating{Model: engine, Rating: arenaRating})
+	return &c.board.Ratings[template][len(c.board.Ratings[template])-1]
+}
+
+// Vote - Record the vote of the match and update the Elo ratings of both engines
+func (c *Arena) Vote(match *model.ArenaMatch, vote model.ArenaVote) error {
+	if match.Vote != "" {
+		return errArenaVoted
+	}
+
+	var score float64
+	switch vote {
+	case model.VoteA:
+		score = 1
+	case model.VoteB:
+		score = 0
+	case model.VoteTie:
+		score = 0.5
+	default:
+		return fmt.Errorf("%w: %v", errArenaVote, vote)
+	}
+
+	if len(match.Models) != 2 || match.Models[0] == match.Models[1] {
+		return errArenaModels
+	}
+
+	// Both ratings are read before the update
+	a := *c.getRating(match.Template, match.Models[0])
+	b := *c.getRating(match.Template, match.Models[1])
+	expected := 1 / (1 + math.Pow(10, (b.Rating-a.Rating)/400))
+
+	a.Rating += arenaFactor * (score - expected)
+	b.Rating -= arenaFactor * (score - expected)
+	switch vote {
+	case model.VoteA:
+		a.Wins++
+		b.Losses++
+	case model.VoteB:
+		a.Losses++
+		b.Wins++
+	case model.VoteTie:
+		a.Ties++
+		b.Ties++
+	}
+
+	*c.getRating(match.Template, match.Models[0]) = a
+	*c.getRating(match.Template, match.Models[1]) = b
+
+	match.Vote = vote
+	c.board.Matches = append(c.board.Matches, *match)
+	return nil
+}
+
+// Leaderboard - Ratings of the template from the highest
+func (c *Arena) Leaderboard(template string) []model.ArenaRating {
+	ratings := append([]model.ArenaRating(nil), c.board.Ratings[template]...)
+	sort.SliceStable(ratings, func(i, j int) bool {
+		return ratings[i].Rating > ratings[j].Rating
+	})
+	return ratings
+}
+
+// GetArenaModels - Engines of the list that can answer an arena prompt
+func GetArenaModels(models []string) []string {
+	var available []string
+	for _, i := range models {
+		isExcluded := false
+		for _, j := range arenaExcluded {
+			isExcluded = isExcluded || strings.Contains(i, j)
+		}
+		if !isExcluded {
+			available = append(available, i)
+		}
+	}
+	return available
+}
+
+// isChatModel - Engines served by the chat endpoint, compatible backends are chat only
+func isChatModel(service Agent, engine string) bool {
+	switch {
+	case strings.Contains(engine, "instruct"):
+		return false
+	case strings.Contains(engine, "turbo") || strings.Contains(engine, "gpt-4"):
+		return true
+	case strings.Contains(engine, "text") || strings.Contains(engine, "davinci") || strings.Contains(engine, "curie") ||
+		strings.Contains(engine, "babbage") || strings.Contains(engine, "ada") || strings.Contains(engine, "code"):
+		return false
+	}
+	return service.preferences.BaseURL != "" || service.preferences.ReplayPath != ""
+}
+
+// NewArenaMatch - Send the prompt with the same template and context to both engines in a random order
+func NewArenaMatch(service Agent, prompt string, models []string) (model.ArenaMatch, error) {
+	if len(models) != 2 || models[0] == models[1] {
+		return model.ArenaMatch{}, errArenaModels
+	}
+
+	match := model.ArenaMatch{
+		ID:        fmt.Sprint("arena-", time.Now().UnixMilli()),
+		Timestamp: fmt.Sprint(time.Now().UnixMilli()),
+		Template:  service.SetTemplateParameters(nil).Name,
+		Prompt:    prompt,
+		Models:    []string{models[0], models[1]},
+	}
+	// The engines are anonymous until the vote
+	if rand.Intn(2) == 1 {
+		match.Models[0], match.Models[1] = match.Models[1], match.Models[0]
+	}
+
+	// The context is retrieved once for both engines
+	urls, ctx := service.SetContext(&model.PromptProperties{Input: []string{prompt}})
+	content := setChatPrompt(prompt, ctx, urls)
+	if service.preferences.KnowledgePath != "" {
+		content = setKnowledgePrompt(prompt, ctx, urls)
+	}
+
+	for _, i := range match.Models {
+		answer, err := getArenaAnswer(service, i, content)
+		if err != nil {
+			return match, fmt.Errorf("%v: %w", i, err)
+		}
+		match.Answers = append(match.Answers, strings.TrimSpace(answer))
+	}
+	return match, nil
+}
+
+// getArenaAnswer - Answer of the engine to the composed prompt with the selected template
+func getArenaAnswer(service Agent, engine string, content string) (string, error) {
+	if !isChatModel(service, engine) {
+		resp, err := service.client.Completion(service.ctx, engine, gpt3.CompletionRequest{
+			Prompt:           service.SetTemplate("", content),
+			MaxTokens:        gpt3.IntPtr(service.preferences.MaxTokens),
+			Temperature:      gpt3.Float32Ptr(service.EngineProperties.Temperature),
+			TopP:             gpt3.Float32Ptr(service.EngineProperties.TopP),
+			PresencePenalty:  *gpt3.Float32Ptr(service.EngineProperties.PresencePenalty),
+			FrequencyPenalty: *gpt3.Float32Ptr(service.EngineProperties.FrequencyPenalty),
+		})
+		if err != nil {
+			return "", err
+		}
+		if resp == nil || len(resp.Choices) == 0 {
+			return "", errArenaEmpty
+		}
+		return resp.Choices[0].Text, nil
+	}
+
+	compose := func(ctx []string) gpt3.ChatCompletionRequestMessage {
+		return gpt3.ChatCompletionRequestMessage{
+			Role:    string(service.preferences.Role),
+			Content: content,
+		}
+	}
+
+	window := NewContextWindow(engine, service.preferences.ContextLimit)
+	messages, budget, err := window.Fit(service.getSystemMessage(), content, nil, compose)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := service.client.ChatCompletion(service.ctx, gpt3.ChatCompletionRequest{
+		Model:            engine,
+		User:             service.id,
+		Messages:         messages,
+		MaxTokens:        *gpt3.IntPtr(budget.Completion),
+		Temperature:      *gpt3.Float32Ptr(service.EngineProperties.Temperature),
+		TopP:             *gpt3.Float32Ptr(service.EngineProperties.TopP),
+		PresencePenalty:  *gpt3.Float32Ptr(service.EngineProperties.PresencePenalty),
+		FrequencyPenalty: *gpt3.Float32Ptr(service.EngineProperties.FrequencyPenalty),
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp == nil || len(resp.Choices) == 0 {
+		return "", errArenaEmpty
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// FormatLeaderboard - Ranked engines of the template with their record
+func FormatLeaderboard(template string, ratings []model.ArenaRating) string {
+	if template == "" {
+		template = "No template"
+	}
+
+	out := []string{fmt.Sprint("Leaderboard: ", template)}
+	if len(ratings) == 0 {
+		return fmt.Sprint(out[0], "\nNo votes yet.")
+	}
+
+	for i, j := range ratings {
+		out = append(out, fmt.Sprintf("%v. %v %.0f (%v-%v-%v)", i+1, j.Model, j.Rating, j.Wins, j.Losses, j.Ties))
+	}
+	return strings.Join(out, "\n")
+}
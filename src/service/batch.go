@@ -0,0 +1,329 @@
+// Package service section
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"caos/model"
+)
+
+// Batch defaults
+const (
+	DefaultBatchWorkers = 4
+	batchLineBytes      = 1024 * 1024
+)
+
+// Batch errors
+var (
+	errBatchFormat   = errors.New("batch: use a .csv or .jsonl file")
+	errBatchPrompt   = errors.New("batch: the csv header doesn't have a prompt column")
+	errBatchValue    = errors.New("batch: invalid value")
+	errBatchTemplate = errors.New("batch: unknown template")
+	errBatchEmpty    = errors.New("batch: the engine didn't respond")
+)
+
+// ReadBatch - Rows of a .csv file with a header or a .jsonl file, rows without a prompt are skipped
+func ReadBatch(path string) ([]model.BatchRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return readBatchCSV(file)
+	case ".jsonl", ".json":
+		return readBatchJSONL(file)
+	}
+	return nil, fmt.Errorf("%w: %v", errBatchFormat, path)
+}
+
+// readBatchCSV - Rows of the prompt, template, engine and temperature columns in any order
+func readBatchCSV(in io.Reader) ([]model.BatchRow, error) {
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, j := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(j, "\ufeff")))] = i
+	}
+	if _, isListed := columns["prompt"]; !isListed {
+		return nil, errBatchPrompt
+	}
+
+	field := func(record []string, name string) string {
+		if i, isListed := columns[name]; isListed && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []model.BatchRow
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := model.BatchRow{
+			Row:      line,
+			Prompt:   field(record, "prompt"),
+			Template: field(record, "template"),
+			Engine:   field(record, "engine"),
+		}
+		if value := field(record, "temperature"); value != "" {
+			temperature, err := strconv.ParseFloat(value, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%w: temperature %q of row %v", errBatchValue, value, line)
+			}
+			row.Temperature = new(float32)
+			*row.Temperature = float32(temperature)
+		}
+
+		if row.Prompt != "" {
+			rows = append(rows, row)
+		}
+	}
+}
+
+// readBatchJSONL - Rows of the JSON objects, one per line
+func readBatchJSONL(in io.Reader) ([]model.BatchRow, error) {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), batchLineBytes)
+
+	var rows []model.BatchRow
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var row model.BatchRow
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			return nil, fmt.Errorf("%w: row %v: %v", errBatchValue, line, err)
+		}
+
+		row.Row = line
+		if strings.TrimSpace(row.Prompt) != "" {
+			rows = append(rows, row)
+		}
+	}
+	return rows, scanner.Err()
+}
+
+// ReadBatchResults - Latest result of each row already written, incomplete lines are ignored
+func ReadBatchResults(path string) (map[int]model.BatchResult, error) {
+	results := make(map[int]model.BatchResult)
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), batchLineBytes)
+	for scanner.Scan() {
+		var result model.BatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err == nil {
+			results[result.Row] = result
+		}
+	}
+	return results, scanner.Err()
+}
+
+// BatchRunner - Send the rows of a batch file through the controller with bounded concurrency
+type BatchRunner struct {
+	Workers int
+	// OnResult - Called after each row with its result
+	OnResult func(result model.BatchResult)
+}
+
+// Run - Send the rows without a successful result and append their results to the output file
+func (c *BatchRunner) Run(service Agent, rows []model.BatchRow, out string) (int, error) {
+	done, err := ReadBatchResults(out)
+	if err != nil {
+		return 0, err
+	}
+
+	var pending []model.BatchRow
+	for _, i := range rows {
+		if result, isDone := done[i.Row]; !isDone || result.Error != "" {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return 0, err
+	}
+
+	// Results are appended as they finish, an interrupted batch continues from the missing rows
+	file, err := os.OpenFile(out, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	workers := c.Workers
+	if workers <= 0 {
+		workers = DefaultBatchWorkers
+	}
+
+	var mutex sync.Mutex
+	var group sync.WaitGroup
+	var writeErr error
+	jobs := make(chan model.BatchRow)
+	for i := 0; i < workers && i < len(pending); i++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			for row := range jobs {
+				result := runBatchRow(service, row)
+				raw, _ := json.Marshal(result)
+
+				mutex.Lock()
+				if _, err := file.Write(append(raw, '\n')); err != nil && writeErr == nil {
+					writeErr = err
+				}
+				if c.OnResult != nil {
+					c.OnResult(result)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for _, i := range pending {
+		jobs <- i
+	}
+	close(jobs)
+	group.Wait()
+
+	return len(pending), writeErr
+}
+
+// selectTemplate - Select the template by name, the current template is kept when it's empty
+func (c *Agent) selectTemplate(name string) bool {
+	if name == "" {
+		return true
+	}
+
+	for i, j := range c.templateID {
+		if strings.EqualFold(strings.TrimSpace(j), strings.TrimSpace(name)) {
+			c.preferences.Template = i
+			return true
+		}
+	}
+	return false
+}
+
+// setRequest - Set the engine, prompt and template properties of a request from the preferences
+func (c *Agent) setRequest(input []string, instruction []string) {
+	c.EngineProperties = c.SetEngineParameters(c.id, c.preferences.Engine, c.preferences.Role,
+		c.preferences.Temperature, c.preferences.Topp, c.preferences.Penalty, c.preferences.Frequency)
+	c.PromptProperties = c.SetPromptParameters(input, instruction,
+		int(c.preferences.Results), int(c.preferences.Probabilities))
+	c.PromptProperties.MaxTokens = c.preferences.MaxTokens
+	c.TemplateProperties = c.SetTemplateParameters(input)
+}
+
+// runBatchRow - Send the row as a new conversation through the controller request path of its engine
+func runBatchRow(service Agent, row model.BatchRow) model.BatchResult {
+	agent := service
+	agent.isolateClient()
+	agent.ClearMessages()
+	agent.cachedPrompt = ""
+	agent.preferences.IsNewSession = true
+	agent.preferences.IsPromptStreaming = false
+
+	result := model.BatchResult{
+		Row:       row.Row,
+		Prompt:    row.Prompt,
+		Template:  row.Template,
+		Engine:    agent.preferences.Engine,
+		Timestamp: fmt.Sprint(time.Now().UnixMilli()),
+	}
+
+	if !agent.selectTemplate(row.Template) {
+		result.Error = fmt.Sprintf("%v: %v", errBatchTemplate, row.Template)
+		return result
+	}
+	if row.Engine != "" {
+		result.Engine = row.Engine
+	}
+	if row.Temperature != nil {
+		agent.preferences.Temperature = *row.Temperature
+	}
+
+	agent.preferences.Engine = result.Engine
+	agent.preferences.Mode = "Text"
+	if isChatModel(agent, result.Engine) {
+		agent.preferences.Mode = "Turbo"
+	}
+
+	provider := &recordingProvider{Provider: service.client}
+	agent.SetProvider(provider)
+	agent.setRequest([]string{row.Prompt}, []string{""})
+
+	controller := NewController(agent)
+	if agent.preferences.Mode == "Turbo" {
+		controller.ChatCompletionRequest()
+	} else {
+		controller.CompletionRequest()
+	}
+
+	result.ID, result.Completion, result.Usage = provider.id, provider.choices, provider.usage
+	switch {
+	case provider.err != nil:
+		result.Error = provider.err.Error()
+	case controller.prompt.err != nil:
+		result.Error = controller.prompt.err.Error()
+	case provider.choices == nil:
+		result.Error = errBatchEmpty.Error()
+	}
+	return result
+}
+
+// GetBatchOutput - Results file of a batch file, next to it
+func GetBatchOutput(path string) string {
+	return fmt.Sprint(strings.TrimSuffix(path, filepath.Ext(path)), ".results.jsonl")
+}
+
+// RunBatch - Run the batch file with a new agent and write the results to the output file
+func RunBatch(path string, out string, workers int, onResult func(result model.BatchResult)) (int, error) {
+	rows, err := ReadBatch(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if out == "" {
+		out = GetBatchOutput(path)
+	}
+
+	runner := &BatchRunner{Workers: workers, OnResult: onResult}
+	return runner.Run(AttachHeadlessProfile(), rows, out)
+}
@@ -4,7 +4,6 @@ package service
 import (
 	"bytes"
 	"caos/model"
-	"caos/service/parameters"
 	"caos/util"
 	"crypto/tls"
 	"encoding/pem"
@@ -12,6 +11,7 @@ import (
 	"net/http/cookiejar"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/andelf/go-curl"
 	"golang.org/x/net/html"
@@ -23,8 +23,13 @@ type Chain struct {
 	Transform model.ChainPrompt
 }
 
+// chainMutex - Run one chain job at a time, the certificate and cookies files are shared
+var chainMutex sync.Mutex
+
 // ExecuteChainJob - ExecuteChainJob chain sequence
 func (c *Chain) ExecuteChainJob(service Agent, prompt *model.PromptProperties) {
+	chainMutex.Lock()
+	defer chainMutex.Unlock()
 	defer clear()
 	c.Input = append(c.Input, prompt.Input...)
 	c.onConstructAssemble(service, prompt.Input)
@@ -33,6 +38,26 @@ func (c *Chain) ExecuteChainJob(service Agent, prompt *model.PromptProperties) {
 	}
 }
 
+// ExecuteSearchJob - Search results of the query without opening the urls
+func (c *Chain) ExecuteSearchJob(service Agent, query string) {
+	chainMutex.Lock()
+	defer chainMutex.Unlock()
+	defer clear()
+	c.Input = append(c.Input, query)
+	c.onConstructAssemble(service, []string{query})
+}
+
+// ExecuteReadJob - Open a source url and append its links and text
+func (c *Chain) ExecuteReadJob(service Agent, url string) ([]string, []string) {
+	chainMutex.Lock()
+	defer chainMutex.Unlock()
+	defer clear()
+	header, context := c.onConstructRead(service, url)
+	c.Transform.Source = append(c.Transform.Source, header...)
+	c.Transform.Context = append(c.Transform.Context, context...)
+	return header, context
+}
+
 // clear - Delete existent cookies and ssl certificate
 func clear() {
 	os.Remove("rootCA.pem")
@@ -42,6 +67,10 @@ func clear() {
 // setCertificateSSL - Implement SSL
 func setCertificateSSL(service Agent, url string) {
 	os.Remove("rootCA.pem")
+	if !strings.HasPrefix(url, "https://") {
+		return
+	}
+
 	trimL := strings.Split(url, "//")
 	if trimL == nil {
 		return
@@ -53,7 +82,10 @@ func setCertificateSSL(service Agent, url string) {
 	}
 
 	domain := trimR[0] + ":443"
-	conn, _ := tls.Dial("tcp", domain, &tls.Config{})
+	conn, err := tls.Dial("tcp", domain, &tls.Config{})
+	if err != nil {
+		return
+	}
 	conn.Handshake()
 
 	defer conn.Close()
@@ -203,10 +235,10 @@ func setOpt(req string, user string, enc string) []byte {
 
 // onConstructAssemble - Assemble transformer
 func (c *Chain) onConstructAssemble(service Agent, input []string) {
-	setCertificateSSL(service, parameters.ExternalSearchBaseURL)
-	setCookieJar(service, parameters.ExternalSearchBaseURL)
+	setCertificateSSL(service, service.preferences.SearchURL)
+	setCookieJar(service, service.preferences.SearchURL)
 	context := strings.ReplaceAll(input[0], " ", "+")
-	req := fmt.Sprint(parameters.ExternalSearchBaseURL, context)
+	req := fmt.Sprint(service.preferences.SearchURL, context)
 	reader := bytes.NewReader(setOpt(req, service.preferences.User, service.preferences.Encoding))
 	c.Transform.Source, c.Transform.Context = setConstructResults(reader)
 }
@@ -217,12 +249,7 @@ func (c *Chain) onConstructValidation(service Agent) {
 	var contextBuffer []string
 	for i := range c.Transform.Source {
 		if i <= 3 {
-			setCertificateSSL(service, c.Transform.Source[i])
-			setCookieJar(service, c.Transform.Source[i])
-
-			req := fmt.Sprint(c.Transform.Source[i])
-			reader := bytes.NewReader(setOpt(req, service.preferences.User, service.preferences.Encoding))
-			header, context := setConstructResults(reader)
+			header, context := c.onConstructRead(service, c.Transform.Source[i])
 
 			sourceBuffer = append(sourceBuffer, header...)
 			contextBuffer = append(contextBuffer, context...)
@@ -232,3 +259,13 @@ func (c *Chain) onConstructValidation(service Agent) {
 	c.Transform.Source = append(c.Transform.Source, sourceBuffer...)
 	c.Transform.Context = append(c.Transform.Context, contextBuffer...)
 }
+
+// onConstructRead - Links and text of a source url
+func (c *Chain) onConstructRead(service Agent, url string) ([]string, []string) {
+	setCertificateSSL(service, url)
+	setCookieJar(service, url)
+
+	req := fmt.Sprint(url)
+	reader := bytes.NewReader(setOpt(req, service.preferences.User, service.preferences.Encoding))
+	return setConstructResults(reader)
+}
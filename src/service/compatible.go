@@ -0,0 +1,287 @@
+// Package service section
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// CompatibleProvider - OpenAI-compatible backend reached through a configurable base URL (Ollama, llama.cpp, vLLM)
+type CompatibleProvider struct {
+	baseURL string
+	key     string
+	client  *http.Client
+}
+
+// compatibleCompletionRequest - Completion request including the model field required by compatible servers
+type compatibleCompletionRequest struct {
+	Model string `json:"model"`
+	gpt3.CompletionRequest
+}
+
+// compatibleModel - Model object returned by the models route
+type compatibleModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// compatibleModelsResponse - Models route response
+type compatibleModelsResponse struct {
+	Object string            `json:"object"`
+	Data   []compatibleModel `json:"data"`
+}
+
+// NewCompatibleProvider - Create a compatible provider, the API key is optional
+func NewCompatibleProvider(baseURL string, key string, client *http.Client) *CompatibleProvider {
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	return &CompatibleProvider{
+		baseURL: normalizeBaseURL(baseURL),
+		key:     key,
+		client:  client,
+	}
+}
+
+// normalizeBaseURL - Trim the base URL and append the API version when missing
+func normalizeBaseURL(baseURL string) string {
+	url := strings.TrimSuffix(strings.TrimSpace(baseURL), "/")
+	if !strings.HasSuffix(url, "/v1") {
+		url = fmt.Sprint(url, "/v1")
+	}
+	return url
+}
+
+// GetBaseURL - Base URL used by the provider
+func (c *CompatibleProvider) GetBaseURL() string {
+	return c.baseURL
+}
+
+// newRequest - Construct a JSON request for the selected route
+func (c *CompatibleProvider) newRequest(ctx context.Context, method string, path string, payload interface{}) (*http.Request, error) {
+	var body io.Reader = bytes.NewBuffer(nil)
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewBuffer(raw)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprint(c.baseURL, path), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-type", "application/json")
+	if c.key != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.key))
+	}
+
+	return req, nil
+}
+
+// performRequest - Execute the request and validate the status code
+func (c *CompatibleProvider) performRequest(req *http.Request) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+
+	var result gpt3.APIErrorResponse
+	if err := json.Unmarshal(data, &result); err != nil || result.Error.Message == "" {
+		return nil, gpt3.APIError{
+			StatusCode: resp.StatusCode,
+			Type:       "Unexpected",
+			Message:    string(data),
+		}
+	}
+
+	result.Error.StatusCode = resp.StatusCode
+	return nil, result.Error
+}
+
+// call - Send a request and decode the JSON response into output
+func (c *CompatibleProvider) call(ctx context.Context, method string, path string, payload interface{}, output interface{}) error {
+	req, err := c.newRequest(ctx, method, path, payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(output); err != nil {
+		return fmt.Errorf("invalid json response: %w", err)
+	}
+	return nil
+}
+
+// stream - Send a request and read the server-sent events until the stream is done
+func (c *CompatibleProvider) stream(ctx context.Context, path string, payload interface{}, onEvent func([]byte) error) error {
+	req, err := c.newRequest(ctx, "POST", path, payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(bytes.TrimSpace(line)) == 0 {
+			return nil
+		} else if err != nil && err != io.EOF {
+			return err
+		}
+
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+
+		line = bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+		if bytes.HasPrefix(line, []byte("[DONE]")) {
+			return nil
+		}
+
+		if err := onEvent(line); err != nil {
+			return fmt.Errorf("invalid json stream data: %v", err)
+		}
+	}
+}
+
+// Forward - Send the raw body to the route with the headers of the client, the key of the profile replaces theirs
+func (c *CompatibleProvider) Forward(ctx context.Context, path string, header http.Header, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprint(c.baseURL, path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range header {
+		switch http.CanonicalHeaderKey(key) {
+		// The transport negotiates its own encoding so the answer can be recorded
+		case "Accept-Encoding", "Connection", "Content-Length", "Host":
+			continue
+		}
+		req.Header[key] = values
+	}
+	if c.key != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.key))
+	}
+
+	return c.client.Do(req)
+}
+
+// ChatCompletion - Send a chat completion request
+func (c *CompatibleProvider) ChatCompletion(ctx context.Context, request gpt3.ChatCompletionRequest) (*gpt3.ChatCompletionResponse, error) {
+	request.Stream = false
+	output := new(gpt3.ChatCompletionResponse)
+	if err := c.call(ctx, "POST", "/chat/completions", request, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// ChatCompletionStream - Send a chat completion request streaming the response through onData
+func (c *CompatibleProvider) ChatCompletionStream(ctx context.Context, request gpt3.ChatCompletionRequest, onData func(*gpt3.ChatCompletionStreamResponse)) error {
+	request.Stream = true
+	return c.stream(ctx, "/chat/completions", request, func(data []byte) error {
+		output := new(gpt3.ChatCompletionStreamResponse)
+		if err := json.Unmarshal(data, output); err != nil {
+			return err
+		}
+		if len(output.Choices) > 0 {
+			onData(output)
+		}
+		return nil
+	})
+}
+
+// Completion - Send a completion request with the selected engine
+func (c *CompatibleProvider) Completion(ctx context.Context, engine string, request gpt3.CompletionRequest) (*gpt3.CompletionResponse, error) {
+	request.Stream = false
+	output := new(gpt3.CompletionResponse)
+	if err := c.call(ctx, "POST", "/completions", compatibleCompletionRequest{Model: engine, CompletionRequest: request}, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// CompletionStream - Send a completion request with the selected engine streaming the response through onData
+func (c *CompatibleProvider) CompletionStream(ctx context.Context, engine string, request gpt3.CompletionRequest, onData func(*gpt3.CompletionResponse)) error {
+	request.Stream = true
+	return c.stream(ctx, "/completions", compatibleCompletionRequest{Model: engine, CompletionRequest: request}, func(data []byte) error {
+		output := new(gpt3.CompletionResponse)
+		if err := json.Unmarshal(data, output); err != nil {
+			return err
+		}
+		if len(output.Choices) > 0 {
+			onData(output)
+		}
+		return nil
+	})
+}
+
+// Edit - Send an edit instruction request
+func (c *CompatibleProvider) Edit(ctx context.Context, request gpt3.EditsRequest) (*gpt3.EditsResponse, error) {
+	output := new(gpt3.EditsResponse)
+	if err := c.call(ctx, "POST", "/edits", request, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// Embedding - Send an embedding vector request
+func (c *CompatibleProvider) Embedding(ctx context.Context, request gpt3.EmbeddingsRequest) (*gpt3.EmbeddingsResponse, error) {
+	output := new(gpt3.EmbeddingsResponse)
+	if err := c.call(ctx, "POST", "/embeddings", request, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// ListModels - List the models served by the backend
+func (c *CompatibleProvider) ListModels(ctx context.Context) (*gpt3.EnginesResponse, error) {
+	models := new(compatibleModelsResponse)
+	if err := c.call(ctx, "GET", "/models", nil, models); err != nil {
+		return nil, err
+	}
+
+	output := &gpt3.EnginesResponse{
+		Object: models.Object,
+	}
+	for _, i := range models.Data {
+		output.Data = append(output.Data, gpt3.EngineObject{
+			ID:     i.ID,
+			Object: i.Object,
+			Owner:  i.OwnedBy,
+			Ready:  true,
+		})
+	}
+	return output, nil
+}
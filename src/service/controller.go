@@ -2,6 +2,10 @@
 package service
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"caos/model"
 )
 
@@ -9,6 +13,25 @@ import (
 type Controller struct {
 	currentAgent Agent
 	events       EventManager
+	prompt       *Prompt
+}
+
+// NewController - Controller of the agent with its own prompt and events, used outside of the console
+func NewController(agent Agent) *Controller {
+	controller := &Controller{
+		currentAgent: agent,
+		prompt:       &Prompt{},
+	}
+	controller.events.agent = &controller.currentAgent
+	return controller
+}
+
+// getPrompt - Prompt requester of the controller, the console prompt when it isn't attached
+func (c *Controller) getPrompt() *Prompt {
+	if c.prompt != nil {
+		return c.prompt
+	}
+	return &node.prompt
 }
 
 // AttachProfile - Attach profile to a new service client
@@ -19,23 +42,161 @@ func (c *Controller) AttachProfile() Agent {
 	return serviceClient
 }
 
+// AttachProvider - Attach a backend provider to the current agent
+func (c *Controller) AttachProvider(provider Provider) {
+	c.currentAgent.SetProvider(provider)
+}
+
+// GetAgent - Current service client
+func (c *Controller) GetAgent() Agent {
+	return c.currentAgent
+}
+
 // FlushEvents - Reset the pool
 func (c *Controller) FlushEvents() {
 	node.controller.events.pool.TrainingEvent = []model.TrainingEvent{}
 	node.controller.events.pool.TrainingSession = []model.TrainingSession{}
 }
 
+// ForkTurn - Continue the conversation from the previous turn in a new branch
+func (c *Controller) ForkTurn() bool {
+	if !c.events.ForkTurn() {
+		return false
+	}
+
+	c.restoreBranch()
+	return true
+}
+
+// SwitchBranch - Continue the conversation from a sibling branch
+func (c *Controller) SwitchBranch(offset int) (int, int) {
+	position, total := c.events.SwitchBranch(offset)
+	c.restoreBranch()
+	return position, total
+}
+
+// ResumeSession - Continue the conversation stored in a log file
+func (c *Controller) ResumeSession(path string) error {
+	var session EventManager
+	if err := session.LoadSession(path); err != nil {
+		return err
+	}
+
+	c.resumeEvents(session)
+	return nil
+}
+
+// resumeEvents - Continue the loaded conversation from the last turn of its branch
+func (c *Controller) resumeEvents(session EventManager) {
+	c.events.pool = session.pool
+
+	branch := c.events.GetBranch()
+	if len(branch) > 0 && branch[len(branch)-1].Event.Event.Header.Model != "" {
+		c.currentAgent.preferences.Engine = branch[len(branch)-1].Event.Event.Header.Model
+	}
+
+	c.currentAgent.preferences.IsNewSession = false
+	c.restoreBranch()
+}
+
+// restoreBranch - Rebuild the chat history, the running summary and the cached prompt from the current branch
+func (c *Controller) restoreBranch() {
+	c.currentAgent.ClearMessages()
+	c.currentAgent.cachedPrompt = ""
+
+	// Summaries are stored with the session of each turn
+	summaries := make(map[string]string)
+	for _, i := range c.events.GetPool().Session {
+		for _, j := range i.Session {
+			if i.Summary != "" && j.ID != "" {
+				summaries[j.ID] = i.Summary
+			}
+		}
+	}
+
+	var summary string
+	for _, i := range c.events.GetBranch() {
+		if summaries[i.Event.ID] != "" {
+			summary = summaries[i.Event.ID]
+		}
+
+		body := i.Event.Event.Body
+		if body.Input == nil || body.Content == nil {
+			continue
+		}
+
+		content := trimSeparator(strings.Join(body.Content, ""))
+		c.currentAgent.AppendMessages(body.Input[0], content)
+		c.currentAgent.cachedPrompt = fmt.Sprint(c.currentAgent.cachedPrompt, body.Input[0], "\n", content, "\n\n")
+	}
+
+	// The summary replaces the older turns, the latest exchanges are kept verbatim like after summarizing
+	if summary != "" {
+		expired := len(c.currentAgent.getTurns()) - c.currentAgent.preferences.SummaryThreshold/2*2
+		if expired < 0 {
+			expired = 0
+		}
+		c.currentAgent.SummarizeMessages(summary, expired)
+	}
+}
+
 // ChatCompletionRequest - Chat completion request to send task prompt
 func (c *Controller) ChatCompletionRequest() {
-	if c.currentAgent.preferences.IsPromptStreaming {
-		resp, _ := node.prompt.SendChatCompletionPrompt(c.currentAgent)
+	if c.currentAgent.preferences.SchemaPath != "" {
+		resp, errs := c.getPrompt().SendSchemaCompletionPrompt(c.currentAgent)
+
+		if resp != nil {
+			if resp.Choices != nil {
+				c.currentAgent.AppendMessages(c.currentAgent.PromptProperties.Input[0], resp.Choices[0].Message.Content)
+				c.summarizeMessages()
+			}
+
+			// Only conforming objects are kept as the structured output
+			c.currentAgent.PromptProperties.Content = nil
+			if errs == nil && resp.Choices != nil {
+				c.currentAgent.PromptProperties.Content = []string{resp.Choices[0].Message.Content}
+			}
+
+			c.events.LogChatCompletion(c.currentAgent.TemplateProperties, c.currentAgent.EngineProperties, c.currentAgent.PromptProperties, resp, nil)
+			c.events.VisualLogCompletion(nil, resp, nil)
+		}
+		c.events.VisualLogSchema(errs)
+	} else if c.currentAgent.preferences.IsToolEnabled {
+		resp, calls := c.getPrompt().SendToolCompletionPrompt(c.currentAgent)
+		c.currentAgent.TemplateProperties.Tools = calls
+
+		if resp != nil {
+			if resp.Choices != nil {
+				c.currentAgent.AppendMessages(c.currentAgent.PromptProperties.Input[0], resp.Choices[0].Message.Content)
+				c.summarizeMessages()
+			}
+
+			c.events.LogChatCompletion(c.currentAgent.TemplateProperties, c.currentAgent.EngineProperties, c.currentAgent.PromptProperties, resp, nil)
+			c.events.VisualLogCompletion(nil, resp, nil)
+		}
+	} else if c.currentAgent.preferences.IsPromptStreaming {
+		resp, _ := c.getPrompt().SendChatCompletionPrompt(c.currentAgent)
+		c.currentAgent.TemplateProperties.PromptValidated = c.getPrompt().validated
+
+		if resp != nil {
+			if resp.Choices != nil {
+				c.currentAgent.AppendMessages(c.currentAgent.PromptProperties.Input[0], trimSeparator(resp.Choices[0].Delta.Content))
+				c.summarizeMessages()
+			}
 
-		c.events.LogChatCompletion(c.currentAgent.TemplateProperties, c.currentAgent.EngineProperties, c.currentAgent.PromptProperties, nil, resp)
-		c.events.VisualLogCompletion(nil, nil, resp)
+			c.events.LogChatCompletion(c.currentAgent.TemplateProperties, c.currentAgent.EngineProperties, c.currentAgent.PromptProperties, nil, resp)
+			c.events.VisualLogCompletion(nil, nil, resp)
+		}
 	} else {
-		_, resp := node.prompt.SendChatCompletionPrompt(c.currentAgent)
+		_, resp := c.getPrompt().SendChatCompletionPrompt(c.currentAgent)
+		c.currentAgent.TemplateProperties.PromptValidated = c.getPrompt().validated
 
 		if resp != nil {
+			if resp.Choices != nil {
+				c.currentAgent.AppendMessages(c.currentAgent.PromptProperties.Input[0], resp.Choices[0].Message.Content)
+				c.summarizeMessages()
+			}
+
 			c.events.LogChatCompletion(c.currentAgent.TemplateProperties, c.currentAgent.EngineProperties, c.currentAgent.PromptProperties, resp, nil)
 			c.events.VisualLogCompletion(nil, resp, nil)
 		}
@@ -44,24 +205,56 @@ func (c *Controller) ChatCompletionRequest() {
 	c.events.LogEngine(c.currentAgent)
 }
 
-// CompletionRequest - Start completion request to send task prompt
-func (c *Controller) CompletionRequest() {
-	resp := node.prompt.SendCompletionPrompt(c.currentAgent)
+// summarizeMessages - Compress the oldest turns in the running summary
+func (c *Controller) summarizeMessages() {
+	expired := c.currentAgent.ExpiredMessages()
+	if expired == nil {
+		return
+	}
+
+	summary := c.getPrompt().SendSummaryPrompt(c.currentAgent, expired)
+	if summary != "" {
+		c.currentAgent.SummarizeMessages(summary, len(expired))
+	}
+}
+
+// trimSeparator - Remove the layout separators from a streamed response
+func trimSeparator(content string) string {
+	out := strings.TrimSpace(content)
+	out = strings.TrimSuffix(out, "###")
+	return strings.TrimSpace(out)
+}
+
+// CompletionRequest - Start completion request to send task prompt, the provider error is returned
+func (c *Controller) CompletionRequest() error {
+	resp := c.getPrompt().SendCompletionPrompt(c.currentAgent)
+
+	// Failed streams keep an empty response
+	if resp != nil && len(resp.Choices) > 0 {
+		var choices []string
+		for i := range resp.Choices {
+			choices = append(choices, resp.Choices[i].Text)
+			// The streamed choices share the joined text
+			if c.currentAgent.preferences.IsPromptStreaming {
+				break
+			}
+		}
 
-	if resp != nil {
 		if c.currentAgent.preferences.Results > 0 {
-			c.events.LogGeneralCompletion(c.currentAgent.EngineProperties, c.currentAgent.PromptProperties, []string{resp.Choices[0].Text}, resp.ID)
+			c.events.LogGeneralCompletion(c.currentAgent.EngineProperties, c.currentAgent.PromptProperties, choices, resp.ID)
 		}
+		c.events.appendToRatings(resp.ID, c.currentAgent.PromptProperties.Input, choices)
 
 		c.events.VisualLogCompletion(resp, nil, nil)
 	}
 
 	c.events.LogEngine(c.currentAgent)
+	return c.getPrompt().err
 }
 
 // EditRequest - Start edit request  to send a task prompt
 func (c *Controller) EditRequest() {
-	resp := node.prompt.SendEditPrompt(c.currentAgent)
+	resp := c.getPrompt().SendEditPrompt(c.currentAgent)
 
 	if resp != nil {
 		for i := range resp.Choices {
@@ -75,13 +268,26 @@ func (c *Controller) EditRequest() {
 
 // EmbeddingRequest - Start a embedding vector request
 func (c *Controller) EmbeddingRequest() {
-	resp := node.prompt.SendEmbeddingPrompt(c.currentAgent)
+	resp := c.getPrompt().SendEmbeddingPrompt(c.currentAgent)
 
 	if resp != nil {
 		for i := range resp.Data {
 			c.events.LogGeneralCompletion(c.currentAgent.EngineProperties, c.currentAgent.PromptProperties, []string{resp.Data[i].Object}, c.currentAgent.preferences.CurrentID)
 		}
 		c.events.VisualLogEmbedding(resp)
+
+		if err := StoreEmbeddings(c.currentAgent, resp); err != nil {
+			c.events.Errata(err)
+		}
+
+		if len(resp.Data) > 0 {
+			similar, err := SimilarSessions(c.currentAgent, c.currentAgent.EngineProperties.Model, resp.Data[0].Embedding, 5)
+			if err != nil {
+				c.events.Errata(err)
+			} else {
+				c.events.VisualLogSimilar(similar)
+			}
+		}
 	}
 
 	c.events.LogEngine(c.currentAgent)
@@ -89,7 +295,7 @@ func (c *Controller) EmbeddingRequest() {
 
 // PredictableRequest - Start a predictable string request
 func (c *Controller) PredictableRequest() {
-	resp := node.prompt.SendPredictablePrompt(c.currentAgent)
+	resp := c.getPrompt().SendPredictablePrompt(c.currentAgent)
 
 	if resp != nil {
 		c.events.LogPredict(c.currentAgent.EngineProperties, c.currentAgent.PredictProperties, resp)
@@ -101,12 +307,77 @@ func (c *Controller) PredictableRequest() {
 	c.events.LogPredictEngine(c.currentAgent)
 }
 
+// ResearchRequest - Research the question with the web chain and log the final report
+func (c *Controller) ResearchRequest(question string) {
+	researcher := NewResearcher(c.currentAgent)
+	researcher.OnStep = func(report model.ResearchReport) {
+		c.events.VisualLogResearch(report)
+	}
+
+	report, err := researcher.Run(c.currentAgent, question)
+	if err != nil {
+		queueLayout(func() {
+			c.events.Errata(err)
+		})
+		return
+	}
+
+	body := c.currentAgent.PromptProperties
+	body.Input = []string{question}
+	c.events.LogGeneralCompletion(c.currentAgent.EngineProperties, body, []string{FormatResearchReport(report)}, fmt.Sprint("research-", time.Now().UnixMilli()))
+	queueLayout(func() {
+		c.events.appendToLayout([]string{FormatResearchReport(report)})
+	})
+}
+
 // ListModels - Get actual models available
 func (c *Controller) ListModels() {
-	resp := node.prompt.GetListModels(c.currentAgent)
+	resp := c.getPrompt().GetListModels(c.currentAgent)
 	if resp != nil {
 		for _, i := range resp.Data {
 			c.currentAgent.preferences.Models = append(c.currentAgent.preferences.Models, i.ID)
 		}
+		// Compatible and replay backends serve their own models, select the first one by default
+		if (c.currentAgent.preferences.BaseURL != "" || c.currentAgent.preferences.ReplayPath != "") && len(resp.Data) > 0 {
+			isAvailable := false
+			for _, i := range resp.Data {
+				if i.ID == c.currentAgent.preferences.Engine {
+					isAvailable = true
+				}
+			}
+
+			if !isAvailable {
+				c.currentAgent.preferences.Engine = resp.Data[0].ID
+				c.currentAgent.preferences.Mode = "Turbo"
+			}
+		}
+	}
+}
+
+// ArenaRequest - Send the prompt to both engines of the arena
+func (c *Controller) ArenaRequest(prompt string, models []string) (model.ArenaMatch, error) {
+	return NewArenaMatch(c.currentAgent, prompt, models)
+}
+
+// ArenaVote - Vote the match and persist the leaderboard of its template
+func (c *Controller) ArenaVote(match *model.ArenaMatch, vote model.ArenaVote) ([]model.ArenaRating, error) {
+	arena, err := OpenArena(arenaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := arena.Vote(match, vote); err != nil {
+		return nil, err
+	}
+	return arena.Leaderboard(match.Template), arena.Save()
+}
+
+// ArenaLeaderboard - Persisted leaderboard of the selected template
+func (c *Controller) ArenaLeaderboard() (string, []model.ArenaRating, error) {
+	template := c.currentAgent.SetTemplateParameters(nil).Name
+	arena, err := OpenArena(arenaPath)
+	if err != nil {
+		return template, nil, err
 	}
+	return template, arena.Leaderboard(template), nil
 }
@@ -0,0 +1,327 @@
+// Package service section
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"caos/model"
+	"caos/store"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// Embedding requests send the pairs in batches
+const embeddingBatch = 16
+
+// Metadata of the embedded session pairs
+const (
+	sessionKind   = "session"
+	promptKind    = "prompt"
+	sessionKey    = "session"
+	timestampKey  = "timestamp"
+	promptKey     = "prompt"
+	completionKey = "completion"
+	modifiedKey   = "modified"
+)
+
+// embeddingPath - Vector store of the stored sessions and the embedded prompts
+var embeddingPath = filepath.Join("index", "vectors.json")
+
+// errEmptyEmbedding - Embedding response without a vector for every input
+var errEmptyEmbedding = errors.New("embedding: the response doesn't contain a vector for every input")
+
+// EmbeddingIndex - Embedded prompt and completion pairs of the log sessions kept in the vector store
+type EmbeddingIndex struct {
+	store *store.VectorStore
+}
+
+// OpenEmbeddingIndex - Load the vector store persisted in path
+func OpenEmbeddingIndex(path string) (*EmbeddingIndex, error) {
+	vectors, err := store.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &EmbeddingIndex{store: vectors}, nil
+}
+
+// Save - Persist the vector store
+func (c *EmbeddingIndex) Save() error {
+	return c.store.Save()
+}
+
+// Records - Embedded pairs of the model
+func (c *EmbeddingIndex) Records(engine string) []model.EmbeddingRecord {
+	var records []model.EmbeddingRecord
+	for _, i := range c.store.List(getSessionFilter(engine)) {
+		records = append(records, getEmbeddingRecord(i))
+	}
+	return records
+}
+
+// getSessionFilter - Filter of the session pairs embedded with the model
+func getSessionFilter(engine string) store.Filter {
+	return store.Filter{
+		Metadata: map[string]string{
+			store.KindKey:  sessionKind,
+			store.ModelKey: engine,
+		},
+	}
+}
+
+// Update - Embed the pairs of the new and modified log files with the model and drop the removed ones
+func (c *EmbeddingIndex) Update(service Agent, engine string, dirs ...string) (int, error) {
+	// Vectors of different models can't be compared, each model keeps its own pairs
+	files := make(map[string]string)
+	for _, i := range c.store.List(getSessionFilter(engine)) {
+		files[i.Metadata[store.SourceKey]] = i.Metadata[modifiedKey]
+	}
+
+	var updated int
+	var pending []model.VectorDocument
+	isListed := make(map[string]bool)
+	for _, i := range dirs {
+		paths, _ := filepath.Glob(filepath.Join(i, "*.json"))
+		for _, j := range paths {
+			isListed[j] = true
+			info, err := os.Stat(j)
+			if err != nil {
+				continue
+			}
+
+			modified := fmt.Sprint(info.ModTime().UnixNano())
+			if files[j] == modified {
+				continue
+			}
+
+			session, err := readLogSession(j)
+			if err != nil {
+				continue
+			}
+
+			documents := parseEmbeddingDocuments(j, modified, engine, session)
+			if documents != nil || files[j] != "" {
+				c.deleteSource(engine, j)
+				updated++
+			}
+			pending = append(pending, documents...)
+		}
+	}
+
+	for i := range files {
+		if !isListed[i] {
+			c.deleteSource(engine, i)
+			updated++
+		}
+	}
+
+	return updated, embedDocuments(service, engine, c.store, pending)
+}
+
+// embedDocuments - Request the vectors of the documents in batches and keep them in the store
+func embedDocuments(service Agent, engine string, vectors *store.VectorStore, pending []model.VectorDocument) error {
+	for i := 0; i < len(pending); i += embeddingBatch {
+		end := i + embeddingBatch
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		var input []string
+		for _, j := range pending[i:end] {
+			input = append(input, j.Text)
+		}
+
+		embedded, err := embedTexts(service, engine, input)
+		if err != nil {
+			return err
+		}
+
+		for j := range embedded {
+			pending[i+j].Vector = embedded[j]
+		}
+
+		if err := vectors.Upsert(pending[i:end]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteSource - Drop the pairs of a log file embedded with the model
+func (c *EmbeddingIndex) deleteSource(engine string, path string) {
+	filter := getSessionFilter(engine)
+	filter.Metadata[store.SourceKey] = path
+	c.store.DeleteMatching(filter)
+}
+
+// parseEmbeddingDocuments - Prompt and completion pairs of a historical session waiting for their vectors
+func parseEmbeddingDocuments(path string, modified string, engine string, session model.HistoricalSession) []model.VectorDocument {
+	var documents []model.VectorDocument
+	for i, j := range session.Session {
+		prompt := strings.TrimSpace(strings.Join(j.Event.Body.Input, "\n"))
+		completion := strings.TrimSpace(strings.Join(j.Event.Body.Content, "\n"))
+		if prompt == "" && completion == "" {
+			continue
+		}
+
+		var date time.Time
+		if ms, err := strconv.ParseInt(j.Timestamp, 10, 64); err == nil {
+			date = time.UnixMilli(ms).UTC()
+		}
+
+		documents = append(documents, model.VectorDocument{
+			ID:   fmt.Sprint(engine, ":", path, "#", i),
+			Text: strings.TrimSpace(fmt.Sprint(prompt, "\n", completion)),
+			Metadata: map[string]string{
+				store.KindKey:     sessionKind,
+				store.ModelKey:    engine,
+				store.SourceKey:   path,
+				store.TemplateKey: j.Event.Template.Name,
+				sessionKey:        session.ID,
+				timestampKey:      j.Timestamp,
+				promptKey:         prompt,
+				completionKey:     completion,
+				modifiedKey:       modified,
+			},
+			Date: date,
+		})
+	}
+	return documents
+}
+
+// getEmbeddingRecord - Session pair of a stored document
+func getEmbeddingRecord(document model.VectorDocument) model.EmbeddingRecord {
+	return model.EmbeddingRecord{
+		ID:         document.ID,
+		Path:       document.Metadata[store.SourceKey],
+		SessionID:  document.Metadata[sessionKey],
+		Timestamp:  document.Metadata[timestampKey],
+		Prompt:     document.Metadata[promptKey],
+		Completion: document.Metadata[completionKey],
+		Vector:     document.Vector,
+	}
+}
+
+// embedTexts - Request a vector for each text, ordered like the input
+func embedTexts(service Agent, engine string, input []string) ([][]float64, error) {
+	resp, err := service.client.Embedding(service.ctx, gpt3.EmbeddingsRequest{
+		Model: engine,
+		Input: input,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || len(resp.Data) != len(input) {
+		return nil, errEmptyEmbedding
+	}
+
+	vectors := make([][]float64, len(input))
+	for _, i := range resp.Data {
+		if i.Index < 0 || i.Index >= len(vectors) {
+			return nil, errEmptyEmbedding
+		}
+		vectors[i.Index] = i.Embedding
+	}
+	return vectors, nil
+}
+
+// Nearest - Sessions embedded with the model ranked by the cosine similarity of their closest pair to the vector
+func (c *EmbeddingIndex) Nearest(engine string, vector []float64, limit int) []model.SimilarResult {
+	best := make(map[string]bool)
+	var results []model.SimilarResult
+	for _, i := range c.store.Search(vector, 0, getSessionFilter(engine)) {
+		if i.Score <= 0 {
+			break
+		}
+
+		// Turns of a session are stored in separated files, the session ID groups them
+		record := getEmbeddingRecord(i.Document)
+		group := record.SessionID
+		if group == "" {
+			group = record.Path
+		}
+
+		if best[group] {
+			continue
+		}
+		best[group] = true
+		results = append(results, model.SimilarResult{Record: record, Score: i.Score})
+
+		if limit > 0 && len(results) == limit {
+			break
+		}
+	}
+	return results
+}
+
+// StoreEmbeddings - Keep the vectors of an embedding response with the template of the prompt
+func StoreEmbeddings(service Agent, resp *gpt3.EmbeddingsResponse) error {
+	vectors, err := store.Open(embeddingPath)
+	if err != nil {
+		return err
+	}
+
+	documents := store.FromEmbeddings(resp, service.PromptProperties.Input, service.EngineProperties.Model, map[string]string{
+		store.KindKey:     promptKind,
+		store.SourceKey:   promptKind,
+		store.TemplateKey: service.SetTemplateParameters(nil).Name,
+	})
+
+	if err := vectors.Upsert(documents...); err != nil {
+		return err
+	}
+	return vectors.Save()
+}
+
+// SimilarSessions - Update the stored vectors with the model and rank the sessions close to the vector
+func SimilarSessions(service Agent, engine string, vector []float64, limit int) ([]model.SimilarResult, error) {
+	index, err := OpenEmbeddingIndex(embeddingPath)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := index.Update(service, engine, sessionPath)
+	if updated > 0 {
+		index.Save()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return index.Nearest(engine, vector, limit), nil
+}
+
+// FindSimilarSessions - Embed the query and rank the stored sessions close to it
+func FindSimilarSessions(service Agent, query string, limit int) ([]model.SimilarResult, error) {
+	engine := service.preferences.EmbeddingModel
+	vectors, err := embedTexts(service, engine, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return SimilarSessions(service, engine, vectors[0], limit)
+}
+
+// FormatSimilarResults - Ranked sessions with the score, the prompt and the earlier answer
+func FormatSimilarResults(results []model.SimilarResult) string {
+	if len(results) == 0 {
+		return "No similar conversations found.\n"
+	}
+
+	var out []string
+	for i, j := range results {
+		out = append(out, fmt.Sprintf("%v. [%.3f] %v (%v)\n   Prompt: %v\n   Answer: %v\n",
+			i+1,
+			j.Score,
+			formatTimestamp(j.Record.Timestamp),
+			j.Record.Path,
+			strings.ReplaceAll(j.Record.Prompt, "\n", " "),
+			strings.ReplaceAll(j.Record.Completion, "\n", " ")))
+	}
+	return strings.Join(out, "\n")
+}
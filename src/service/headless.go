@@ -0,0 +1,401 @@
+// Package service section
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"caos/model"
+)
+
+// Headless errors
+var (
+	errHeadlessEmpty       = errors.New("headless: the engine didn't respond")
+	errHeadlessTemplate    = errors.New("headless: unknown template")
+	errHeadlessRole        = errors.New("headless: unknown role")
+	errHeadlessInstruction = errors.New("headless: the edit needs an instruction")
+	errHeadlessFormat      = errors.New("headless: unknown format, use log, training or text")
+)
+
+// HeadlessOptions - Engine, template, role and sampling parameters, empty values keep the profile defaults
+type HeadlessOptions struct {
+	Engine      string            `json:"engine,omitempty"`
+	Template    string            `json:"template,omitempty"`
+	Variables   map[string]string `json:"variables,omitempty"`
+	Role        string            `json:"role,omitempty"`
+	Temperature *float32          `json:"temperature,omitempty"`
+	TopP        *float32          `json:"top_p,omitempty"`
+	Penalty     *float32          `json:"presence_penalty,omitempty"`
+	Frequency   *float32          `json:"frequency_penalty,omitempty"`
+	Results     int               `json:"n,omitempty"`
+	MaxTokens   int               `json:"max_tokens,omitempty"`
+	IsStreaming bool              `json:"stream,omitempty"`
+	Resume      string            `json:"resume,omitempty"`
+}
+
+// Headless - Controller requests written to an output instead of the console
+type Headless struct {
+	controller *Controller
+	provider   *recordingProvider
+	options    HeadlessOptions
+	out        io.Writer
+}
+
+// AttachHeadlessProfile - Agent of the profile, compatible and replay backends select their first model like the console
+func AttachHeadlessProfile() Agent {
+	var console Controller
+	controller := NewController(console.AttachProfile())
+	if controller.currentAgent.preferences.BaseURL != "" || controller.currentAgent.preferences.ReplayPath != "" {
+		controller.ListModels()
+	}
+	return controller.currentAgent
+}
+
+// NewHeadless - Apply the options to the agent and write the answers of its requests to the output
+func NewHeadless(agent Agent, options HeadlessOptions, out io.Writer) (*Headless, error) {
+	if options.Engine != "" {
+		agent.preferences.Engine = options.Engine
+	}
+	if !agent.selectTemplate(options.Template) {
+		return nil, fmt.Errorf("%w: %v", errHeadlessTemplate, options.Template)
+	}
+	if options.Template != "" || options.Variables != nil {
+		agent.SetTemplateVariables(options.Variables)
+	}
+
+	switch model.Roles(strings.ToLower(options.Role)) {
+	case "":
+	case model.System, model.Assistant, model.User:
+		agent.preferences.Role = model.Roles(strings.ToLower(options.Role))
+	default:
+		return nil, fmt.Errorf("%w: %v", errHeadlessRole, options.Role)
+	}
+
+	if options.Temperature != nil {
+		agent.preferences.Temperature = *options.Temperature
+	}
+	if options.TopP != nil {
+		agent.preferences.Topp = *options.TopP
+	}
+	if options.Penalty != nil {
+		agent.preferences.Penalty = *options.Penalty
+	}
+	if options.Frequency != nil {
+		agent.preferences.Frequency = *options.Frequency
+	}
+	if options.Results > 0 {
+		agent.preferences.Results = int32(options.Results)
+	}
+	if options.MaxTokens > 0 {
+		agent.preferences.MaxTokens = options.MaxTokens
+	}
+	agent.preferences.IsPromptStreaming = options.IsStreaming
+	agent.preferences.IsNewSession = true
+	// Streamed completions of each client are relayed through its own channel
+	agent.preferences.InlineText = make(chan string)
+	// Clients run in parallel, the chain sets the cookie jar of its own external client
+	agent.isolateClient()
+
+	provider := &recordingProvider{Provider: agent.client}
+	agent.SetProvider(provider)
+
+	controller := NewController(agent)
+	if options.Resume != "" {
+		if err := controller.ResumeSession(options.Resume); err != nil {
+			return nil, err
+		}
+		// The engine of the options replaces the one of the session
+		if options.Engine != "" {
+			controller.currentAgent.preferences.Engine = options.Engine
+		}
+	}
+
+	return &Headless{
+		controller: controller,
+		provider:   provider,
+		options:    options,
+		out:        out,
+	}, nil
+}
+
+// getAgent - Agent of the headless controller
+func (c *Headless) getAgent() *Agent {
+	return &c.controller.currentAgent
+}
+
+// describe - Engine, template and turns of the conversation
+func (c *Headless) describe(id string) model.ServerSession {
+	agent := c.getAgent()
+	return model.ServerSession{
+		ID:       id,
+		Engine:   agent.preferences.Engine,
+		Template: agent.SetTemplateParameters(nil).Name,
+		Turns:    len(c.controller.events.GetBranch()),
+	}
+}
+
+// request - Select the mode and engine of the request and clear the previous responses, empty values keep the current ones
+func (c *Headless) request(mode string, engine string) (*Agent, func()) {
+	agent := c.getAgent()
+	if mode != "" {
+		agent.preferences.Mode = mode
+	}
+
+	// The engine of a request doesn't replace the one of the session
+	previous := agent.preferences.Engine
+	isReplaced := c.options.Engine == "" && engine != ""
+	if isReplaced {
+		agent.preferences.Engine = engine
+	}
+
+	c.controller.prompt = &Prompt{writer: c.out}
+	c.provider.err = nil
+	return agent, func() {
+		if isReplaced {
+			agent.preferences.Engine = previous
+		}
+		// The next requests are turns of the same session
+		if c.controller.events.GetPool().Event != nil {
+			agent.preferences.IsNewSession = false
+		}
+	}
+}
+
+// write - Write the choices separated by an empty line
+func (c *Headless) write(choices []string) error {
+	var out []string
+	for _, i := range choices {
+		out = append(out, strings.TrimSpace(i))
+	}
+	_, err := fmt.Fprintln(c.out, strings.Join(out, "\n\n"))
+	return err
+}
+
+// Complete - Send the prompt through the chat or completion request of the engine, streamed tokens are written as they arrive
+func (c *Headless) Complete(prompt string) ([]string, bool, error) {
+	agent, done := c.request("Text", "")
+	defer done()
+	if isChatModel(*agent, agent.preferences.Engine) {
+		agent.preferences.Mode = "Turbo"
+	}
+	agent.setRequest([]string{prompt}, []string{""})
+
+	var choices []string
+	var err error
+	isStreamed := false
+	if agent.preferences.Mode == "Turbo" {
+		c.controller.ChatCompletionRequest()
+		err = c.controller.prompt.err
+		if resp := c.controller.prompt.chatResponse; resp != nil {
+			for _, i := range resp.Choices {
+				choices = append(choices, i.Message.Content)
+			}
+		}
+		if resp := c.controller.prompt.chatStreamResponse; resp != nil {
+			for _, i := range resp.Choices {
+				choices = append(choices, trimSeparator(i.Delta.Content))
+			}
+			isStreamed = true
+		}
+	} else {
+		err = c.controller.CompletionRequest()
+		if resp := c.controller.prompt.contextualResponse; resp != nil {
+			// The streamed choices share the joined text
+			for _, i := range resp.Choices {
+				choices = append(choices, i.Text)
+				if agent.preferences.IsPromptStreaming {
+					break
+				}
+			}
+			isStreamed = agent.preferences.IsPromptStreaming
+		}
+	}
+
+	if c.provider.err != nil {
+		return nil, false, c.provider.err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if choices == nil {
+		return nil, false, errHeadlessEmpty
+	}
+	return choices, isStreamed, nil
+}
+
+// Revise - Apply the instruction to the input with an edit engine
+func (c *Headless) Revise(input string, instruction string) ([]string, error) {
+	if strings.TrimSpace(instruction) == "" {
+		return nil, errHeadlessInstruction
+	}
+
+	agent, done := c.request("Edit", "text-davinci-edit-001")
+	defer done()
+	agent.setRequest([]string{input}, []string{instruction})
+	c.controller.EditRequest()
+
+	if c.provider.err != nil {
+		return nil, c.provider.err
+	}
+
+	var choices []string
+	if resp := c.controller.prompt.extendedResponse; resp != nil {
+		for _, i := range resp.Choices {
+			choices = append(choices, i.Text)
+		}
+	}
+	if choices == nil {
+		return nil, errHeadlessEmpty
+	}
+	return choices, nil
+}
+
+// Embedding - Embedding vectors of the input
+func (c *Headless) Embedding(input string) ([][]float64, error) {
+	agent, done := c.request("Embedded", c.getAgent().preferences.EmbeddingModel)
+	defer done()
+	agent.setRequest([]string{input}, []string{""})
+	c.controller.EmbeddingRequest()
+
+	if c.provider.err != nil {
+		return nil, c.provider.err
+	}
+
+	resp := c.controller.prompt.embeddingResponse
+	if resp == nil || len(resp.Data) == 0 {
+		return nil, errHeadlessEmpty
+	}
+
+	var vectors [][]float64
+	for _, i := range resp.Data {
+		vectors = append(vectors, i.Embedding)
+	}
+	return vectors, nil
+}
+
+// Prediction - Probabilities of the input being generated
+func (c *Headless) Prediction(input string) (*model.PredictResponse, error) {
+	agent, done := c.request("Predicted", "zero-gpt")
+	defer done()
+	agent.setRequest([]string{input}, []string{""})
+	agent.PredictProperties = agent.SetPredictionParameters([]string{input})
+	c.controller.PredictableRequest()
+
+	resp := c.controller.prompt.predictableResponse
+	if resp == nil {
+		return nil, errHeadlessEmpty
+	}
+	return resp, nil
+}
+
+// ListModels - Models available on the backend
+func (c *Headless) ListModels() ([]string, error) {
+	agent, done := c.request("", "")
+	defer done()
+	agent.preferences.Models = nil
+	c.controller.ListModels()
+
+	if c.provider.err != nil {
+		return nil, c.provider.err
+	}
+	return agent.preferences.Models, nil
+}
+
+// Export - Logged events, training sessions of the current branch or transcript of the conversation
+func (c *Headless) Export(id string, format string) (interface{}, error) {
+	switch format {
+	case "", "log":
+		return model.HistoricalSession{ID: id, Session: c.controller.events.GetPool().Event}, nil
+	case "training":
+		return c.controller.events.GetBranchTraining(), nil
+	case "text":
+		return c.getAgent().cachedPrompt, nil
+	}
+	return nil, fmt.Errorf("%w: %v", errHeadlessFormat, format)
+}
+
+// Ask - Write the answer of the prompt, streamed answers are written as they arrive
+func (c *Headless) Ask(prompt string) error {
+	choices, isStreamed, err := c.Complete(prompt)
+	if err != nil {
+		return err
+	}
+	if isStreamed {
+		_, err := fmt.Fprintln(c.out)
+		return err
+	}
+	return c.write(choices)
+}
+
+// Chat - Send each line of the input as a turn of the same conversation
+func (c *Headless) Chat(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), batchLineBytes)
+	for scanner.Scan() {
+		prompt := strings.TrimSpace(scanner.Text())
+		if prompt == "" {
+			continue
+		}
+
+		if err := c.Ask(prompt); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Edit - Write the edited input
+func (c *Headless) Edit(input string, instruction string) error {
+	choices, err := c.Revise(input, instruction)
+	if err != nil {
+		return err
+	}
+	return c.write(choices)
+}
+
+// Embed - Write each embedding vector of the input as a JSON array
+func (c *Headless) Embed(input string) error {
+	vectors, err := c.Embedding(input)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range vectors {
+		raw, _ := json.Marshal(i)
+		if _, err := fmt.Fprintln(c.out, string(raw)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Predict - Write the probabilities of the input being generated as JSON
+func (c *Headless) Predict(input string) error {
+	resp, err := c.Prediction(input)
+	if err != nil {
+		return err
+	}
+
+	raw, _ := json.MarshalIndent(resp, "", "\u0009")
+	_, err = fmt.Fprintln(c.out, string(raw))
+	return err
+}
+
+// Models - Write the models available on the backend, one per line
+func (c *Headless) Models() error {
+	models, err := c.ListModels()
+	if err != nil {
+		return err
+	}
+
+	for _, i := range models {
+		if _, err := fmt.Fprintln(c.out, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
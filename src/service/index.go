@@ -0,0 +1,429 @@
+// Package service section
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"caos/model"
+)
+
+// BM25 ranking parameters
+const (
+	bm25K1       = 1.2
+	bm25B        = 0.75
+	snippetWords = 30
+)
+
+// Saving of the shared index, after an amount of indexed files or an interval
+const (
+	indexSaveFiles    = 20
+	indexSaveInterval = time.Minute
+)
+
+// indexPath - Index file of the stored sessions
+var indexPath = filepath.Join("index", "search.json")
+
+// posting - Term frequency in a document
+type posting struct {
+	document  int
+	frequency int
+}
+
+// SearchIndex - Inverted index over the log and training sessions
+type SearchIndex struct {
+	path      string
+	Files     map[string]int64       `json:"files"`
+	Documents []model.SearchDocument `json:"documents"`
+	postings  map[string][]posting
+	length    int
+}
+
+// OpenSearchIndex - Load the index stored in path, an empty index is created when it doesn't exist
+func OpenSearchIndex(path string) *SearchIndex {
+	index := &SearchIndex{
+		path:  path,
+		Files: make(map[string]int64),
+	}
+
+	raw, err := os.ReadFile(path)
+	if err == nil {
+		json.Unmarshal(raw, index)
+	}
+
+	if index.Files == nil {
+		index.Files = make(map[string]int64)
+	}
+	index.build()
+	return index
+}
+
+// Save - Store the index
+func (c *SearchIndex) Save() error {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, raw, 0644)
+}
+
+// Update - Index the new and modified session files of the directories and drop the removed ones
+func (c *SearchIndex) Update(dirs ...string) int {
+	var updated int
+	isListed := make(map[string]bool)
+	for _, i := range dirs {
+		files, _ := filepath.Glob(filepath.Join(i, "*.json"))
+		for _, j := range files {
+			isListed[j] = true
+			info, err := os.Stat(j)
+			if err != nil || c.Files[j] == info.ModTime().UnixNano() {
+				continue
+			}
+
+			if c.IndexFile(j) == nil {
+				updated++
+			}
+		}
+	}
+
+	var removed []string
+	for i := range c.Files {
+		if !isListed[i] {
+			removed = append(removed, i)
+		}
+	}
+
+	if removed != nil {
+		for _, i := range removed {
+			delete(c.Files, i)
+		}
+		c.remove(removed...)
+		updated += len(removed)
+	}
+	return updated
+}
+
+// IndexFile - Index the events of a log or training session file
+func (c *SearchIndex) IndexFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	documents, err := parseSessionDocuments(path, raw)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if _, isIndexed := c.Files[path]; isIndexed {
+		c.remove(path)
+	}
+	c.Files[path] = info.ModTime().UnixNano()
+
+	for _, i := range documents {
+		c.add(i)
+	}
+	return nil
+}
+
+// parseSessionDocuments - Documents of a historical session object or a list of training sessions
+func parseSessionDocuments(path string, raw []byte) ([]model.SearchDocument, error) {
+	var documents []model.SearchDocument
+	add := func(id string, timestamp string, kind string, fields ...[]string) {
+		var text []string
+		for _, i := range fields {
+			for _, j := range i {
+				if strings.TrimSpace(j) != "" {
+					text = append(text, strings.TrimSpace(j))
+				}
+			}
+		}
+
+		documents = append(documents, model.SearchDocument{
+			ID:        fmt.Sprint(path, "#", len(documents)),
+			Path:      path,
+			SessionID: id,
+			Timestamp: timestamp,
+			Kind:      kind,
+			Text:      strings.Join(text, "\n"),
+		})
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+		var sessions []model.TrainingSession
+		if err := json.Unmarshal(raw, &sessions); err != nil {
+			return nil, err
+		}
+
+		for _, i := range sessions {
+			for _, j := range i.Session {
+				add(i.ID, j.Timestamp, "training", j.Event.Prompt, j.Event.Completion)
+			}
+		}
+		return documents, nil
+	}
+
+	var session model.HistoricalSession
+	if err := json.Unmarshal(raw, &session); err != nil {
+		return nil, err
+	}
+
+	for _, i := range session.Session {
+		add(session.ID, i.Timestamp, "log",
+			i.Event.Body.Input,
+			i.Event.Body.Content,
+			i.Event.Template.PromptValidated.Context,
+			i.Event.Template.PromptValidated.Source)
+	}
+	return documents, nil
+}
+
+// tokenize - Lower case terms of a text
+func tokenize(text string) []string {
+	var terms []string
+	for _, i := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if len([]rune(i)) > 1 {
+			terms = append(terms, i)
+		}
+	}
+	return terms
+}
+
+// add - Append the document postings
+func (c *SearchIndex) add(document model.SearchDocument) {
+	terms := tokenize(document.Text)
+	document.Length = len(terms)
+
+	frequencies := make(map[string]int)
+	for _, i := range terms {
+		frequencies[i]++
+	}
+
+	for term, frequency := range frequencies {
+		c.postings[term] = append(c.postings[term], posting{len(c.Documents), frequency})
+	}
+
+	c.Documents = append(c.Documents, document)
+	c.length += document.Length
+}
+
+// remove - Drop the documents of the files
+func (c *SearchIndex) remove(paths ...string) {
+	isRemoved := make(map[string]bool)
+	for _, i := range paths {
+		isRemoved[i] = true
+	}
+
+	var documents []model.SearchDocument
+	for _, i := range c.Documents {
+		if !isRemoved[i.Path] {
+			documents = append(documents, i)
+		}
+	}
+
+	c.Documents = documents
+	c.build()
+}
+
+// build - Rebuild the postings of the stored documents
+func (c *SearchIndex) build() {
+	documents := c.Documents
+	c.Documents = nil
+	c.postings = make(map[string][]posting)
+	c.length = 0
+	for _, i := range documents {
+		c.add(i)
+	}
+}
+
+// Search - Documents ranked by BM25 with a snippet around the first matching term
+func (c *SearchIndex) Search(query string, limit int) []model.SearchResult {
+	if len(c.Documents) == 0 {
+		return nil
+	}
+
+	terms := tokenize(query)
+	average := float64(c.length) / float64(len(c.Documents))
+	scores := make(map[int]float64)
+
+	isCounted := make(map[string]bool)
+	for _, i := range terms {
+		if isCounted[i] {
+			continue
+		}
+		isCounted[i] = true
+
+		postings := c.postings[i]
+		idf := math.Log(1 + (float64(len(c.Documents))-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+		for _, j := range postings {
+			frequency := float64(j.frequency)
+			norm := 1 - bm25B + bm25B*float64(c.Documents[j.document].Length)/average
+			scores[j.document] += idf * frequency * (bm25K1 + 1) / (frequency + bm25K1*norm)
+		}
+	}
+
+	var results []model.SearchResult
+	for i, score := range scores {
+		results = append(results, model.SearchResult{
+			Document: c.Documents[i],
+			Score:    score,
+		})
+	}
+
+	sort.SliceStable(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].Document.ID < results[b].Document.ID
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	for i := range results {
+		results[i].Snippet = getSnippet(results[i].Document.Text, terms)
+	}
+	return results
+}
+
+// getSnippet - Words around the first matching term of the text
+func getSnippet(text string, terms []string) string {
+	words := strings.Fields(text)
+	isTerm := make(map[string]bool)
+	for _, i := range terms {
+		isTerm[i] = true
+	}
+
+	match := -1
+	for i := 0; i < len(words) && match < 0; i++ {
+		for _, j := range tokenize(words[i]) {
+			if isTerm[j] {
+				match = i
+				break
+			}
+		}
+	}
+
+	start := match - snippetWords/3
+	if start < 0 {
+		start = 0
+	}
+
+	end := start + snippetWords
+	if end > len(words) {
+		end = len(words)
+	}
+
+	snippet := strings.Join(words[start:end], " ")
+	if start > 0 {
+		snippet = fmt.Sprint("...", snippet)
+	}
+	if end < len(words) {
+		snippet = fmt.Sprint(snippet, "...")
+	}
+	return snippet
+}
+
+// searchPaths - Directories of the indexed sessions
+var searchPaths = []string{sessionPath, "training"}
+
+// sessionIndex - Index shared by the console and the headless commands, opened on the first use
+var sessionIndex struct {
+	mutex   sync.Mutex
+	index   *SearchIndex
+	pending int
+	saved   time.Time
+}
+
+// getSessionIndex - Open the stored index and add the sessions stored since it was saved, the caller holds the mutex
+func getSessionIndex() *SearchIndex {
+	if sessionIndex.index == nil {
+		sessionIndex.index = OpenSearchIndex(indexPath)
+		sessionIndex.pending = sessionIndex.index.Update(searchPaths...)
+		sessionIndex.saved = time.Now()
+	}
+	return sessionIndex.index
+}
+
+// saveSessionIndex - Store the index once enough files are pending or the interval passed, the caller holds the mutex
+func saveSessionIndex(isForced bool) error {
+	if sessionIndex.index == nil || sessionIndex.pending == 0 {
+		return nil
+	}
+	if !isForced && sessionIndex.pending < indexSaveFiles && time.Since(sessionIndex.saved) < indexSaveInterval {
+		return nil
+	}
+
+	sessionIndex.pending = 0
+	sessionIndex.saved = time.Now()
+	return sessionIndex.index.Save()
+}
+
+// IndexSession - Index a stored session file, the index is saved periodically
+func IndexSession(path string) error {
+	sessionIndex.mutex.Lock()
+	defer sessionIndex.mutex.Unlock()
+
+	if err := getSessionIndex().IndexFile(path); err != nil {
+		return err
+	}
+	sessionIndex.pending++
+	return saveSessionIndex(false)
+}
+
+// SaveSessionIndex - Store the pending changes of the index, called on exit
+func SaveSessionIndex() error {
+	sessionIndex.mutex.Lock()
+	defer sessionIndex.mutex.Unlock()
+	return saveSessionIndex(true)
+}
+
+// SearchSessions - Update the stored index and rank the sessions matching the query
+func SearchSessions(query string, limit int) []model.SearchResult {
+	sessionIndex.mutex.Lock()
+	defer sessionIndex.mutex.Unlock()
+
+	index := getSessionIndex()
+	sessionIndex.pending += index.Update(searchPaths...)
+	saveSessionIndex(true)
+	return index.Search(query, limit)
+}
+
+// FormatSearchResults - Ranked results with the session, score and snippet
+func FormatSearchResults(results []model.SearchResult) string {
+	if len(results) == 0 {
+		return "No sessions match the query.\n"
+	}
+
+	var out []string
+	for i, j := range results {
+		out = append(out, fmt.Sprintf("%v. [%.3f] %v %v (%v)\n   %v\n",
+			i+1,
+			j.Score,
+			formatTimestamp(j.Document.Timestamp),
+			j.Document.Kind,
+			j.Document.Path,
+			strings.ReplaceAll(j.Snippet, "\n", " ")))
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,220 @@
+// Package service section
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"caos/model"
+	"caos/store"
+
+	"golang.org/x/net/html"
+)
+
+// Passages retrieved for each question
+const knowledgePassages = 5
+
+// Metadata of the local document passages
+const (
+	knowledgeKind = "knowledge"
+	baseKey       = "base"
+)
+
+// knowledgeExtensions - Document formats ingested in the knowledge base
+var knowledgeExtensions = map[string]bool{
+	".txt":  true,
+	".md":   true,
+	".html": true,
+	".go":   true,
+}
+
+// KnowledgeBase - Passages of the documents in a local directory kept in the vector store
+type KnowledgeBase struct {
+	path  string
+	store *store.VectorStore
+}
+
+// OpenKnowledgeBase - Knowledge base of the directory with the vectors persisted in storePath
+func OpenKnowledgeBase(path string, storePath string) (*KnowledgeBase, error) {
+	vectors, err := store.Open(storePath)
+	if err != nil {
+		return nil, err
+	}
+	return &KnowledgeBase{path: filepath.Clean(path), store: vectors}, nil
+}
+
+// Save - Persist the vector store
+func (c *KnowledgeBase) Save() error {
+	return c.store.Save()
+}
+
+// getFilter - Filter of the passages embedded with the model
+func (c *KnowledgeBase) getFilter(engine string) store.Filter {
+	return store.Filter{
+		Metadata: map[string]string{
+			store.KindKey:  knowledgeKind,
+			store.ModelKey: engine,
+			baseKey:        c.path,
+		},
+	}
+}
+
+// Update - Chunk and embed the new and modified documents with the model and drop the removed ones
+func (c *KnowledgeBase) Update(service Agent, engine string) (int, error) {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return 0, err
+	}
+	if !info.IsDir() {
+		return 0, fmt.Errorf("knowledge: %v is not a directory", c.path)
+	}
+
+	files := make(map[string]string)
+	for _, i := range c.store.List(c.getFilter(engine)) {
+		files[i.Metadata[store.SourceKey]] = i.Metadata[modifiedKey]
+	}
+
+	var updated int
+	var pending []model.VectorDocument
+	isListed := make(map[string]bool)
+	filepath.WalkDir(c.path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !knowledgeExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+
+		isListed[path] = true
+		modified := fmt.Sprint(info.ModTime().UnixNano())
+		if files[path] == modified {
+			return nil
+		}
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		c.deleteSource(engine, path)
+		pending = append(pending, c.parseDocument(path, modified, engine, info.ModTime().UTC(), raw)...)
+		updated++
+		return nil
+	})
+
+	for i := range files {
+		if !isListed[i] {
+			c.deleteSource(engine, i)
+			updated++
+		}
+	}
+
+	return updated, embedDocuments(service, engine, c.store, pending)
+}
+
+// deleteSource - Drop the passages of a document embedded with the model
+func (c *KnowledgeBase) deleteSource(engine string, path string) {
+	filter := c.getFilter(engine)
+	filter.Metadata[store.SourceKey] = path
+	c.store.DeleteMatching(filter)
+}
+
+// parseDocument - Passages of a document waiting for their vectors
+func (c *KnowledgeBase) parseDocument(path string, modified string, engine string, date time.Time, raw []byte) []model.VectorDocument {
+	text := string(raw)
+	if strings.EqualFold(filepath.Ext(path), ".html") {
+		text = extractText(raw)
+	}
+
+	var documents []model.VectorDocument
+	for i, j := range splitPassages([]string{text}) {
+		documents = append(documents, model.VectorDocument{
+			ID:   fmt.Sprint(engine, ":", path, "#", i),
+			Text: j,
+			Metadata: map[string]string{
+				store.KindKey:   knowledgeKind,
+				store.ModelKey:  engine,
+				store.SourceKey: path,
+				baseKey:         c.path,
+				modifiedKey:     modified,
+			},
+			Date: date,
+		})
+	}
+	return documents
+}
+
+// extractText - Visible text of an HTML document
+func extractText(raw []byte) string {
+	var text []string
+	var skip int
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(raw))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return strings.Join(text, " ")
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "script" || string(name) == "style" {
+				skip++
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); (string(name) == "script" || string(name) == "style") && skip > 0 {
+				skip--
+			}
+		case html.TextToken:
+			if content := strings.TrimSpace(string(tokenizer.Text())); skip == 0 && content != "" {
+				text = append(text, content)
+			}
+		}
+	}
+}
+
+// Retrieve - Passages closest to the query embedded with the model
+func (c *KnowledgeBase) Retrieve(service Agent, engine string, query string, k int) ([]model.VectorResult, error) {
+	vectors, err := embedTexts(service, engine, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return c.store.Search(vectors[0], k, c.getFilter(engine)), nil
+}
+
+// GetKnowledgeContext - Update the knowledge base and retrieve the documents and passages for the question
+func GetKnowledgeContext(service Agent, query string) ([]string, []string, error) {
+	engine := service.preferences.EmbeddingModel
+	base, err := OpenKnowledgeBase(service.preferences.KnowledgePath, embeddingPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updated, err := base.Update(service, engine)
+	if updated > 0 {
+		base.Save()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results, err := base.Retrieve(service, engine, query, knowledgePassages)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var sources, context []string
+	isListed := make(map[string]bool)
+	for _, i := range results {
+		context = append(context, i.Document.Text)
+		if source := i.Document.Metadata[store.SourceKey]; !isListed[source] {
+			isListed[source] = true
+			sources = append(sources, source)
+		}
+	}
+	return sources, context, nil
+}
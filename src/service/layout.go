@@ -25,9 +25,31 @@ type Layout struct {
 	// Flex
 	consoleView  *tview.Grid
 	affinityView *tview.Grid
+	sessionView  *tview.Grid
+	choiceView   *tview.Grid
+	arenaView    *tview.Grid
+	variableView *tview.Grid
 	// User form
 	refinementInput *tview.Form
 	detailsInput    *tview.Form
+	sessionInput    *tview.Form
+	// Session browser
+	sessionList    *tview.List
+	sessionPreview *tview.TextView
+	sessions       []model.StoredSession
+	// Choice comparison
+	choiceColumns *tview.Flex
+	choiceInput   *tview.Form
+	choiceIndex   int
+	// Model arena
+	arenaInput  *tview.Form
+	arenaPrompt *tview.TextArea
+	arenaOutput []*tview.TextView
+	arenaBoard  *tview.TextView
+	arenaModels []string
+	arenaMatch  model.ArenaMatch
+	// Template variables
+	variableInput *tview.Form
 	// User modal
 	modalInput *tview.Modal
 	// User input
@@ -68,11 +90,52 @@ func onFrequencyChange(text string) {
 	node.controller.currentAgent.preferences.Frequency = util.ParseFloat32(text)
 }
 
+// onBaseURLChange - Evaluates when an input text changes for the base URL input field
+func onBaseURLChange(text string) {
+	node.controller.currentAgent.preferences.BaseURL = strings.TrimSpace(text)
+}
+
+// onSummaryThresholdChange - Evaluates when an input text changes for the summary threshold input field
+func onSummaryThresholdChange(text string) {
+	node.controller.currentAgent.preferences.SummaryThreshold = int(util.ParseInt32(text))
+}
+
+// onSummaryModelChange - Evaluates when an input text changes for the summary model input field
+func onSummaryModelChange(text string) {
+	node.controller.currentAgent.preferences.SummaryModel = strings.TrimSpace(text)
+}
+
+// onKnowledgePathChange - Evaluates when an input text changes for the knowledge base input field
+func onKnowledgePathChange(text string) {
+	node.controller.currentAgent.preferences.KnowledgePath = strings.TrimSpace(text)
+}
+
+// onSchemaPathChange - Evaluates when an input text changes for the JSON schema input field
+func onSchemaPathChange(text string) {
+	node.controller.currentAgent.preferences.SchemaPath = strings.TrimSpace(text)
+}
+
+// onResearchStepsChange - Evaluates when an input text changes for the research steps input field
+func onResearchStepsChange(text string) {
+	node.controller.currentAgent.preferences.ResearchSteps = int(util.ParseInt32(text))
+}
+
+// onResearchBudgetChange - Evaluates when an input text changes for the research budget input field
+func onResearchBudgetChange(text string) {
+	node.controller.currentAgent.preferences.ResearchBudget = int(util.ParseInt32(text))
+}
+
 // onTemplateChange - Template dropdown selection
 func onTemplateChange(option string, index int) {
 	if node.controller.currentAgent.preferences.Template != index {
 		node.controller.currentAgent.preferences.Template = index
+		node.controller.currentAgent.SetTemplateVariables(nil)
+		// The training modal opens the variables view after it's closed
+		isEmpty := node.layout.promptOutput.GetText(true) == ""
 		onNewTopic()
+		if isEmpty && isTemplateUnfilled() {
+			onTemplateVariables()
+		}
 	}
 }
 
@@ -102,6 +165,7 @@ func onNewTopic() {
 	node.controller.currentAgent.preferences.IsPromptReady = false
 	node.controller.currentAgent.preferences.PromptCtx = []string{""}
 	node.controller.currentAgent.cachedPrompt = ""
+	node.controller.currentAgent.ClearMessages()
 	if node.layout.promptOutput.GetText(true) == "" {
 		// Clear console view
 		clearConsoleView()
@@ -136,6 +200,62 @@ func onRefinement() {
 	returnToPage(2)
 }
 
+// onSessions - Session browser view event
+func onSessions() {
+	// Session view
+	refreshSessions()
+	returnToPage(4)
+}
+
+// onChoices - Choice comparison view event
+func onChoices() {
+	if _, isRated := node.controller.events.GetRating(); !isRated {
+		node.layout.infoOutput.SetText("Request several results in the configuration menu to compare the choices.")
+		return
+	}
+
+	// Choice view
+	node.layout.choiceIndex = 0
+	refreshChoices()
+	returnToPage(5)
+	node.layout.app.SetFocus(node.layout.choiceColumns)
+}
+
+// isTemplateUnfilled - Evaluates when the selected template has placeholders without values
+func isTemplateUnfilled() bool {
+	variables, _ := node.controller.currentAgent.GetTemplateVariables()
+	return len(variables) > 0 && len(node.controller.currentAgent.variables) == 0
+}
+
+// onTemplateVariables - Template variables view event
+func onTemplateVariables() {
+	// Variables view
+	refreshTemplateVariables()
+	returnToPage(7)
+	node.layout.app.SetFocus(node.layout.variableInput)
+}
+
+// onTemplateVariablesSave - Button event to keep the filled template variables
+func onTemplateVariablesSave() {
+	variables, _ := node.controller.currentAgent.GetTemplateVariables()
+	values := make(map[string]string)
+	for _, i := range variables {
+		if field, isField := node.layout.variableInput.GetFormItemByLabel(i).(*tview.InputField); isField {
+			values[i] = field.GetText()
+		}
+	}
+
+	node.controller.currentAgent.SetTemplateVariables(values)
+	// Empty fields are shown with their default
+	var filled []string
+	values = node.controller.currentAgent.SetTemplateParameters(nil).Variables
+	for _, i := range variables {
+		filled = append(filled, fmt.Sprint(i, ": ", values[i]))
+	}
+	node.layout.infoOutput.SetText(fmt.Sprint("Template variables - ", strings.Join(filled, ", ")))
+	onConsole()
+}
+
 // OnModal - Modal confirmation to export training
 func OnModal() {
 	// Training modal view
@@ -159,14 +279,149 @@ func onExportTrainedTopic() {
 		node.layout.infoOutput.SetText("You don't have any interaction to be exported...")
 		return
 	}
-	// Event training
+	// Event training of the selected branch
 	var event EventManager
-	event.ExportTraining(node.controller.events.pool.TrainingSession)
+	event.ExportTraining(node.controller.events.GetBranchTraining())
+	// Rated choices
+	preferences := event.ExportPreferences()
 	// Clear console
 	clearConsoleView()
+	if preferences != "" {
+		node.layout.infoOutput.SetText(fmt.Sprint("Training session and preferences exported to ", preferences, ", you can continue with a new conversation."))
+		return
+	}
 	node.layout.infoOutput.SetText("Training session exported, you can continue with a new conversation.")
 }
 
+// onExportStructured - Export the validated structured output as a .json file
+func onExportStructured() {
+	if node.controller.currentAgent.PromptProperties.Content == nil || node.controller.currentAgent.preferences.SchemaPath == "" {
+		node.layout.infoOutput.SetText("Attach a JSON schema and send a prompt to export the structured output...")
+		return
+	}
+
+	var event EventManager
+	path := event.ExportStructured(node.controller.currentAgent.PromptProperties.Content[0])
+	node.layout.infoOutput.SetText(fmt.Sprint("Structured output exported to ", path))
+}
+
+// onForkTurn - Continue the conversation from the previous turn in a new branch
+func onForkTurn() {
+	if !node.controller.ForkTurn() {
+		node.layout.infoOutput.SetText("There is no previous turn to fork the conversation.")
+		return
+	}
+
+	node.layout.promptOutput.SetText(node.controller.currentAgent.cachedPrompt)
+	node.layout.infoOutput.SetText(fmt.Sprintf("New branch after %v turns, the next prompt continues from here.", len(node.controller.events.GetBranch())))
+}
+
+// onSwitchBranch - Move to the previous or next sibling branch of the current turn
+func onSwitchBranch(offset int) {
+	position, total := node.controller.SwitchBranch(offset)
+	if total == 0 {
+		node.layout.infoOutput.SetText("There are no branches in the current conversation.")
+		return
+	}
+
+	node.layout.promptOutput.SetText(node.controller.currentAgent.cachedPrompt)
+	node.layout.infoOutput.SetText(fmt.Sprintf("Branch %v/%v with %v turns.", position, total, len(node.controller.events.GetBranch())))
+}
+
+// onResumeSession - Continue the conversation stored in a log file
+func onResumeSession(path string) {
+	var session EventManager
+	if err := session.LoadSession(path); err != nil {
+		node.layout.infoOutput.SetText(fmt.Sprint("The session can't be resumed: ", err))
+		return
+	}
+
+	branch := session.GetBranch()
+	if len(branch) == 0 {
+		node.layout.infoOutput.SetText(fmt.Sprint("The session can't be resumed: ", errEmptySession))
+		return
+	}
+
+	// Engine selection starts a new topic, select it before restoring the conversation
+	engine := node.layout.detailsInput.GetFormItem(1).(*tview.DropDown)
+	engine.SetCurrentOption(validateSelector(branch[len(branch)-1].Event.Event.Header.Model))
+
+	node.controller.resumeEvents(session)
+	_, node.controller.currentAgent.preferences.Engine = engine.GetCurrentOption()
+	node.layout.promptOutput.SetText(node.controller.currentAgent.cachedPrompt)
+	node.layout.infoOutput.SetText(fmt.Sprintf("Session resumed with %v turns, the next prompt continues the conversation.", len(node.controller.events.GetBranch())))
+}
+
+// searchCommand - Prompt prefix of the full-text search over the stored sessions
+const searchCommand = "/search "
+
+// onSearchCommand - Show the stored sessions ranked for the query
+func onSearchCommand(query string) {
+	if strings.TrimSpace(query) == "" {
+		node.layout.infoOutput.SetText("Type a query after the search command.")
+		return
+	}
+
+	results := SearchSessions(query, 10)
+	node.layout.promptOutput.SetText(FormatSearchResults(results)).ScrollToBeginning()
+	node.layout.infoOutput.SetText(fmt.Sprintf("%v sessions found for: %v", len(results), strings.TrimSpace(query)))
+}
+
+// similarCommand - Prompt prefix of the semantic search over the stored sessions
+const similarCommand = "/similar "
+
+// onSimilarCommand - Show the stored sessions close to the query by meaning
+func onSimilarCommand(query string) {
+	if strings.TrimSpace(query) == "" {
+		node.layout.infoOutput.SetText("Type a query after the similar command.")
+		return
+	}
+
+	results, err := FindSimilarSessions(node.controller.currentAgent, query, 5)
+	if err != nil {
+		node.layout.infoOutput.SetText(err.Error())
+		return
+	}
+
+	node.layout.promptOutput.SetText(FormatSimilarResults(results)).ScrollToBeginning()
+	node.layout.infoOutput.SetText(fmt.Sprintf("%v similar conversations found for: %v", len(results), strings.TrimSpace(query)))
+}
+
+// researchCommand - Prompt prefix of the research agent
+const researchCommand = "/research "
+
+// onResearchCommand - Research the question showing the steps in the details section
+func onResearchCommand(question string) {
+	if strings.TrimSpace(question) == "" {
+		node.layout.infoOutput.SetText("Type a question after the research command.")
+		return
+	}
+
+	if node.controller.currentAgent.preferences.Mode != "Turbo" {
+		node.layout.infoOutput.SetText("Select a chat model to start a research.")
+		return
+	}
+
+	if node.controller.currentAgent.preferences.ResearchSteps < 1 || node.controller.currentAgent.preferences.ResearchBudget < 1 {
+		node.layout.infoOutput.SetText("Set the research steps and token budget greater than 0.")
+		return
+	}
+
+	if node.controller.currentAgent.preferences.IsLoading {
+		return
+	}
+
+	// The steps are drawn while the research runs, the event loop isn't blocked
+	node.controller.currentAgent.preferences.IsLoading = true
+	node.layout.infoOutput.SetText("Researching...")
+	go func() {
+		node.controller.ResearchRequest(strings.TrimSpace(question))
+		queueLayout(func() {
+			node.controller.currentAgent.preferences.IsLoading = false
+		})
+	}()
+}
+
 // onChangeRoles - Dropdown from input to change role
 func onChangeRoles(option string, optionIndex int) {
 	if strings.Contains(option, string(model.User)) {
@@ -214,6 +469,8 @@ func onChangeEngine(option string, optionIndex int) {
 	} else if strings.Contains(option, "zero") {
 		node.controller.currentAgent.preferences.Mode = "Predicted"
 		node.layout.promptArea.SetLabel("Enter the text that you want to analyze for AI plagiarism: ")
+	} else if node.controller.currentAgent.preferences.BaseURL != "" || node.controller.currentAgent.preferences.ReplayPath != "" {
+		node.controller.currentAgent.preferences.Mode = "Turbo"
 	} else {
 		node.controller.currentAgent.preferences.Mode = "NOT_SUPPORTED"
 	}
@@ -282,6 +539,7 @@ func onTextAccept(key tcell.Key) {
 
 	if key == tcell.KeyCtrlSpace &&
 		!node.controller.currentAgent.preferences.IsLoading {
+		previous, _ := node.controller.events.GetRating()
 		group.Add(1)
 		go func() {
 			defer group.Done()
@@ -323,6 +581,11 @@ func onTextAccept(key tcell.Key) {
 		if node.controller.currentAgent.preferences.IsNewSession {
 			node.controller.currentAgent.preferences.IsNewSession = false
 		}
+
+		// Several choices are compared side by side
+		if rating, isRated := node.controller.events.GetRating(); isRated && (rating.ID != previous.ID || rating.Timestamp != previous.Timestamp) {
+			onChoices()
+		}
 	}
 }
 
@@ -344,6 +607,12 @@ func onStreamingChecked(state bool) {
 	onNewTopic()
 }
 
+// onToolChecked - Let the chat models call the tools instead of searching the web up front
+func onToolChecked(state bool) {
+	node.controller.currentAgent.preferences.IsToolEnabled = state
+	onNewTopic()
+}
+
 // validateSelector - Validate the selected engine
 func validateSelector(engine string) int {
 	var index int
@@ -365,6 +634,7 @@ func validateRefinementForm() {
 	penaltyInput := node.layout.refinementInput.GetFormItem(4).(*tview.InputField)
 	frequencyInput := node.layout.refinementInput.GetFormItem(5).(*tview.InputField)
 	keyInput := node.layout.refinementInput.GetFormItem(6).(*tview.InputField)
+	summaryInput := node.layout.refinementInput.GetFormItem(8).(*tview.InputField)
 
 	if !util.MatchNumber(resultInput.GetText()) {
 		resultInput.SetText("\u0031")
@@ -390,6 +660,10 @@ func validateRefinementForm() {
 		frequencyInput.SetText("\u0030\u002e\u0035")
 	}
 
+	if !util.MatchNumber(summaryInput.GetText()) {
+		summaryInput.SetText("\u0036")
+	}
+
 	// Validate Key
 	file, _ := resources.Asset.Open("template/.env")
 	if file != nil {
@@ -400,6 +674,41 @@ func validateRefinementForm() {
 			node.controller.currentAgent.key = append(node.controller.currentAgent.key, keyInput.GetText())
 		}
 	}
+
+	// Validate backend
+	if node.controller.currentAgent.clientURL != node.controller.currentAgent.preferences.BaseURL {
+		node.controller.currentAgent.Connect()
+		refreshModels()
+	}
+}
+
+// refreshModels - Reload the models served by the current backend into the engine dropdown
+func refreshModels() {
+	node.controller.currentAgent.preferences.Models = []string{"zero-gpt"}
+	node.controller.ListModels()
+
+	engine := node.layout.detailsInput.GetFormItem(1).(*tview.DropDown)
+	engine.SetOptions(node.controller.currentAgent.preferences.Models, onChangeEngine)
+	engine.SetCurrentOption(validateSelector(node.controller.currentAgent.preferences.Engine))
+	refreshArenaModels()
+}
+
+// refreshArenaModels - Reload the arena engine dropdowns, the selected engines are kept when they're still served
+func refreshArenaModels() {
+	models := GetArenaModels(node.controller.currentAgent.preferences.Models)
+	defaults := []int{0, len(models) - 1}
+	for slot := range node.layout.arenaModels {
+		current := defaults[slot]
+		for i := range models {
+			if models[i] == node.layout.arenaModels[slot] {
+				current = i
+			}
+		}
+
+		engine := node.layout.arenaInput.GetFormItem(slot).(*tview.DropDown)
+		engine.SetOptions(models, onArenaModel(slot))
+		engine.SetCurrentOption(current)
+	}
 }
 
 // returnToPage - Switch to page according to their index
@@ -409,18 +718,58 @@ func returnToPage(index int) {
 		node.layout.pages.ShowPage("console")
 		node.layout.pages.HidePage("refinement")
 		node.layout.pages.HidePage("training")
+		node.layout.pages.HidePage("sessions")
+		node.layout.pages.HidePage("choices")
+		node.layout.pages.HidePage("arena")
+		node.layout.pages.HidePage("variables")
 	case 2:
 		node.layout.pages.HidePage("console")
 		node.layout.pages.ShowPage("refinement")
 		node.layout.pages.HidePage("training")
+		node.layout.pages.HidePage("sessions")
+		node.layout.pages.HidePage("choices")
+		node.layout.pages.HidePage("arena")
+		node.layout.pages.HidePage("variables")
 	case 3:
 		node.layout.pages.HidePage("console")
 		node.layout.pages.HidePage("refinement")
 		node.layout.pages.ShowPage("training")
+		node.layout.pages.HidePage("sessions")
+		node.layout.pages.HidePage("choices")
+		node.layout.pages.HidePage("arena")
+		node.layout.pages.HidePage("variables")
 	case 4:
 		node.layout.pages.HidePage("console")
 		node.layout.pages.HidePage("refinement")
 		node.layout.pages.HidePage("training")
+		node.layout.pages.ShowPage("sessions")
+		node.layout.pages.HidePage("choices")
+		node.layout.pages.HidePage("arena")
+		node.layout.pages.HidePage("variables")
+	case 5:
+		node.layout.pages.HidePage("console")
+		node.layout.pages.HidePage("refinement")
+		node.layout.pages.HidePage("training")
+		node.layout.pages.HidePage("sessions")
+		node.layout.pages.ShowPage("choices")
+		node.layout.pages.HidePage("arena")
+		node.layout.pages.HidePage("variables")
+	case 6:
+		node.layout.pages.HidePage("console")
+		node.layout.pages.HidePage("refinement")
+		node.layout.pages.HidePage("training")
+		node.layout.pages.HidePage("sessions")
+		node.layout.pages.HidePage("choices")
+		node.layout.pages.ShowPage("arena")
+		node.layout.pages.HidePage("variables")
+	case 7:
+		node.layout.pages.HidePage("console")
+		node.layout.pages.HidePage("refinement")
+		node.layout.pages.HidePage("training")
+		node.layout.pages.HidePage("sessions")
+		node.layout.pages.HidePage("choices")
+		node.layout.pages.HidePage("arena")
+		node.layout.pages.ShowPage("variables")
 	}
 }
 
@@ -522,7 +871,7 @@ func createConsoleView() bool {
 	// help
 	helpOutput := tview.NewTextView()
 	helpOutput.
-		SetText("Press CTRL+SPACE or CMD+SPACE to send the prompt.\nPress CTRL+C or CMD+Q to exit from the application.\nGo to fullscreen for advanced options.").
+		SetText("Press CTRL+SPACE or CMD+SPACE to send the prompt, start it with /search or /similar to search the stored sessions, /research to research a question on the web.\nPress CTRL+R to fork from the previous turn, CTRL+P or CTRL+N to switch branches.\nPress CTRL+C or CMD+Q to exit from the application.\nGo to fullscreen for advanced options.").
 		SetTextAlign(tview.AlignRight).
 		SetBackgroundColor(tcell.ColorBlack)
 	// Layout
@@ -530,13 +879,17 @@ func createConsoleView() bool {
 
 	node.layout.detailsInput.
 		AddTextView("Mode", "", 15, 2, true, false).
-		AddDropDown("Engine", node.controller.currentAgent.preferences.Models, 11, onChangeEngine).
+		AddDropDown("Engine", node.controller.currentAgent.preferences.Models, validateSelector(node.controller.currentAgent.preferences.Engine), onChangeEngine).
 		AddDropDown("Role", node.controller.currentAgent.preferences.Roles, 1, onChangeRoles).
 		AddDropDown("Template", node.controller.currentAgent.templateID, 0, onTemplateChange).
 		AddButton("Configuration", onRefinement).
+		AddButton("Sessions", onSessions).
+		AddButton("Choices", onChoices).
+		AddButton("Arena", onArena).
 		AddButton("New conversation", onNewTopic).
 		AddButton("Export conversation", onExportTopic).
 		AddButton("Export training", onExportTrainedTopic).
+		AddButton("Export JSON", onExportStructured).
 		SetHorizontal(true).
 		SetLabelColor(tcell.Color105).
 		SetFieldBackgroundColor(tcell.Color100).
@@ -569,6 +922,24 @@ func createConsoleView() bool {
 	ddT.SetListStyles(tcell.StyleDefault.Background(tcell.Color100), tcell.StyleDefault.Background(tcell.Color101))
 	// Key event
 	_ = node.layout.promptArea.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyCtrlSpace && strings.HasPrefix(node.layout.promptArea.GetText(), searchCommand) {
+			onSearchCommand(strings.TrimPrefix(node.layout.promptArea.GetText(), searchCommand))
+			node.layout.promptArea.SetText("", true)
+			return nil
+		}
+
+		if event.Key() == tcell.KeyCtrlSpace && strings.HasPrefix(node.layout.promptArea.GetText(), researchCommand) {
+			onResearchCommand(strings.TrimPrefix(node.layout.promptArea.GetText(), researchCommand))
+			node.layout.promptArea.SetText("", true)
+			return nil
+		}
+
+		if event.Key() == tcell.KeyCtrlSpace && strings.HasPrefix(node.layout.promptArea.GetText(), similarCommand) {
+			onSimilarCommand(strings.TrimPrefix(node.layout.promptArea.GetText(), similarCommand))
+			node.layout.promptArea.SetText("", true)
+			return nil
+		}
+
 		if event.Key() == tcell.KeyCtrlSpace {
 			if onTextChange(node.layout.promptArea.GetText(), rune(event.Key())) {
 				onTextAccept(event.Key())
@@ -576,6 +947,22 @@ func createConsoleView() bool {
 				return nil
 			}
 		}
+
+		if node.controller.currentAgent.preferences.IsLoading {
+			return event
+		}
+
+		switch event.Key() {
+		case tcell.KeyCtrlR:
+			onForkTurn()
+			return nil
+		case tcell.KeyCtrlP:
+			onSwitchBranch(-1)
+			return nil
+		case tcell.KeyCtrlN:
+			onSwitchBranch(1)
+			return nil
+		}
 		return event
 	})
 	// Console
@@ -606,8 +993,16 @@ func createRefinementView() bool {
 			node.controller.currentAgent.key[0] = textToCheck
 			return true
 		}, nil).
+		AddInputField("Base URL (OpenAI-compatible server): ", node.controller.currentAgent.preferences.BaseURL, 60, nil, onBaseURLChange).
+		AddInputField("Summary threshold [exchanges, 0 disabled]: ", fmt.Sprintf("%v", node.controller.currentAgent.preferences.SummaryThreshold), 5, onTypeAccept, onSummaryThresholdChange).
+		AddInputField("Summary model (empty for the current engine): ", node.controller.currentAgent.preferences.SummaryModel, 30, nil, onSummaryModelChange).
+		AddInputField("Knowledge base (local documents, empty for web search): ", node.controller.currentAgent.preferences.KnowledgePath, 60, nil, onKnowledgePathChange).
+		AddInputField("JSON schema file (structured output on Turbo mode): ", node.controller.currentAgent.preferences.SchemaPath, 60, nil, onSchemaPathChange).
+		AddInputField("Research steps: ", fmt.Sprintf("%v", node.controller.currentAgent.preferences.ResearchSteps), 5, onTypeAccept, onResearchStepsChange).
+		AddInputField("Research token budget: ", fmt.Sprintf("%v", node.controller.currentAgent.preferences.ResearchBudget), 8, onTypeAccept, onResearchBudgetChange).
 		AddCheckbox("Edit mode (edit and improve the previous response)", false, onEditChecked).
 		AddCheckbox("Streaming mode (on Text and Turbo mode only)", true, onStreamingChecked).
+		AddCheckbox("Tools mode (web search, file read, calculator and time on Turbo mode only)", false, onToolChecked).
 		AddButton("Back to chat", onBack).
 		SetFieldBackgroundColor(tcell.ColorGray).
 		SetButtonBackgroundColor(tcell.ColorDarkOliveGreen).
@@ -638,6 +1033,563 @@ func createRefinementView() bool {
 	return node.layout.affinityView != nil
 }
 
+// refreshSessions - Reload the stored sessions matching the filter into the session list
+func refreshSessions() {
+	filter := node.layout.sessionInput.GetFormItem(0).(*tview.InputField)
+	node.layout.sessions = FilterSessions(ListSessions(sessionPath), filter.GetText())
+
+	node.layout.sessionList.Clear()
+	for _, i := range node.layout.sessions {
+		title := i.Name
+		if title == "" {
+			title = i.Prompt
+		}
+
+		node.layout.sessionList.AddItem(
+			fmt.Sprintf("%v | %v | %v | %v turns", formatTimestamp(i.Timestamp), i.Model, i.Template, i.Turns),
+			title, 0, nil)
+	}
+
+	node.layout.sessionPreview.SetText("")
+	if len(node.layout.sessions) == 0 {
+		node.layout.sessionPreview.SetText("No sessions stored in the log directory.")
+		return
+	}
+	onSessionChange(node.layout.sessionList.GetCurrentItem(), "", "", 0)
+}
+
+// getSelectedSession - Session selected in the session list
+func getSelectedSession() (model.StoredSession, bool) {
+	index := node.layout.sessionList.GetCurrentItem()
+	if index < 0 || index >= len(node.layout.sessions) {
+		return model.StoredSession{}, false
+	}
+	return node.layout.sessions[index], true
+}
+
+// onSessionChange - Preview the conversation of the selected session
+func onSessionChange(index int, mainText string, secondaryText string, shortcut rune) {
+	if index < 0 || index >= len(node.layout.sessions) {
+		return
+	}
+
+	var session EventManager
+	if err := session.LoadSession(node.layout.sessions[index].Path); err != nil {
+		node.layout.sessionPreview.SetText(fmt.Sprint("The session can't be loaded: ", err))
+		return
+	}
+
+	var preview []string
+	for _, i := range session.GetBranch() {
+		body := i.Event.Event.Body
+		if body.Input == nil {
+			continue
+		}
+		preview = append(preview, fmt.Sprint("> ", body.Input[0], "\n", trimSeparator(strings.Join(body.Content, "")), "\n"))
+	}
+	node.layout.sessionPreview.SetText(strings.Join(preview, "\n")).ScrollToBeginning()
+}
+
+// onSessionOpen - Load the selected session back into the console view
+func onSessionOpen() {
+	stored, isSelected := getSelectedSession()
+	if !isSelected {
+		return
+	}
+
+	onConsole()
+	onResumeSession(stored.Path)
+}
+
+// onSessionRename - Rename the selected session with the name field
+func onSessionRename() {
+	stored, isSelected := getSelectedSession()
+	name := node.layout.sessionInput.GetFormItem(1).(*tview.InputField)
+	if !isSelected || strings.TrimSpace(name.GetText()) == "" {
+		return
+	}
+
+	if err := RenameSession(stored, name.GetText()); err != nil {
+		node.layout.sessionPreview.SetText(fmt.Sprint("The session can't be renamed: ", err))
+		return
+	}
+
+	name.SetText("")
+	refreshSessions()
+}
+
+// onSessionDelete - Remove the log files of the selected session
+func onSessionDelete() {
+	stored, isSelected := getSelectedSession()
+	if !isSelected {
+		return
+	}
+
+	if err := DeleteSession(stored); err != nil {
+		node.layout.sessionPreview.SetText(fmt.Sprint("The session can't be deleted: ", err))
+		return
+	}
+	refreshSessions()
+}
+
+// createSessionView - Creates session browser page view
+func createSessionView() bool {
+	// Session list
+	node.layout.sessionList = tview.NewList()
+	node.layout.sessionList.
+		SetChangedFunc(onSessionChange).
+		SetSelectedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
+			onSessionOpen()
+		}).
+		SetMainTextColor(tcell.ColorDarkCyan).
+		SetSecondaryTextColor(tcell.ColorDarkGray).
+		SetSelectedBackgroundColor(tcell.ColorDarkOliveGreen).
+		SetBorder(true).
+		SetBorderColor(tcell.ColorDarkCyan).
+		SetTitle("Sessions").
+		SetTitleColor(tcell.ColorDarkOliveGreen).
+		SetTitleAlign(tview.AlignLeft).
+		SetBackgroundColor(tcell.ColorBlack)
+	// Preview
+	node.layout.sessionPreview = tview.NewTextView()
+	node.layout.sessionPreview.
+		SetWordWrap(true).
+		SetScrollable(true).
+		SetBorder(true).
+		SetBorderColor(tcell.ColorDarkOliveGreen).
+		SetBorderPadding(1, 1, 2, 2).
+		SetTitle("Preview").
+		SetTitleColor(tcell.ColorDarkTurquoise).
+		SetTitleAlign(tview.AlignLeft).
+		SetBackgroundColor(tcell.ColorBlack)
+	// Actions
+	node.layout.sessionInput = tview.NewForm()
+	node.layout.sessionInput.
+		AddInputField("Filter: ", "", 30, nil, func(text string) {
+			refreshSessions()
+		}).
+		AddInputField("Name: ", "", 30, nil, nil).
+		AddButton("Open", onSessionOpen).
+		AddButton("Rename", onSessionRename).
+		AddButton("Delete", onSessionDelete).
+		AddButton("Back to chat", onConsole).
+		SetHorizontal(true).
+		SetLabelColor(tcell.Color105).
+		SetFieldBackgroundColor(tcell.Color100).
+		SetFieldTextColor(tcell.ColorBlack).
+		SetButtonBackgroundColor(tcell.ColorDarkOliveGreen).
+		SetButtonsAlign(tview.AlignRight).
+		SetBackgroundColor(tcell.ColorBlack)
+	// Session grid
+	node.layout.sessionView = tview.NewGrid()
+	node.layout.sessionView.
+		SetRows(3, 0).
+		SetColumns(0, 0).
+		AddItem(node.layout.sessionInput, 0, 0, 1, 2, 0, 0, false).
+		AddItem(node.layout.sessionList, 1, 0, 1, 1, 0, 0, true).
+		AddItem(node.layout.sessionPreview, 1, 1, 1, 1, 0, 0, false).
+		SetBorder(true).
+		SetTitle(" C A O S - Conversational Assistant for OpenAI Services ").
+		SetBackgroundColor(tcell.ColorBlack).
+		SetBorderColor(tcell.ColorDarkSlateGray).
+		SetTitleColor(tcell.ColorDarkOliveGreen)
+	// Validate view
+	return node.layout.sessionView != nil
+}
+
+// refreshChoices - Render the choices of the latest response side by side
+func refreshChoices() {
+	node.layout.choiceColumns.Clear()
+	rating, isRated := node.controller.events.GetRating()
+	if !isRated {
+		return
+	}
+
+	for i := range rating.Choices {
+		column := tview.NewTextView()
+		column.
+			SetText(strings.TrimSpace(rating.Choices[i])).
+			SetWordWrap(true).
+			SetScrollable(true).
+			SetBorder(true).
+			SetBorderPadding(1, 1, 2, 2).
+			SetTitleAlign(tview.AlignLeft).
+			SetBackgroundColor(tcell.ColorBlack)
+
+		index := i
+		column.SetFocusFunc(func() {
+			node.layout.choiceIndex = index
+			markChoices()
+		})
+		node.layout.choiceColumns.AddItem(column, 0, 1, false)
+	}
+	markChoices()
+}
+
+// markChoices - Highlight the selected, best and worst choices
+func markChoices() {
+	rating, _ := node.controller.events.GetRating()
+	for i := 0; i < node.layout.choiceColumns.GetItemCount() && i < len(rating.Choices); i++ {
+		column := node.layout.choiceColumns.GetItem(i).(*tview.TextView)
+
+		color := tcell.ColorDarkSlateGray
+		switch i {
+		case rating.Best:
+			color = tcell.ColorDarkOliveGreen
+		case rating.Worst:
+			color = tcell.ColorDarkRed
+		}
+
+		title := tcell.ColorDarkTurquoise
+		if i == node.layout.choiceIndex {
+			title = tcell.ColorOrange
+		}
+
+		column.
+			SetTitle(FormatRating(rating, i)).
+			SetTitleColor(title).
+			SetBorderColor(color)
+	}
+}
+
+// onMoveChoice - Select the previous or next choice
+func onMoveChoice(offset int) {
+	rating, isRated := node.controller.events.GetRating()
+	if !isRated {
+		return
+	}
+
+	node.layout.choiceIndex = (node.layout.choiceIndex + offset + len(rating.Choices)) % len(rating.Choices)
+	markChoices()
+}
+
+// onRateChoice - Mark the selected choice as the best or the worst
+func onRateChoice(isBest bool) {
+	if err := node.controller.events.RateChoice(node.layout.choiceIndex, isBest); err != nil {
+		node.layout.infoOutput.SetText(err.Error())
+		return
+	}
+	markChoices()
+}
+
+// onExportPreferences - Export the rated choices as chosen and rejected pairs in a .jsonl file
+func onExportPreferences() {
+	var event EventManager
+	path := event.ExportPreferences()
+	if path == "" {
+		node.layout.infoOutput.SetText("Mark the best or the worst choice to export the preferences...")
+		return
+	}
+
+	onConsole()
+	node.layout.infoOutput.SetText(fmt.Sprint("Preferences exported to ", path))
+}
+
+// createChoiceView - Creates choice comparison page view
+func createChoiceView() bool {
+	// Choices
+	node.layout.choiceColumns = tview.NewFlex()
+	node.layout.choiceColumns.
+		SetDirection(tview.FlexColumn).
+		SetBackgroundColor(tcell.ColorBlack)
+	// Key event
+	node.layout.choiceColumns.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyLeft:
+			onMoveChoice(-1)
+		case event.Key() == tcell.KeyRight:
+			onMoveChoice(1)
+		case event.Rune() == 'b' || event.Rune() == 'B':
+			onRateChoice(true)
+		case event.Rune() == 'w' || event.Rune() == 'W':
+			onRateChoice(false)
+		case event.Key() == tcell.KeyEscape:
+			onConsole()
+		case event.Key() == tcell.KeyTab:
+			node.layout.app.SetFocus(node.layout.choiceInput)
+		default:
+			return event
+		}
+		return nil
+	})
+	// Actions
+	node.layout.choiceInput = tview.NewForm()
+	node.layout.choiceInput.
+		AddButton("Best", func() {
+			onRateChoice(true)
+		}).
+		AddButton("Worst", func() {
+			onRateChoice(false)
+		}).
+		AddButton("Export preferences", onExportPreferences).
+		AddButton("Back to chat", onConsole).
+		SetHorizontal(true).
+		SetButtonBackgroundColor(tcell.ColorDarkOliveGreen).
+		SetButtonsAlign(tview.AlignRight).
+		SetBackgroundColor(tcell.ColorBlack)
+	// help
+	helpOutput := tview.NewTextView()
+	helpOutput.
+		SetText("Press LEFT or RIGHT to select a choice, B to mark it as the best and W as the worst, TAB to move to the buttons and ESC to return to the chat.").
+		SetTextAlign(tview.AlignRight).
+		SetBackgroundColor(tcell.ColorBlack)
+	// Choice grid
+	node.layout.choiceView = tview.NewGrid()
+	node.layout.choiceView.
+		SetRows(0, 3, 1).
+		SetColumns(0).
+		AddItem(node.layout.choiceColumns, 0, 0, 1, 1, 0, 0, true).
+		AddItem(node.layout.choiceInput, 1, 0, 1, 1, 0, 0, false).
+		AddItem(helpOutput, 2, 0, 1, 1, 0, 0, false).
+		SetBorder(true).
+		SetTitle(" C A O S - Conversational Assistant for OpenAI Services ").
+		SetBackgroundColor(tcell.ColorBlack).
+		SetBorderColor(tcell.ColorDarkSlateGray).
+		SetTitleColor(tcell.ColorDarkOliveGreen)
+	// Validate view
+	return node.layout.choiceView != nil
+}
+
+// onArena - Model arena view event
+func onArena() {
+	// Arena view
+	refreshArenaBoard()
+	returnToPage(6)
+	node.layout.app.SetFocus(node.layout.arenaPrompt)
+}
+
+// onArenaModel - Dropdown from input to select an arena engine
+func onArenaModel(slot int) func(option string, optionIndex int) {
+	return func(option string, optionIndex int) {
+		node.layout.arenaModels[slot] = option
+	}
+}
+
+// refreshArenaBoard - Show the leaderboard of the selected template
+func refreshArenaBoard() {
+	template, ratings, err := node.controller.ArenaLeaderboard()
+	if err != nil {
+		node.layout.arenaBoard.SetText(fmt.Sprint("The leaderboard can't be loaded: ", err))
+		return
+	}
+	node.layout.arenaBoard.SetText(FormatLeaderboard(template, ratings))
+}
+
+// showArenaMatch - Show the answers of the match, the engines are revealed after the vote
+func showArenaMatch() {
+	for i, j := range node.layout.arenaOutput {
+		title := fmt.Sprint("Assistant ", string(rune('A'+i)))
+		if node.layout.arenaMatch.Vote != "" {
+			title = fmt.Sprint(title, " - ", node.layout.arenaMatch.Models[i])
+		}
+
+		answer := ""
+		if i < len(node.layout.arenaMatch.Answers) {
+			answer = node.layout.arenaMatch.Answers[i]
+		}
+		j.SetText(answer).ScrollToBeginning()
+		j.SetTitle(title)
+	}
+}
+
+// onArenaSend - Send the arena prompt to both engines
+func onArenaSend() {
+	prompt := strings.TrimSpace(node.layout.arenaPrompt.GetText())
+	if prompt == "" || node.controller.currentAgent.preferences.IsLoading {
+		return
+	}
+
+	var err error
+	node.controller.currentAgent.preferences.IsLoading = true
+	group.Add(1)
+	go func() {
+		defer group.Done()
+		node.layout.arenaMatch, err = node.controller.ArenaRequest(prompt, node.layout.arenaModels)
+		node.controller.currentAgent.preferences.IsLoading = false
+	}()
+
+	group.Wait()
+
+	showArenaMatch()
+	if err != nil {
+		node.layout.arenaBoard.SetText(err.Error())
+		return
+	}
+	refreshArenaBoard()
+}
+
+// onArenaVote - Vote the answers and reveal the engines
+func onArenaVote(vote model.ArenaVote) {
+	if len(node.layout.arenaMatch.Answers) != 2 {
+		node.layout.arenaBoard.SetText("Send a prompt to both engines before voting.")
+		return
+	}
+
+	ratings, err := node.controller.ArenaVote(&node.layout.arenaMatch, vote)
+	if err != nil {
+		node.layout.arenaBoard.SetText(err.Error())
+		return
+	}
+
+	showArenaMatch()
+	node.layout.arenaBoard.SetText(FormatLeaderboard(node.layout.arenaMatch.Template, ratings))
+}
+
+// createArenaView - Creates model arena page view
+func createArenaView() bool {
+	models := GetArenaModels(node.controller.currentAgent.preferences.Models)
+	node.layout.arenaModels = []string{"", ""}
+	if len(models) > 0 {
+		node.layout.arenaModels = []string{models[0], models[len(models)-1]}
+	}
+	// Actions
+	node.layout.arenaInput = tview.NewForm()
+	node.layout.arenaInput.
+		AddDropDown("Engine", models, 0, onArenaModel(0)).
+		AddDropDown("Engine", models, len(models)-1, onArenaModel(1)).
+		AddButton("Send", onArenaSend).
+		AddButton("A is better", func() {
+			onArenaVote(model.VoteA)
+		}).
+		AddButton("B is better", func() {
+			onArenaVote(model.VoteB)
+		}).
+		AddButton("Tie", func() {
+			onArenaVote(model.VoteTie)
+		}).
+		AddButton("Back to chat", onConsole).
+		SetHorizontal(true).
+		SetLabelColor(tcell.Color105).
+		SetFieldBackgroundColor(tcell.Color100).
+		SetFieldTextColor(tcell.ColorBlack).
+		SetButtonBackgroundColor(tcell.ColorDarkOliveGreen).
+		SetButtonsAlign(tview.AlignRight).
+		SetBackgroundColor(tcell.ColorBlack)
+	// Answers
+	node.layout.arenaOutput = nil
+	answers := tview.NewFlex()
+	for i := 0; i < 2; i++ {
+		output := tview.NewTextView()
+		output.
+			SetWordWrap(true).
+			SetScrollable(true).
+			SetBorder(true).
+			SetBorderColor(tcell.ColorDarkCyan).
+			SetBorderPadding(1, 1, 2, 2).
+			SetTitle(fmt.Sprint("Assistant ", string(rune('A'+i)))).
+			SetTitleColor(tcell.ColorDarkOliveGreen).
+			SetTitleAlign(tview.AlignLeft).
+			SetBackgroundColor(tcell.ColorBlack)
+		node.layout.arenaOutput = append(node.layout.arenaOutput, output)
+		answers.AddItem(output, 0, 1, false)
+	}
+	// Leaderboard
+	node.layout.arenaBoard = tview.NewTextView()
+	node.layout.arenaBoard.
+		SetScrollable(true).
+		SetBorder(true).
+		SetBorderColor(tcell.ColorDarkOliveGreen).
+		SetBorderPadding(1, 1, 2, 2).
+		SetTitle("Leaderboard").
+		SetTitleColor(tcell.ColorDarkTurquoise).
+		SetTitleAlign(tview.AlignLeft).
+		SetBackgroundColor(tcell.ColorBlack)
+	// Prompt
+	node.layout.arenaPrompt = tview.NewTextArea()
+	node.layout.arenaPrompt.
+		SetPlaceholder("Type the prompt for both engines, the selected template and context are sent to both...").
+		SetBorder(true).
+		SetBorderColor(tcell.ColorDarkSlateGray).
+		SetBorderPadding(1, 1, 2, 2)
+	// Key event
+	node.layout.arenaPrompt.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyCtrlSpace {
+			onArenaSend()
+			return nil
+		}
+		return event
+	})
+	// help
+	helpOutput := tview.NewTextView()
+	helpOutput.
+		SetText("Press CTRL+SPACE or CMD+SPACE to send the prompt to both engines, the engines are revealed after the vote.").
+		SetTextAlign(tview.AlignRight).
+		SetBackgroundColor(tcell.ColorBlack)
+	// Arena grid
+	node.layout.arenaView = tview.NewGrid()
+	node.layout.arenaView.
+		SetRows(3, 0, 6, 1).
+		SetColumns(0, 40).
+		AddItem(node.layout.arenaInput, 0, 0, 1, 2, 0, 0, false).
+		AddItem(answers, 1, 0, 1, 1, 0, 0, false).
+		AddItem(node.layout.arenaBoard, 1, 1, 2, 1, 0, 0, false).
+		AddItem(node.layout.arenaPrompt, 2, 0, 1, 1, 0, 0, true).
+		AddItem(helpOutput, 3, 0, 1, 2, 0, 0, false).
+		SetBorder(true).
+		SetTitle(" C A O S - Conversational Assistant for OpenAI Services ").
+		SetBackgroundColor(tcell.ColorBlack).
+		SetBorderColor(tcell.ColorDarkSlateGray).
+		SetTitleColor(tcell.ColorDarkOliveGreen)
+	// Validate view
+	return node.layout.arenaView != nil
+}
+
+// refreshTemplateVariables - Input fields of the selected template placeholders, prefilled with their defaults
+func refreshTemplateVariables() {
+	variables, defaults := node.controller.currentAgent.GetTemplateVariables()
+	values := node.controller.currentAgent.SetTemplateParameters(nil).Variables
+
+	node.layout.variableInput.Clear(false)
+	for _, i := range variables {
+		value, isSet := values[i]
+		if !isSet {
+			value = defaults[i]
+		}
+		node.layout.variableInput.AddInputField(i, value, 0, nil, nil)
+	}
+	node.layout.variableInput.SetTitle(fmt.Sprint("Template - ", node.controller.currentAgent.SetTemplateParameters(nil).Name))
+}
+
+// createVariableView - Creates template variables page view
+func createVariableView() bool {
+	// Variables
+	node.layout.variableInput = tview.NewForm()
+	node.layout.variableInput.
+		AddButton("Save", onTemplateVariablesSave).
+		AddButton("Cancel", onConsole).
+		SetLabelColor(tcell.Color105).
+		SetFieldBackgroundColor(tcell.Color100).
+		SetFieldTextColor(tcell.ColorBlack).
+		SetButtonBackgroundColor(tcell.ColorDarkOliveGreen).
+		SetButtonsAlign(tview.AlignRight).
+		SetBorder(true).
+		SetBorderColor(tcell.ColorDarkCyan).
+		SetBorderPadding(1, 1, 2, 2).
+		SetTitleColor(tcell.ColorDarkOliveGreen).
+		SetTitleAlign(tview.AlignLeft).
+		SetBackgroundColor(tcell.ColorBlack)
+	// help
+	helpOutput := tview.NewTextView()
+	helpOutput.
+		SetText("Fill the template variables, the empty ones take their default value.").
+		SetTextAlign(tview.AlignRight).
+		SetBackgroundColor(tcell.ColorBlack)
+	// Variables grid
+	node.layout.variableView = tview.NewGrid()
+	node.layout.variableView.
+		SetRows(0, 1).
+		SetColumns(0).
+		AddItem(node.layout.variableInput, 0, 0, 1, 1, 0, 0, true).
+		AddItem(helpOutput, 1, 0, 1, 1, 0, 0, false).
+		SetBorder(true).
+		SetTitle(" C A O S - Conversational Assistant for OpenAI Services ").
+		SetBackgroundColor(tcell.ColorBlack).
+		SetBorderColor(tcell.ColorDarkSlateGray).
+		SetTitleColor(tcell.ColorDarkOliveGreen)
+	// Validate view
+	return node.layout.variableView != nil
+}
+
 // createModalView - Create modal view for training mode
 func createModalView() {
 	// Modal layout
@@ -657,6 +1609,11 @@ func createModalView() {
 				clearConsoleView()
 			}
 
+			// Placeholders are filled before the new conversation starts
+			if isTemplateUnfilled() {
+				onTemplateVariables()
+				return
+			}
 			onConsole()
 		})
 }
@@ -679,6 +1636,10 @@ func InitializeLayout() {
 	// Create views
 	createConsoleView()
 	createRefinementView()
+	createSessionView()
+	createChoiceView()
+	createArenaView()
+	createVariableView()
 	createModalView()
 	// Window frame
 	node.layout.pages = tview.NewPages()
@@ -686,6 +1647,10 @@ func InitializeLayout() {
 		AddAndSwitchToPage("console", node.layout.consoleView, true).
 		AddAndSwitchToPage("refinement", node.layout.affinityView, true).
 		AddAndSwitchToPage("training", node.layout.modalInput, true).
+		AddAndSwitchToPage("sessions", node.layout.sessionView, true).
+		AddAndSwitchToPage("choices", node.layout.choiceView, true).
+		AddAndSwitchToPage("arena", node.layout.arenaView, true).
+		AddAndSwitchToPage("variables", node.layout.variableView, true).
 		SetBackgroundColor(tcell.ColorBlack)
 	// App terminal configuration
 	node.layout.app.
@@ -698,8 +1663,14 @@ func InitializeLayout() {
 	onRefinement()
 	// Validate forms
 	validateRefinementForm()
+	// Previous session
+	if node.controller.currentAgent.preferences.ResumePath != "" {
+		onResumeSession(node.controller.currentAgent.preferences.ResumePath)
+	}
 	// Exception
 	if err := node.layout.app.Run(); err != nil {
 		panic(err)
 	}
+	// Pending sessions of the search index
+	SaveSessionIndex()
 }
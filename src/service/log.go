@@ -2,7 +2,6 @@
 package service
 
 import (
-	"flag"
 	"fmt"
 	"strings"
 	"time"
@@ -16,7 +15,21 @@ import (
 
 // EventManager - Log event service
 type EventManager struct {
-	pool model.PoolProperties
+	pool  model.PoolProperties
+	agent *Agent
+}
+
+// getAgent - Agent of the logged requests, the console agent when it isn't attached
+func (c *EventManager) getAgent() *Agent {
+	if c.agent != nil {
+		return c.agent
+	}
+	return &node.controller.currentAgent
+}
+
+// GetPool - Current session pool
+func (c *EventManager) GetPool() model.PoolProperties {
+	return c.pool
 }
 
 // ExportTraining - Export training in JSON format
@@ -24,14 +37,24 @@ func (c *EventManager) ExportTraining(session []model.TrainingSession) {
 	raw, _ := json.MarshalIndent(session, "", "\u0009")
 	out := util.ConstructTsPathFileTo("training", "json")
 	out.WriteString(string(raw))
+	c.appendToIndex(out.Name())
 }
 
 // saveLogSession - Save log session with actual detail
-func (c *EventManager) saveLogSession() {
+func (c *EventManager) saveLogSession() string {
 	if c.pool.Session != nil {
 		raw, _ := json.MarshalIndent(c.pool.Session[len(c.pool.Session)-1], "", "\u0009")
 		out := util.ConstructTsPathFileTo("log", "json")
 		out.WriteString(string(raw))
+		return out.Name()
+	}
+	return ""
+}
+
+// appendToIndex - Index a stored session file for the full-text search
+func (c *EventManager) appendToIndex(path string) {
+	if path != "" {
+		IndexSession(path)
 	}
 }
 
@@ -41,6 +64,9 @@ func (c *EventManager) clearSession() {
 	c.pool.Session = nil
 	c.pool.TrainingEvent = nil
 	c.pool.TrainingSession = nil
+	c.pool.Turns = nil
+	c.pool.CurrentTurn = ""
+	c.pool.Ratings = nil
 }
 
 // appendToSession - Add a set of events as a session
@@ -50,6 +76,13 @@ func (c *EventManager) appendToSession(id string, prompt model.HistoricalPrompt,
 		Event:     prompt,
 	}
 
+	event := model.TrainingEvent{
+		Timestamp: fmt.Sprint(time.Now().UnixMilli()),
+		Event:     train,
+	}
+
+	c.appendToTree(id, &lEvent, event)
+
 	c.pool.Event = append(c.pool.Event, lEvent)
 
 	lSession := model.HistoricalSession{
@@ -57,13 +90,12 @@ func (c *EventManager) appendToSession(id string, prompt model.HistoricalPrompt,
 		Session: []model.HistoricalEvent{lEvent},
 	}
 
-	c.pool.Session = append(c.pool.Session, lSession)
-
-	event := model.TrainingEvent{
-		Timestamp: fmt.Sprint(time.Now().UnixMilli()),
-		Event:     train,
+	if c.getAgent().preferences.Mode == "Turbo" {
+		lSession.Summary = c.getAgent().summary
 	}
 
+	c.pool.Session = append(c.pool.Session, lSession)
+
 	c.pool.TrainingEvent = append(c.pool.TrainingEvent, event)
 
 	session := model.TrainingSession{
@@ -73,13 +105,40 @@ func (c *EventManager) appendToSession(id string, prompt model.HistoricalPrompt,
 
 	c.pool.TrainingSession = append(c.pool.TrainingSession, session)
 
-	c.saveLogSession()
+	c.appendToIndex(c.saveLogSession())
 }
 
 // appendToLayout - Append and visualize content in console page view
 func (c *EventManager) appendToLayout(responses []string) {
 	log := strings.Join(responses, "")
-	node.layout.promptOutput.SetText(log)
+	if !isHeadless() {
+		node.layout.promptOutput.SetText(log)
+	}
+}
+
+// queueLayout - Update the layout from the event loop, the requests running outside of it can't draw directly
+func queueLayout(update func()) {
+	if isHeadless() {
+		update()
+		return
+	}
+	node.layout.app.QueueUpdateDraw(update)
+}
+
+// appendToDetails - Visualize response details in the details section
+func (c *EventManager) appendToDetails(details string) string {
+	if !isHeadless() {
+		node.layout.infoOutput.SetText(details)
+	}
+	return details
+}
+
+// appendToMetadata - Visualize engine properties in the metadata section
+func (c *EventManager) appendToMetadata(metadata string) string {
+	if !isHeadless() {
+		node.layout.metadataOutput.SetText(metadata)
+	}
+	return metadata
 }
 
 // appendToChoice - Append choice to response
@@ -87,7 +146,7 @@ func (c *EventManager) appendToChoice(comp *gpt3.CompletionResponse, edit *gpt3.
 	var responses []string
 	responses = append(responses, "\n")
 	if comp != nil && edit == nil && search == nil && chat == nil {
-		if node.controller.currentAgent.preferences.IsPromptStreaming && comp.Choices != nil {
+		if c.getAgent().preferences.IsPromptStreaming && comp.Choices != nil {
 			responses = append(responses, comp.Choices[0].Text, "\n\n###\n\n")
 		} else {
 			for i := range comp.Choices {
@@ -99,7 +158,7 @@ func (c *EventManager) appendToChoice(comp *gpt3.CompletionResponse, edit *gpt3.
 			responses = append(responses, edit.Choices[i].Text, "\n\n###\n\n")
 		}
 	} else if chat != nil && comp == nil && search == nil && edit == nil {
-		if node.controller.currentAgent.preferences.IsPromptStreaming && chat.Choices != nil {
+		if c.getAgent().preferences.IsPromptStreaming && chat.Choices != nil {
 			responses = append(responses, chat.Choices[0].Message.Content, "\n\n###\n\n")
 		} else {
 			for i := range chat.Choices {
@@ -140,7 +199,7 @@ func (c *EventManager) appendToModel(transform model.TemplateProperties, header
 
 // checkNewSession - Evaluate a new session
 func (c *EventManager) checkNewSession() {
-	if node.controller.currentAgent.preferences.IsNewSession {
+	if c.getAgent().preferences.IsNewSession {
 		c.clearSession()
 	}
 }
@@ -153,13 +212,16 @@ func (c *EventManager) LogChatCompletion(chain model.TemplateProperties, header
 	var modelPrompt model.HistoricalPrompt
 
 	if resp != nil && cresp == nil {
+		var choices []string
 		for i := range resp.Choices {
 			body.Content = []string{resp.Choices[i].Message.Content}
 			modelTrainer, modelPrompt = c.appendToModel(chain, header, body, model.PredictProperties{}, []string{resp.Choices[i].Message.Content})
+			choices = append(choices, resp.Choices[i].Message.Content)
 		}
 
 		c.appendToSession(resp.ID, modelPrompt, modelTrainer)
-		node.controller.currentAgent.preferences.CurrentID = resp.ID
+		c.appendToRatings(resp.ID, body.Input, choices)
+		c.getAgent().preferences.CurrentID = resp.ID
 	} else if cresp != nil && resp == nil {
 		for i := range cresp.Choices {
 			body.Content = []string{cresp.Choices[i].Delta.Content}
@@ -167,7 +229,7 @@ func (c *EventManager) LogChatCompletion(chain model.TemplateProperties, header
 		}
 
 		c.appendToSession(cresp.ID, modelPrompt, modelTrainer)
-		node.controller.currentAgent.preferences.CurrentID = cresp.ID
+		c.getAgent().preferences.CurrentID = cresp.ID
 	}
 }
 
@@ -179,7 +241,7 @@ func (c *EventManager) LogGeneralCompletion(header model.EngineProperties, body
 	modelTrainer, modelPrompt := c.appendToModel(model.TemplateProperties{}, header, body, model.PredictProperties{}, resp)
 
 	c.appendToSession(id, modelPrompt, modelTrainer)
-	node.controller.currentAgent.preferences.CurrentID = id
+	c.getAgent().preferences.CurrentID = id
 }
 
 // LogPredict - ResponseDetails in a .json file
@@ -198,90 +260,124 @@ func (c *EventManager) LogPredict(header model.EngineProperties, body model.Pred
 		modelTrainer, modelPrompt = c.appendToModel(model.TemplateProperties{}, header, model.PromptProperties{}, PredictProperties, []string{fmt.Sprintf("%v", resp.Documents[i])})
 	}
 
-	c.appendToSession(node.controller.currentAgent.preferences.CurrentID, modelPrompt, modelTrainer)
+	c.appendToSession(c.getAgent().preferences.CurrentID, modelPrompt, modelTrainer)
 }
 
 // VisualLogCompletion - Chat response details
-func (c *EventManager) VisualLogCompletion(resp *gpt3.CompletionResponse, cresp *gpt3.ChatCompletionResponse, sresp *gpt3.ChatCompletionStreamResponse) {
+func (c *EventManager) VisualLogCompletion(resp *gpt3.CompletionResponse, cresp *gpt3.ChatCompletionResponse, sresp *gpt3.ChatCompletionStreamResponse) string {
+	var details string
 	if resp != nil && cresp == nil && sresp == nil {
 		c.appendToLayout(c.appendToChoice(resp, nil, nil, nil, nil))
 
 		for i := range resp.Choices {
-			node.layout.infoOutput.SetText(
-				fmt.Sprintf("ID: %v\nModel: %v\nCreated: %v\nObject: %v\nCompletion tokens: %v\nPrompt tokens: %v\nTotal tokens: %v\nToken probs: %v \nToken top: %v\nFinish reason: %v\nIndex: %v\n",
-					resp.ID,
-					resp.Model,
-					resp.Created,
-					resp.Object,
-					resp.Usage.CompletionTokens,
-					resp.Usage.PromptTokens,
-					resp.Usage.TotalTokens,
-					resp.Choices[i].LogProbs.TokenLogprobs,
-					resp.Choices[i].LogProbs.TopLogprobs,
-					resp.Choices[i].FinishReason,
-					resp.Choices[i].Index))
+			details = fmt.Sprintf("ID: %v\nModel: %v\nCreated: %v\nObject: %v\nCompletion tokens: %v\nPrompt tokens: %v\nTotal tokens: %v\nToken probs: %v \nToken top: %v\nFinish reason: %v\nIndex: %v\n",
+				resp.ID,
+				resp.Model,
+				resp.Created,
+				resp.Object,
+				resp.Usage.CompletionTokens,
+				resp.Usage.PromptTokens,
+				resp.Usage.TotalTokens,
+				resp.Choices[i].LogProbs.TokenLogprobs,
+				resp.Choices[i].LogProbs.TopLogprobs,
+				resp.Choices[i].FinishReason,
+				resp.Choices[i].Index)
 		}
 	} else if cresp != nil && sresp == nil && resp == nil {
 		c.appendToLayout(c.appendToChoice(nil, nil, nil, cresp, nil))
 
 		for i := range cresp.Choices {
-			node.layout.infoOutput.SetText(
-				fmt.Sprintf("ID: %v\nModel: %v\nCreated: %v\nObject: %v\nCompletion tokens: %v\nPrompt tokens: %v\nTotal tokens: %v\nFinish reason: %v\nIndex: %v \n",
-					cresp.ID,
-					cresp.Model,
-					cresp.Created,
-					cresp.Object,
-					cresp.Usage.CompletionTokens,
-					cresp.Usage.PromptTokens,
-					cresp.Usage.TotalTokens,
-					cresp.Choices[i].FinishReason,
-					cresp.Choices[i].Index))
+			details = fmt.Sprintf("ID: %v\nModel: %v\nCreated: %v\nObject: %v\nCompletion tokens: %v\nPrompt tokens: %v\nTotal tokens: %v\nFinish reason: %v\nIndex: %v \n",
+				cresp.ID,
+				cresp.Model,
+				cresp.Created,
+				cresp.Object,
+				cresp.Usage.CompletionTokens,
+				cresp.Usage.PromptTokens,
+				cresp.Usage.TotalTokens,
+				cresp.Choices[i].FinishReason,
+				cresp.Choices[i].Index)
 		}
 	} else if sresp != nil && cresp == nil && resp == nil {
 		for i := range sresp.Choices {
-			node.layout.infoOutput.SetText(
-				fmt.Sprintf("ID: %v\nModel: %v\nCreated: %v\nObject: %v\nCompletion tokens: %v\nPrompt tokens: %v\nTotal tokens: %v\nFinish reason: %v\nIndex: %v \n",
-					sresp.ID,
-					sresp.Model,
-					sresp.Created,
-					sresp.Object,
-					sresp.Usage.CompletionTokens,
-					sresp.Usage.PromptTokens,
-					sresp.Usage.TotalTokens,
-					sresp.Choices[i].FinishReason,
-					sresp.Choices[i].Index))
+			details = fmt.Sprintf("ID: %v\nModel: %v\nCreated: %v\nObject: %v\nCompletion tokens: %v\nPrompt tokens: %v\nTotal tokens: %v\nFinish reason: %v\nIndex: %v \n",
+				sresp.ID,
+				sresp.Model,
+				sresp.Created,
+				sresp.Object,
+				sresp.Usage.CompletionTokens,
+				sresp.Usage.PromptTokens,
+				sresp.Usage.TotalTokens,
+				sresp.Choices[i].FinishReason,
+				sresp.Choices[i].Index)
 		}
 	}
+
+	return c.appendToDetails(details)
 }
 
 // VisualLogEdit - Log edited response details
-func (c *EventManager) VisualLogEdit(resp *gpt3.EditsResponse) {
+func (c *EventManager) VisualLogEdit(resp *gpt3.EditsResponse) string {
+	var details string
 	c.appendToLayout(c.appendToChoice(nil, resp, nil, nil, nil))
 	for i := range resp.Choices {
-		node.layout.infoOutput.SetText(fmt.Sprintf("Created: %v\nObject: %v\nCompletion tokens: %v\nPrompt tokens: %v\nTotal tokens: %v\nIndex: %v\n",
+		details = fmt.Sprintf("Created: %v\nObject: %v\nCompletion tokens: %v\nPrompt tokens: %v\nTotal tokens: %v\nIndex: %v\n",
 			resp.Created,
 			resp.Object,
 			resp.Usage.CompletionTokens,
 			resp.Usage.PromptTokens,
 			resp.Usage.TotalTokens,
-			resp.Choices[i].Index))
+			resp.Choices[i].Index)
 	}
+
+	return c.appendToDetails(details)
 }
 
 // VisualLogEmbedding - Log embedding response details
-func (c *EventManager) VisualLogEmbedding(resp *gpt3.EmbeddingsResponse) {
+func (c *EventManager) VisualLogEmbedding(resp *gpt3.EmbeddingsResponse) string {
+	var details string
 	c.appendToLayout(c.appendToChoice(nil, nil, resp, nil, nil))
 	for i := range resp.Data {
-		node.layout.infoOutput.SetText(fmt.Sprintf("Object: %v\nPrompt tokens: %v\nTotal tokens: %v\nIndex: %v\n",
+		details = fmt.Sprintf("Object: %v\nPrompt tokens: %v\nTotal tokens: %v\nIndex: %v\n",
 			resp.Object,
 			resp.Usage.PromptTokens,
 			resp.Usage.TotalTokens,
-			resp.Data[i].Index))
+			resp.Data[i].Index)
 	}
+
+	return c.appendToDetails(details)
+}
+
+// VisualLogSimilar - Log the stored sessions close to the prompt
+func (c *EventManager) VisualLogSimilar(results []model.SimilarResult) string {
+	out := FormatSimilarResults(results)
+	c.appendToLayout([]string{"Similar conversations:\n\n", out})
+	return out
+}
+
+// VisualLogSchema - Log the validation result of the structured output
+func (c *EventManager) VisualLogSchema(errs []string) string {
+	return c.appendToDetails(FormatSchemaErrors(errs))
+}
+
+// ExportStructured - Export the validated structured output as a JSON file
+func (c *EventManager) ExportStructured(content string) string {
+	out := util.ConstructTsPathFileTo("output", "json")
+	out.WriteString(content)
+	return out.Name()
+}
+
+// VisualLogResearch - Log the research steps in the details section
+func (c *EventManager) VisualLogResearch(report model.ResearchReport) string {
+	details := FormatResearchSteps(report)
+	queueLayout(func() {
+		c.appendToDetails(details)
+	})
+	return details
 }
 
 // VisualLogPredict - Log predicted response details
-func (c *EventManager) VisualLogPredict(resp *model.PredictResponse) {
+func (c *EventManager) VisualLogPredict(resp *model.PredictResponse) string {
 	var buffer []string
 	for i := range resp.Documents {
 		c.appendToLayout(c.appendToChoice(nil, nil, nil, nil, &resp.Documents[i]))
@@ -310,7 +406,7 @@ func (c *EventManager) VisualLogPredict(resp *model.PredictResponse) {
 	}
 
 	inline := fmt.Sprintf("%v", buffer)
-	node.layout.infoOutput.SetText(util.RemoveWrapper(inline))
+	return c.appendToDetails(util.RemoveWrapper(inline))
 }
 
 // LogClient - Log client context
@@ -399,7 +495,7 @@ func (c *EventManager) LogClient(client Agent) {
 
 // LogEngine - Log current engine
 func (c *EventManager) LogEngine(client Agent) {
-	node.layout.metadataOutput.SetText(
+	c.appendToMetadata(
 		fmt.Sprintf("Model: %v\nRole: %v\nTemperature: %v\nTopp: %v\nFrequency penalty: %v\nPresence penalty: %v\nPrompt: %v\nInstruction: %v\nProbabilities: %v\nResults: %v\nMax tokens: %v\n",
 			client.EngineProperties.Model,
 			client.EngineProperties.Role,
@@ -411,7 +507,26 @@ func (c *EventManager) LogEngine(client Agent) {
 			client.PromptProperties.Instruction,
 			client.PromptProperties.Probabilities,
 			client.PromptProperties.Results,
-			client.preferences.MaxTokens))
+			client.preferences.MaxTokens,
+		) + logContextBudget(client))
+}
+
+// logContextBudget - Token breakdown of the last chat request
+func logContextBudget(client Agent) string {
+	budget := client.preferences.Budget
+	if client.preferences.Mode != "Turbo" || budget.Limit == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("Context window: %v\nSystem tokens: %v\nHistory tokens: %v\nPrompt tokens: %v\nContext tokens: %v\nCompletion tokens: %v\nDropped turns: %v\nDropped context: %v\n",
+		budget.Limit,
+		budget.System,
+		budget.History,
+		budget.Prompt,
+		budget.Context,
+		budget.Completion,
+		budget.DroppedTurns,
+		budget.DroppedContext)
 }
 
 // LogPredictEngine - Log current predict engine
@@ -424,7 +539,7 @@ func (c *EventManager) LogPredictEngine(client Agent) {
 			out = "Mostly human generated content"
 		}
 
-		node.layout.metadataOutput.SetText(
+		c.appendToMetadata(
 			fmt.Sprintf("Model: %v\nAverage Prob: %v\nCompletely Prob: %v\noversall burstiness: %v\n---\n%v\n",
 				client.EngineProperties.Model,
 				client.PredictProperties.Details.Documents[i].AverageProb,
@@ -436,7 +551,7 @@ func (c *EventManager) LogPredictEngine(client Agent) {
 
 // Errata - Generic error method
 func (c *EventManager) Errata(err error) {
-	if flag.Lookup("test.v") == nil {
+	if !isHeadless() {
 		if err != nil {
 			node.layout.infoOutput.SetText(err.Error())
 			node.layout.promptArea.SetPlaceholder("An error was found or the response was not complete, just press CTRL+SPACE or CMD+SPACE to repeat it.")
@@ -6,7 +6,7 @@ import (
 )
 
 // node - Global node service
-var node *Node
+var node = onConstruct()
 
 // Node - Node manager
 type Node struct {
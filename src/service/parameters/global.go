@@ -8,8 +8,17 @@ import (
 // GlobalPreferences - General
 type GlobalPreferences struct {
 	// Agent
-	User     string
-	Encoding string
+	User       string
+	Encoding   string
+	BaseURL    string
+	ReplayPath string
+	ResumePath string
+	// Knowledge base
+	KnowledgePath string
+	// Endpoints
+	OpenAIURL  string
+	PredictURL string
+	SearchURL  string
 	// Engine properties
 	TemplateIDs       int
 	Template          int
@@ -28,6 +37,19 @@ type GlobalPreferences struct {
 	Penalty       float32
 	Frequency     float32
 	PromptCtx     []string
+	ContextLimit  int
+	Budget        model.ContextBudget
+	// Summary properties
+	SummaryThreshold int
+	SummaryModel     string
+	// Embedding properties
+	EmbeddingModel string
+	// Structured output properties
+	SchemaPath    string
+	SchemaRetries int
+	// Research properties
+	ResearchSteps  int
+	ResearchBudget int
 	// Modes
 	IsChained         bool
 	IsLoading         bool
@@ -35,6 +57,7 @@ type GlobalPreferences struct {
 	IsNewSession      bool
 	IsPromptReady     bool
 	IsPromptStreaming bool
+	IsToolEnabled     bool
 	// Utilitaries
 	Role       model.Roles
 	CurrentID  string
@@ -43,3 +66,35 @@ type GlobalPreferences struct {
 
 // ExternalSearchBaseURL - External API endpoint
 const ExternalSearchBaseURL = "https://www.google.com/search?client=firefox-b-m&gbv=1&q="
+
+// OpenAIBaseURL - OpenAI API endpoint
+const OpenAIBaseURL = "https://api.openai.com/v1"
+
+// PredictBaseURL - GPTZero API endpoint
+const PredictBaseURL = "https://api.gptzero.me/v2/predict/text"
+
+// DefaultEmbeddingModel - Model embedding the stored sessions
+const DefaultEmbeddingModel = "text-embedding-ada-002"
+
+// DefaultSchemaRetries - Repair prompts when the structured output doesn't conform to the schema
+const DefaultSchemaRetries = 3
+
+// Research agent limits when they are not defined
+const (
+	DefaultResearchSteps  = 8
+	DefaultResearchBudget = 16000
+)
+
+// DefaultContextLimit - Context window size for unknown models
+const DefaultContextLimit = 4096
+
+// ContextLimits - Context window size in tokens by model prefix
+var ContextLimits = map[string]int{
+	"gpt-4":             8192,
+	"gpt-4-32k":         32768,
+	"gpt-3.5-turbo":     4096,
+	"gpt-3.5-turbo-16k": 16384,
+	"text-davinci-002":  4097,
+	"text-davinci-003":  4097,
+	"code-davinci-002":  8001,
+}
@@ -0,0 +1,184 @@
+// Package service section
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"caos/model"
+	"caos/util"
+)
+
+// Rating errors
+var (
+	errRatingEmpty  = errors.New("rating: there are no choices to compare")
+	errRatingChoice = errors.New("rating: the choice doesn't exist")
+)
+
+// ratingsPath - Persisted ratings of every conversation
+var ratingsPath = filepath.Join("training", "ratings.json")
+
+// ratingsMutex - Serialize the updates of the ratings file between the sessions
+var ratingsMutex sync.Mutex
+
+// loadRatings - Persisted ratings, empty when the file doesn't exist
+func loadRatings() ([]model.ChoiceRating, error) {
+	raw, err := os.ReadFile(ratingsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ratings []model.ChoiceRating
+	if err := json.Unmarshal(raw, &ratings); err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
+
+// saveRating - Add or replace the rating in the persisted ratings
+func saveRating(rating model.ChoiceRating) error {
+	ratingsMutex.Lock()
+	defer ratingsMutex.Unlock()
+
+	ratings, err := loadRatings()
+	if err != nil {
+		return err
+	}
+
+	isStored := false
+	for i := range ratings {
+		if ratings[i].ID == rating.ID && ratings[i].Timestamp == rating.Timestamp {
+			ratings[i] = rating
+			isStored = true
+		}
+	}
+	if !isStored {
+		ratings = append(ratings, rating)
+	}
+
+	raw, err := json.MarshalIndent(ratings, "", "\u0009")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ratingsPath), 0755); err != nil {
+		return err
+	}
+
+	tmp := ratingsPath + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ratingsPath)
+}
+
+// appendToRatings - Keep the choices of a response to be compared, single choices aren't rated
+func (c *EventManager) appendToRatings(id string, prompt []string, choices []string) {
+	if len(choices) < 2 {
+		return
+	}
+
+	rating := model.ChoiceRating{
+		ID:        id,
+		Timestamp: fmt.Sprint(time.Now().UnixMilli()),
+		Prompt:    prompt,
+		Choices:   choices,
+		Best:      -1,
+		Worst:     -1,
+	}
+	c.pool.Ratings = append(c.pool.Ratings, rating)
+	saveRating(rating)
+}
+
+// GetRating - Choices of the latest response with several choices
+func (c *EventManager) GetRating() (model.ChoiceRating, bool) {
+	if len(c.pool.Ratings) == 0 {
+		return model.ChoiceRating{}, false
+	}
+	return c.pool.Ratings[len(c.pool.Ratings)-1], true
+}
+
+// RateChoice - Mark a choice of the latest response as the best or the worst
+func (c *EventManager) RateChoice(choice int, isBest bool) error {
+	if len(c.pool.Ratings) == 0 {
+		return errRatingEmpty
+	}
+
+	rating := &c.pool.Ratings[len(c.pool.Ratings)-1]
+	if choice < 0 || choice >= len(rating.Choices) {
+		return fmt.Errorf("%w: %v", errRatingChoice, choice+1)
+	}
+
+	// A choice can't be the best and the worst at the same time
+	if isBest {
+		rating.Best = choice
+		if rating.Worst == choice {
+			rating.Worst = -1
+		}
+	} else {
+		rating.Worst = choice
+		if rating.Best == choice {
+			rating.Best = -1
+		}
+	}
+	return saveRating(*rating)
+}
+
+// GetPreferencePairs - Chosen and rejected pairs, the best choice wins over the others and the others over the worst
+func GetPreferencePairs(ratings []model.ChoiceRating) []model.PreferencePair {
+	var pairs []model.PreferencePair
+	for _, i := range ratings {
+		prompt := strings.Join(i.Prompt, "\n")
+		for j := range i.Choices {
+			if i.Best >= 0 && j != i.Best {
+				pairs = append(pairs, model.PreferencePair{Prompt: prompt, Chosen: i.Choices[i.Best], Rejected: i.Choices[j]})
+			}
+			if i.Worst >= 0 && j != i.Worst && j != i.Best {
+				pairs = append(pairs, model.PreferencePair{Prompt: prompt, Chosen: i.Choices[j], Rejected: i.Choices[i.Worst]})
+			}
+		}
+	}
+	return pairs
+}
+
+// ExportPreferences - Export the persisted rated pairs in JSONL format, empty when nothing was rated
+func (c *EventManager) ExportPreferences() string {
+	ratingsMutex.Lock()
+	ratings, _ := loadRatings()
+	ratingsMutex.Unlock()
+
+	pairs := GetPreferencePairs(ratings)
+	if pairs == nil {
+		return ""
+	}
+
+	var lines []string
+	for _, i := range pairs {
+		raw, _ := json.Marshal(i)
+		lines = append(lines, string(raw))
+	}
+
+	out := util.ConstructTsPathFileTo("preference", "jsonl")
+	out.WriteString(fmt.Sprint(strings.Join(lines, "\n"), "\n"))
+	return out.Name()
+}
+
+// FormatRating - Choice title with its best or worst mark
+func FormatRating(rating model.ChoiceRating, choice int) string {
+	title := fmt.Sprintf("Choice %v/%v", choice+1, len(rating.Choices))
+	switch choice {
+	case rating.Best:
+		title = fmt.Sprint(title, " - best")
+	case rating.Worst:
+		title = fmt.Sprint(title, " - worst")
+	}
+	return title
+}
@@ -28,6 +28,28 @@ type Prompt struct {
 	chatStreamResponse  *gpt3.ChatCompletionStreamResponse
 	chatResponse        *gpt3.ChatCompletionResponse
 	predictableResponse *model.PredictResponse
+	// Provider or context window error of the last completion
+	err error
+	// Passages and sources retrieved for the last chat completion
+	validated model.ChainPrompt
+	// Headless output of the streamed responses
+	writer io.Writer
+}
+
+// clearTerminal - Clear the terminal before echoing a streamed response, the headless output is kept
+func (c *Prompt) clearTerminal() {
+	if c.writer == nil {
+		fmt.Print("\033[H\033[2J")
+	}
+}
+
+// echo - Echo a streamed token in the terminal or write it as is to the headless output
+func (c *Prompt) echo(text string, width uint) {
+	if c.writer != nil {
+		io.WriteString(c.writer, text)
+		return
+	}
+	fmt.Printf("\x1b[1:32m%s", wordwrap.WrapString(text, width))
 }
 
 // isContextValid - Client context validation
@@ -49,46 +71,95 @@ func isTestingEnvironment() bool {
 	return hasTestFlag
 }
 
+// isHeadless - Validate when the requests run without the terminal layout
+func isHeadless() bool {
+	return isTestingEnvironment() || node.layout.app == nil
+}
+
+// Beginning of the contextual information appended after the user input, replays strip it to match the input
+const (
+	chatContextPrompt      = "\nNow you have real-time access to the internet"
+	knowledgeContextPrompt = "\nI'll provide some passages retrieved from our local documents"
+)
+
+// setChatPrompt - Prompt with the real-time contextual information and the response schema
+func setChatPrompt(prompt string, ctx []string, urls []string) string {
+	return fmt.Sprint(
+		prompt,
+		chatContextPrompt,
+		", I'll provide some contextual information with urls for main reference for your responses",
+		"\nthis is fundamentally from real-time online actual results, provide a response around the following contextual information:",
+		ctx,
+		"\nObtained from the following urls:",
+		urls,
+		"\nPlease always elaborate a detailed response with the following complete schema (KEEP LINE BY LINE):",
+		"\nQuestion: <User input ONLY>",
+		"\nResponse: <Your Detailed response should contain the contextual information from the real-time online results ONLY>",
+		"\nResume: <Include more than 3000-WORDS per response ONLY>",
+		"\nSuggestions: <based entirely on the verified context include suggestions to look or search ONLY>",
+		"\nSource: <List all the urls from the contextual information ONLY>",
+	)
+}
+
+// setKnowledgePrompt - Prompt with the passages retrieved from the local documents
+func setKnowledgePrompt(prompt string, ctx []string, sources []string) string {
+	return fmt.Sprint(
+		prompt,
+		knowledgeContextPrompt,
+		" as the main reference for your response,",
+		"\nanswer based on the following contextual information and say so when it doesn't contain the answer:",
+		ctx,
+		"\nObtained from the following documents:",
+		sources,
+		"\nPlease always elaborate a detailed response with the following complete schema (KEEP LINE BY LINE):",
+		"\nQuestion: <User input ONLY>",
+		"\nResponse: <Your Detailed response should contain the contextual information from the local documents ONLY>",
+		"\nSource: <List the documents used in the response ONLY>",
+	)
+}
+
 // SendChatCompletionPrompt - Send streaming chat completion prompt
 func (c *Prompt) SendChatCompletionPrompt(service Agent) (*gpt3.ChatCompletionStreamResponse, *gpt3.ChatCompletionResponse) {
 	if isContextValid(service) {
 		var buffer []string
 
-		prompt := service.SetTemplate(string(""), service.PromptProperties.Input[0])[0]
+		prompt := service.PromptProperties.Input[0]
 		urls, ctxVerified := service.SetContext(&service.PromptProperties)
 
-		service.TemplateProperties.PromptValidated.Source = append(service.TemplateProperties.PromptValidated.Source, urls...)
-		service.TemplateProperties.PromptValidated.Context = append(service.TemplateProperties.PromptValidated.Context, ctxVerified...)
-
-		msg := fmt.Sprint(
-			prompt,
-			"\nNow you have real-time access to the internet, I'll provide some contextual information with urls for main reference for your responses",
-			"\nthis is fundamentally from real-time online actual results, provide a response around the following contextual information:",
-			ctxVerified,
-			"\nObtained from the following urls:",
-			urls,
-			"\nPlease always elaborate a detailed response with the following complete schema (KEEP LINE BY LINE):",
-			"\nQuestion: <User input ONLY>",
-			"\nResponse: <Your Detailed response should contain the contextual information from the real-time online results ONLY>",
-			"\nResume: <Include more than 3000-WORDS per response ONLY>",
-			"\nSuggestions: <based entirely on the verified context include suggestions to look or search ONLY>",
-			"\nSource: <List all the urls from the contextual information ONLY>",
-		)
-
-		if !isTestingEnvironment() {
-			service.PromptProperties.MaxTokens = len(util.EncodePromptBytePair([]string{msg}, service.EngineProperties.Model))
-			node.controller.currentAgent.preferences.MaxTokens = service.PromptProperties.MaxTokens
+		// The agent is a copy, the controller logs the passages from the prompt
+		c.validated = model.ChainPrompt{Source: urls, Context: ctxVerified}
+
+		compose := func(ctx []string) gpt3.ChatCompletionRequestMessage {
+			content := setChatPrompt(prompt, ctx, urls)
+			if service.preferences.KnowledgePath != "" {
+				content = setKnowledgePrompt(prompt, ctx, urls)
+			}
+
+			return gpt3.ChatCompletionRequestMessage{
+				Role:    string(service.preferences.Role),
+				Content: content,
+			}
 		}
 
-		ctx := gpt3.ChatCompletionRequestMessage{
-			Role:    string(service.preferences.Role),
-			Content: msg,
+		window := NewContextWindow(service.EngineProperties.Model, service.preferences.ContextLimit)
+		messages, budget, err := window.Fit(service.getHistory(), prompt, ctxVerified, compose)
+		c.err = err
+		if err != nil {
+			var event EventManager
+			event.Errata(err)
+			return nil, nil
+		}
+
+		service.PromptProperties.MaxTokens = budget.Completion
+		if !isHeadless() {
+			node.controller.currentAgent.preferences.MaxTokens = service.PromptProperties.MaxTokens
+			node.controller.currentAgent.preferences.Budget = budget
 		}
 
 		req := gpt3.ChatCompletionRequest{
 			Model:            service.EngineProperties.Model,
 			User:             service.id,
-			Messages:         []gpt3.ChatCompletionRequestMessage{ctx},
+			Messages:         messages,
 			MaxTokens:        *gpt3.IntPtr(service.PromptProperties.MaxTokens),
 			Temperature:      *gpt3.Float32Ptr(service.EngineProperties.Temperature),
 			TopP:             *gpt3.Float32Ptr(service.EngineProperties.TopP),
@@ -103,7 +174,7 @@ func (c *Prompt) SendChatCompletionPrompt(service Agent) (*gpt3.ChatCompletionSt
 			sresp := &gpt3.ChatCompletionStreamResponse{}
 
 			var bWriter tview.TextViewWriter
-			if !isTestingEnvironment() {
+			if !isHeadless() {
 				bWriter = node.layout.promptOutput.BatchWriter()
 				defer bWriter.Close()
 				bWriter.Clear()
@@ -111,9 +182,8 @@ func (c *Prompt) SendChatCompletionPrompt(service Agent) (*gpt3.ChatCompletionSt
 				buffer = append(buffer, "\n")
 			}
 
-			fmt.Print("\033[H\033[2J")
-			client := *service.client
-			err := client.ChatCompletionStream(
+			c.clearTerminal()
+			err := service.client.ChatCompletionStream(
 				service.ctx,
 				req, func(out *gpt3.ChatCompletionStreamResponse) {
 					sresp.ID = out.ID
@@ -130,18 +200,17 @@ func (c *Prompt) SendChatCompletionPrompt(service Agent) (*gpt3.ChatCompletionSt
 					sresp.Choices[0].Delta.Content = out.Choices[0].Delta.Content
 					sresp.Choices[0].Delta.Role = out.Choices[0].Delta.Role
 					// Write buffer
-					if !isTestingEnvironment() {
-						buffer = append(buffer, out.Choices[0].Delta.Content)
+					buffer = append(buffer, out.Choices[0].Delta.Content)
+					if !isHeadless() {
 						bWriter.Write([]byte(out.Choices[0].Delta.Content))
 					}
-					str := wordwrap.WrapString(out.Choices[0].Delta.Content, 25)
-					fmt.Printf("\x1b[1:32m%s", str)
+					c.echo(out.Choices[0].Delta.Content, 25)
 				})
 
 			var event EventManager
 			event.Errata(err)
 
-			if !isTestingEnvironment() {
+			if !isHeadless() {
 				bWriter.Write([]byte("\n\n###\n\n"))
 				buffer = append(buffer, "\n\n###\n\n")
 			}
@@ -150,7 +219,7 @@ func (c *Prompt) SendChatCompletionPrompt(service Agent) (*gpt3.ChatCompletionSt
 				sresp.Choices[i].Delta.Content = fmt.Sprint(util.RemoveWrapper(out))
 			}
 
-			if !isTestingEnvironment() {
+			if !isHeadless() {
 				node.layout.app.Sync()
 			}
 
@@ -158,15 +227,14 @@ func (c *Prompt) SendChatCompletionPrompt(service Agent) (*gpt3.ChatCompletionSt
 			return c.chatStreamResponse, nil
 		}
 
-		client := *service.client
-		resp, err := client.ChatCompletion(
+		resp, err := service.client.ChatCompletion(
 			service.ctx,
 			req)
 
 		var event EventManager
 		event.Errata(err)
 
-		if !isTestingEnvironment() {
+		if !isHeadless() {
 			node.layout.app.Sync()
 		}
 		c.chatResponse = resp
@@ -176,6 +244,170 @@ func (c *Prompt) SendChatCompletionPrompt(service Agent) (*gpt3.ChatCompletionSt
 	return nil, nil
 }
 
+// SendToolCompletionPrompt - Send chat completion prompts running the requested tools until the final answer
+func (c *Prompt) SendToolCompletionPrompt(service Agent) (*gpt3.ChatCompletionResponse, []model.ToolCall) {
+	if !isContextValid(service) {
+		return nil, nil
+	}
+
+	prompt := service.PromptProperties.Input[0]
+	compose := func(ctx []string) gpt3.ChatCompletionRequestMessage {
+		return gpt3.ChatCompletionRequestMessage{
+			Role:    string(service.preferences.Role),
+			Content: prompt,
+		}
+	}
+
+	// Tools are declared after the template and summary messages
+	history := insertSystemMessage(service.getHistory(), getToolMessage())
+
+	window := NewContextWindow(service.EngineProperties.Model, service.preferences.ContextLimit)
+	messages, budget, err := window.Fit(history, prompt, nil, compose)
+	completion := budget.Completion
+
+	var calls []model.ToolCall
+	var event EventManager
+	c.err = err
+	if err != nil {
+		event.Errata(err)
+		return nil, nil
+	}
+	for {
+		req := gpt3.ChatCompletionRequest{
+			Model:            service.EngineProperties.Model,
+			User:             service.id,
+			Messages:         messages,
+			MaxTokens:        *gpt3.IntPtr(completion),
+			Temperature:      *gpt3.Float32Ptr(service.EngineProperties.Temperature),
+			TopP:             *gpt3.Float32Ptr(service.EngineProperties.TopP),
+			PresencePenalty:  *gpt3.Float32Ptr(service.EngineProperties.PresencePenalty),
+			FrequencyPenalty: *gpt3.Float32Ptr(service.EngineProperties.FrequencyPenalty),
+			Stop:             []string{"stop"},
+		}
+
+		resp, err := service.client.ChatCompletion(service.ctx, req)
+		event.Errata(err)
+		if err != nil || resp == nil || len(resp.Choices) == 0 {
+			c.chatResponse = resp
+			return c.chatResponse, calls
+		}
+
+		call, isCall := parseToolCall(resp.Choices[0].Message.Content)
+		if !isCall {
+			c.chatResponse = resp
+			return c.chatResponse, calls
+		}
+		// The pending call isn't shown as the answer
+		if len(calls) >= maxToolCalls {
+			c.chatResponse = getToolLimitResponse(resp)
+			return c.chatResponse, calls
+		}
+
+		call = RunTool(service, call)
+		calls = append(calls, call)
+
+		if !isHeadless() {
+			event.appendToDetails(FormatToolCalls(calls))
+			node.layout.app.Sync()
+		}
+
+		reply := getToolResultMessage(call)
+		if len(calls) == maxToolCalls {
+			reply.Content = fmt.Sprint(reply.Content, "\nThe tool limit was reached, reply with the final answer now.")
+		}
+
+		// The tool exchange takes room from the completion
+		request := gpt3.ChatCompletionRequestMessage{Role: string(model.Assistant), Content: resp.Choices[0].Message.Content}
+		completion -= window.countMessage(request) + window.countMessage(reply)
+		if completion <= 0 {
+			c.chatResponse = getToolLimitResponse(resp)
+			return c.chatResponse, calls
+		}
+		messages = append(messages, request, reply)
+	}
+}
+
+// SendSchemaCompletionPrompt - Send chat completion prompts until the output conforms to the attached JSON Schema or the retries are exhausted
+func (c *Prompt) SendSchemaCompletionPrompt(service Agent) (*gpt3.ChatCompletionResponse, []string) {
+	if !isContextValid(service) {
+		return nil, nil
+	}
+
+	var event EventManager
+	schema, raw, err := loadSchema(service.preferences.SchemaPath)
+	if err != nil {
+		event.Errata(err)
+		return nil, []string{err.Error()}
+	}
+
+	prompt := service.PromptProperties.Input[0]
+	compose := func(ctx []string) gpt3.ChatCompletionRequestMessage {
+		return gpt3.ChatCompletionRequestMessage{
+			Role:    string(service.preferences.Role),
+			Content: prompt,
+		}
+	}
+
+	window := NewContextWindow(service.EngineProperties.Model, service.preferences.ContextLimit)
+	messages, budget, err := window.Fit(insertSystemMessage(service.getHistory(), getSchemaMessage(raw)), prompt, nil, compose)
+	c.err = err
+	if err != nil {
+		event.Errata(err)
+		return nil, []string{err.Error()}
+	}
+
+	for retry := 0; ; retry++ {
+		req := gpt3.ChatCompletionRequest{
+			Model:            service.EngineProperties.Model,
+			User:             service.id,
+			Messages:         messages,
+			MaxTokens:        *gpt3.IntPtr(budget.Completion),
+			Temperature:      *gpt3.Float32Ptr(service.EngineProperties.Temperature),
+			TopP:             *gpt3.Float32Ptr(service.EngineProperties.TopP),
+			PresencePenalty:  *gpt3.Float32Ptr(service.EngineProperties.PresencePenalty),
+			FrequencyPenalty: *gpt3.Float32Ptr(service.EngineProperties.FrequencyPenalty),
+		}
+
+		resp, err := service.client.ChatCompletion(service.ctx, req)
+		event.Errata(err)
+		if err != nil || resp == nil || len(resp.Choices) == 0 {
+			c.chatResponse = resp
+			return c.chatResponse, []string{"schema: the model didn't reply"}
+		}
+
+		content := resp.Choices[0].Message.Content
+		out, errs := validateSchemaOutput(schema, content)
+		if errs == nil {
+			resp.Choices = resp.Choices[:1]
+			resp.Choices[0].Message.Content = out
+		}
+
+		if errs == nil || retry >= service.preferences.SchemaRetries {
+			if !isHeadless() {
+				node.layout.app.Sync()
+			}
+
+			c.chatResponse = resp
+			return c.chatResponse, errs
+		}
+
+		if !isHeadless() {
+			event.appendToDetails(fmt.Sprint("Repairing the output, retry ", retry+1, "\n", FormatSchemaErrors(errs)))
+			node.layout.app.Sync()
+		}
+
+		// The repair exchange takes room from the completion
+		reply := gpt3.ChatCompletionRequestMessage{Role: string(model.Assistant), Content: content}
+		repair := getSchemaRepairMessage(errs)
+		budget.Completion -= window.countMessage(reply) + window.countMessage(repair)
+		if budget.Completion <= 0 {
+			c.chatResponse = resp
+			return c.chatResponse, errs
+		}
+		messages = append(messages, reply, repair)
+	}
+}
+
 // SendCompletionPrompt - Send task prompt on stream mode
 func (c *Prompt) SendCompletionPrompt(service Agent) *gpt3.CompletionResponse {
 	if isContextValid(service) {
@@ -185,7 +417,7 @@ func (c *Prompt) SendCompletionPrompt(service Agent) *gpt3.CompletionResponse {
 
 		msg := service.SetTemplate(service.cachedPrompt, service.PromptProperties.Input[0])
 
-		if !isTestingEnvironment() {
+		if !isHeadless() {
 			service.PromptProperties.MaxTokens = 1024 + len(util.EncodePromptBytePair(msg, service.EngineProperties.Model))
 			node.controller.currentAgent.preferences.MaxTokens = service.PromptProperties.MaxTokens
 		}
@@ -204,18 +436,20 @@ func (c *Prompt) SendCompletionPrompt(service Agent) *gpt3.CompletionResponse {
 		if service.preferences.IsPromptStreaming {
 
 			var bWriter tview.TextViewWriter
-			if !isTestingEnvironment() {
+			if !isHeadless() {
 				bWriter = node.layout.promptOutput.BatchWriter()
 				defer bWriter.Close()
 				bWriter.Clear()
 			}
-			fmt.Print("\033[H\033[2J")
+			c.clearTerminal()
 			isOnce := false
-			client := *service.client
-			err := client.CompletionStreamWithEngine(
+			err := service.client.CompletionStream(
 				service.ctx,
 				service.EngineProperties.Model,
 				req, func(out *gpt3.CompletionResponse) {
+					if len(out.Choices) == 0 {
+						return
+					}
 					go func(in chan string) {
 						if !isOnce {
 							resp.ID = out.ID
@@ -237,28 +471,27 @@ func (c *Prompt) SendCompletionPrompt(service Agent) *gpt3.CompletionResponse {
 						resp.Choices[0].LogProbs.TopLogprobs = append(resp.Choices[0].LogProbs.TopLogprobs, out.Choices[0].LogProbs.TopLogprobs...)
 
 						for i := range out.Choices {
-							if !isTestingEnvironment() {
-								buffer = append(buffer, out.Choices[i].Text)
-							}
+							buffer = append(buffer, out.Choices[i].Text)
+							c.echo(out.Choices[i].Text, 50)
 							in <- out.Choices[i].Text
-							str := wordwrap.WrapString(out.Choices[i].Text, 50)
-							fmt.Printf("\x1b[1:32m%s", str)
 						}
 					}(service.preferences.InlineText)
-					if !isTestingEnvironment() {
-						bWriter.Write([]byte(<-service.preferences.InlineText))
+					text := <-service.preferences.InlineText
+					if !isHeadless() {
+						bWriter.Write([]byte(text))
 					}
 				})
 
 			var event EventManager
 			event.Errata(err)
+			c.err = err
 
 			out := strings.Join(buffer, "")
 			for i := range resp.Choices {
 				resp.Choices[i].Text = fmt.Sprint(util.RemoveWrapper(out))
 			}
 
-			if !isTestingEnvironment() {
+			if !isHeadless() {
 				bWriter.Write([]byte("\n\n###\n\n"))
 				node.layout.app.Sync()
 			}
@@ -266,16 +499,16 @@ func (c *Prompt) SendCompletionPrompt(service Agent) *gpt3.CompletionResponse {
 			return c.contextualResponse
 		}
 
-		client := *service.client
-		resp, err := client.CompletionWithEngine(
+		resp, err := service.client.Completion(
 			service.ctx,
 			service.EngineProperties.Model,
 			req)
 
 		var event EventManager
 		event.Errata(err)
+		c.err = err
 
-		if isTestingEnvironment() {
+		if !isHeadless() {
 			node.layout.app.Sync()
 		}
 		c.contextualResponse = resp
@@ -298,15 +531,14 @@ func (c *Prompt) SendEditPrompt(service Agent) *gpt3.EditsResponse {
 			TopP:        gpt3.Float32Ptr(service.EngineProperties.TopP),
 			N:           gpt3.IntPtr(service.PromptProperties.Results)}
 
-		client := *service.client
-		resp, err := client.Edits(
+		resp, err := service.client.Edit(
 			service.ctx,
 			req)
 
 		var event EventManager
 		event.Errata(err)
 
-		if !isTestingEnvironment() {
+		if !isHeadless() {
 			node.layout.app.Sync()
 		}
 
@@ -324,15 +556,14 @@ func (c *Prompt) SendEmbeddingPrompt(service Agent) *gpt3.EmbeddingsResponse {
 			Input: service.PromptProperties.Input,
 		}
 
-		client := *service.client
-		resp, err := client.Embeddings(
+		resp, err := service.client.Embedding(
 			service.ctx,
 			req)
 
 		var event EventManager
 		event.Errata(err)
 
-		if !isTestingEnvironment() {
+		if !isHeadless() {
 			node.layout.app.Sync()
 		}
 
@@ -342,6 +573,43 @@ func (c *Prompt) SendEmbeddingPrompt(service Agent) *gpt3.EmbeddingsResponse {
 	return nil
 }
 
+// SendSummaryPrompt - Summarize the expired turns together with the running summary
+func (c *Prompt) SendSummaryPrompt(service Agent, expired []gpt3.ChatCompletionRequestMessage) string {
+	engine := service.preferences.SummaryModel
+	if engine == "" {
+		engine = service.EngineProperties.Model
+	}
+
+	var transcript []string
+	for i := range expired {
+		transcript = append(transcript, fmt.Sprint(expired[i].Role, ": ", expired[i].Content))
+	}
+
+	req := gpt3.ChatCompletionRequest{
+		Model: engine,
+		User:  service.id,
+		Messages: []gpt3.ChatCompletionRequestMessage{
+			{
+				Role:    string(model.System),
+				Content: "Summarize the conversation in a concise paragraph, keep the names, facts, figures and open questions, the summary replaces the original messages.",
+			},
+			{
+				Role:    string(model.User),
+				Content: fmt.Sprint("Previous summary:\n", service.summary, "\n\nConversation:\n", strings.Join(transcript, "\n")),
+			},
+		},
+	}
+
+	resp, err := service.client.ChatCompletion(service.ctx, req)
+	if err != nil || resp == nil || resp.Choices == nil {
+		var event EventManager
+		event.Errata(err)
+		return ""
+	}
+
+	return strings.TrimSpace(resp.Choices[0].Message.Content)
+}
+
 // SendPredictablePrompt - Send a predictable request
 func (c *Prompt) SendPredictablePrompt(service Agent) *model.PredictResponse {
 	isValid := isContextValid(service)
@@ -359,7 +627,7 @@ func (c *Prompt) SendPredictablePrompt(service Agent) *model.PredictResponse {
 
 		var body io.Reader = bytes.NewBuffer(out)
 
-		path := string("https://api.gptzero.me/v2/predict/text")
+		path := service.preferences.PredictURL
 
 		if out != nil {
 			req, err := http.NewRequestWithContext(service.ctx, "POST", path, body)
@@ -401,8 +669,7 @@ func (c *Prompt) SendPredictablePrompt(service Agent) *model.PredictResponse {
 
 // GetListModels - Get actual list of available models
 func (c *Prompt) GetListModels(service Agent) *gpt3.EnginesResponse {
-	client := *service.client
-	resp, err := client.Engines(service.ctx)
+	resp, err := service.client.ListModels(service.ctx)
 
 	var event EventManager
 	event.Errata(err)
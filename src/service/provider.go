@@ -0,0 +1,168 @@
+// Package service section
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"caos/model"
+	"caos/service/parameters"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// Provider - Language model backend API used by the agent
+type Provider interface {
+	// ChatCompletion - Send a chat completion request
+	ChatCompletion(ctx context.Context, request gpt3.ChatCompletionRequest) (*gpt3.ChatCompletionResponse, error)
+	// ChatCompletionStream - Send a chat completion request streaming the response through onData
+	ChatCompletionStream(ctx context.Context, request gpt3.ChatCompletionRequest, onData func(*gpt3.ChatCompletionStreamResponse)) error
+	// Completion - Send a completion request with the selected engine
+	Completion(ctx context.Context, engine string, request gpt3.CompletionRequest) (*gpt3.CompletionResponse, error)
+	// CompletionStream - Send a completion request with the selected engine streaming the response through onData
+	CompletionStream(ctx context.Context, engine string, request gpt3.CompletionRequest, onData func(*gpt3.CompletionResponse)) error
+	// Edit - Send an edit instruction request
+	Edit(ctx context.Context, request gpt3.EditsRequest) (*gpt3.EditsResponse, error)
+	// Embedding - Send an embedding vector request
+	Embedding(ctx context.Context, request gpt3.EmbeddingsRequest) (*gpt3.EmbeddingsResponse, error)
+	// ListModels - List the models available on the backend
+	ListModels(ctx context.Context) (*gpt3.EnginesResponse, error)
+}
+
+// Forwarder - Backend relaying raw OpenAI compatible requests, the proxy keeps every field sent by its clients
+type Forwarder interface {
+	// Forward - Send the body as is to the route, the response is returned whatever its status
+	Forward(ctx context.Context, path string, header http.Header, body []byte) (*http.Response, error)
+}
+
+// OpenAIProvider - OpenAI backend implemented with go-gpt3
+type OpenAIProvider struct {
+	client gpt3.Client
+	// Raw requests relayed by the proxy
+	forwarder *CompatibleProvider
+}
+
+// NewOpenAIProvider - Create an OpenAI provider with the client options
+func NewOpenAIProvider(key string, options ...gpt3.ClientOption) *OpenAIProvider {
+	return &OpenAIProvider{
+		client:    gpt3.NewClient(key, options...),
+		forwarder: NewCompatibleProvider(parameters.OpenAIBaseURL, key, nil),
+	}
+}
+
+// Forward - Send the raw body to the route of the OpenAI endpoint
+func (c *OpenAIProvider) Forward(ctx context.Context, path string, header http.Header, body []byte) (*http.Response, error) {
+	return c.forwarder.Forward(ctx, path, header, body)
+}
+
+// ChatCompletion - Send a chat completion request
+func (c *OpenAIProvider) ChatCompletion(ctx context.Context, request gpt3.ChatCompletionRequest) (*gpt3.ChatCompletionResponse, error) {
+	return c.client.ChatCompletion(ctx, request)
+}
+
+// ChatCompletionStream - Send a chat completion request streaming the response through onData
+func (c *OpenAIProvider) ChatCompletionStream(ctx context.Context, request gpt3.ChatCompletionRequest, onData func(*gpt3.ChatCompletionStreamResponse)) error {
+	return c.client.ChatCompletionStream(ctx, request, onData)
+}
+
+// Completion - Send a completion request with the selected engine
+func (c *OpenAIProvider) Completion(ctx context.Context, engine string, request gpt3.CompletionRequest) (*gpt3.CompletionResponse, error) {
+	return c.client.CompletionWithEngine(ctx, engine, request)
+}
+
+// CompletionStream - Send a completion request with the selected engine streaming the response through onData
+func (c *OpenAIProvider) CompletionStream(ctx context.Context, engine string, request gpt3.CompletionRequest, onData func(*gpt3.CompletionResponse)) error {
+	return c.client.CompletionStreamWithEngine(ctx, engine, request, onData)
+}
+
+// Edit - Send an edit instruction request
+func (c *OpenAIProvider) Edit(ctx context.Context, request gpt3.EditsRequest) (*gpt3.EditsResponse, error) {
+	return c.client.Edits(ctx, request)
+}
+
+// Embedding - Send an embedding vector request
+func (c *OpenAIProvider) Embedding(ctx context.Context, request gpt3.EmbeddingsRequest) (*gpt3.EmbeddingsResponse, error) {
+	return c.client.Embeddings(ctx, request)
+}
+
+// ListModels - List the models available on the backend
+func (c *OpenAIProvider) ListModels(ctx context.Context) (*gpt3.EnginesResponse, error) {
+	return c.client.Engines(ctx)
+}
+
+// recordingProvider - Backend recording the responses, usage and errors of a headless request
+type recordingProvider struct {
+	Provider
+	id      string
+	choices []string
+	usage   model.BatchUsage
+	err     error
+}
+
+// ChatCompletion - Send the chat completion request and record the response
+func (c *recordingProvider) ChatCompletion(ctx context.Context, request gpt3.ChatCompletionRequest) (*gpt3.ChatCompletionResponse, error) {
+	resp, err := c.Provider.ChatCompletion(ctx, request)
+	c.err = err
+	if err == nil && resp != nil {
+		c.id, c.choices = resp.ID, nil
+		for _, i := range resp.Choices {
+			c.choices = append(c.choices, i.Message.Content)
+		}
+		c.addUsage(resp.Usage.PromptTokens, resp.Usage.CompletionTokens, resp.Usage.TotalTokens)
+	}
+	return resp, err
+}
+
+// Completion - Send the completion request and record the response
+func (c *recordingProvider) Completion(ctx context.Context, engine string, request gpt3.CompletionRequest) (*gpt3.CompletionResponse, error) {
+	resp, err := c.Provider.Completion(ctx, engine, request)
+	c.err = err
+	if err == nil && resp != nil {
+		c.id, c.choices = resp.ID, nil
+		for _, i := range resp.Choices {
+			c.choices = append(c.choices, i.Text)
+		}
+		c.addUsage(resp.Usage.PromptTokens, resp.Usage.CompletionTokens, resp.Usage.TotalTokens)
+	}
+	return resp, err
+}
+
+// addUsage - Add the tokens of a request, tool and repair loops send several
+func (c *recordingProvider) addUsage(prompt int, completion int, total int) {
+	c.usage.PromptTokens += prompt
+	c.usage.CompletionTokens += completion
+	c.usage.TotalTokens += total
+}
+
+// ChatCompletionStream - Send the streamed chat completion request and record its error
+func (c *recordingProvider) ChatCompletionStream(ctx context.Context, request gpt3.ChatCompletionRequest, onData func(*gpt3.ChatCompletionStreamResponse)) error {
+	c.err = c.Provider.ChatCompletionStream(ctx, request, onData)
+	return c.err
+}
+
+// CompletionStream - Send the streamed completion request and record its error
+func (c *recordingProvider) CompletionStream(ctx context.Context, engine string, request gpt3.CompletionRequest, onData func(*gpt3.CompletionResponse)) error {
+	c.err = c.Provider.CompletionStream(ctx, engine, request, onData)
+	return c.err
+}
+
+// Edit - Send the edit request and record its error
+func (c *recordingProvider) Edit(ctx context.Context, request gpt3.EditsRequest) (*gpt3.EditsResponse, error) {
+	resp, err := c.Provider.Edit(ctx, request)
+	c.err = err
+	return resp, err
+}
+
+// Embedding - Send the embedding request and record its error
+func (c *recordingProvider) Embedding(ctx context.Context, request gpt3.EmbeddingsRequest) (*gpt3.EmbeddingsResponse, error) {
+	resp, err := c.Provider.Embedding(ctx, request)
+	c.err = err
+	return resp, err
+}
+
+// ListModels - List the models and record the error
+func (c *recordingProvider) ListModels(ctx context.Context) (*gpt3.EnginesResponse, error) {
+	resp, err := c.Provider.ListModels(ctx)
+	c.err = err
+	return resp, err
+}
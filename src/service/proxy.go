@@ -0,0 +1,598 @@
+// Package service section
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"caos/model"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// DefaultProxyAddress - Local address of the logging proxy
+const DefaultProxyAddress = "127.0.0.1:8081"
+
+// proxyTrainingPath - Training sessions of the proxied exchanges, one per line
+var proxyTrainingPath = filepath.Join("training", "proxy.jsonl")
+
+// Proxy errors
+var (
+	errProxyRoute    = errors.New("proxy: unknown route, use /v1/chat/completions, /v1/completions or /v1/models")
+	errProxyMethod   = errors.New("proxy: method not allowed")
+	errProxyMessages = errors.New("proxy: the request doesn't have messages")
+	errProxyPrompt   = errors.New("proxy: the request doesn't have a prompt")
+)
+
+// Proxy - OpenAI compatible API forwarding to the backend of the agent and recording each exchange as a session
+type Proxy struct {
+	agent Agent
+	mutex sync.Mutex
+	// Conversations by the hash of their messages, the next request of a conversation continues its session
+	conversations map[string]*EventManager
+}
+
+// NewProxy - Proxy forwarding to the backend of the agent
+func NewProxy(agent Agent) *Proxy {
+	return &Proxy{
+		agent:         agent,
+		conversations: make(map[string]*EventManager),
+	}
+}
+
+// ServeProxy - Listen on the address with the backend of the profile
+func ServeProxy(address string) error {
+	if address == "" {
+		address = DefaultProxyAddress
+	}
+	return http.ListenAndServe(address, NewProxy(AttachHeadlessProfile()))
+}
+
+// ServeHTTP - Route the request
+func (c *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+
+	switch {
+	case path == "/v1/chat/completions" && r.Method == http.MethodPost:
+		c.chatCompletion(w, r)
+	case path == "/v1/completions" && r.Method == http.MethodPost:
+		c.completion(w, r)
+	case path == "/v1/models" && r.Method == http.MethodGet:
+		resp, err := c.agent.client.ListModels(r.Context())
+		if err != nil {
+			writeProxyError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	case path == "/v1/chat/completions" || path == "/v1/completions" || path == "/v1/models":
+		writeProxyError(w, gpt3.APIError{StatusCode: http.StatusMethodNotAllowed, Type: "invalid_request_error", Message: errProxyMethod.Error()})
+	default:
+		writeProxyError(w, gpt3.APIError{StatusCode: http.StatusNotFound, Type: "invalid_request_error", Message: errProxyRoute.Error()})
+	}
+}
+
+// proxyMessage - Chat message of a client, the content can be a text or a list of parts
+type proxyMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// proxyChatRequest - Fields of a chat completion body kept for the record, the body itself is forwarded as is
+type proxyChatRequest struct {
+	Model            string          `json:"model"`
+	Messages         []proxyMessage  `json:"messages"`
+	Temperature      *float32        `json:"temperature"`
+	TopP             *float32        `json:"top_p"`
+	N                int             `json:"n"`
+	Stream           bool            `json:"stream"`
+	Stop             json.RawMessage `json:"stop"`
+	MaxTokens        int             `json:"max_tokens"`
+	PresencePenalty  float32         `json:"presence_penalty"`
+	FrequencyPenalty float32         `json:"frequency_penalty"`
+	User             string          `json:"user"`
+}
+
+// chatCompletion - Forward the chat completion and record the answer as the next turn of its conversation
+func (c *Proxy) chatCompletion(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeProxyError(w, gpt3.APIError{StatusCode: http.StatusBadRequest, Type: "invalid_request_error", Message: err.Error()})
+		return
+	}
+
+	// The body is relayed as is, a decoded copy is recorded
+	if forwarder, isForwarded := c.agent.client.(Forwarder); isForwarded {
+		answer, isAnswered := forward(w, r, forwarder, "/chat/completions", body)
+		request, err := decodeProxyChat(body)
+		if !isAnswered || err != nil || len(request.Messages) == 0 {
+			return
+		}
+
+		resp := &gpt3.ChatCompletionResponse{}
+		if request.Stream {
+			readProxyStream(answer, func(data []byte) {
+				var out gpt3.ChatCompletionStreamResponse
+				if json.Unmarshal(data, &out) == nil {
+					appendChatChunk(resp, &out)
+				}
+			})
+		} else {
+			json.Unmarshal(answer, resp)
+		}
+		if resp.Choices != nil {
+			c.recordChat(request, resp)
+		}
+		return
+	}
+
+	request, err := decodeProxyChat(body)
+	if err != nil {
+		writeProxyError(w, gpt3.APIError{StatusCode: http.StatusBadRequest, Type: "invalid_request_error", Message: err.Error()})
+		return
+	}
+	if len(request.Messages) == 0 {
+		writeProxyError(w, gpt3.APIError{StatusCode: http.StatusBadRequest, Type: "invalid_request_error", Message: errProxyMessages.Error()})
+		return
+	}
+
+	if !request.Stream {
+		resp, err := c.agent.client.ChatCompletion(r.Context(), request)
+		if err != nil {
+			writeProxyError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+		c.recordChat(request, resp)
+		return
+	}
+
+	// The streamed deltas are relayed as they arrive and joined for the record
+	resp := &gpt3.ChatCompletionResponse{}
+	events := newProxyWriter(w)
+	err = c.agent.client.ChatCompletionStream(r.Context(), request, func(out *gpt3.ChatCompletionStreamResponse) {
+		events.send(out)
+		appendChatChunk(resp, out)
+	})
+	if err != nil {
+		events.fail(err)
+		return
+	}
+	events.done()
+
+	if resp.Choices != nil {
+		c.recordChat(request, resp)
+	}
+}
+
+// completion - Forward the completion and record the answer as a session
+func (c *Proxy) completion(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeProxyError(w, gpt3.APIError{StatusCode: http.StatusBadRequest, Type: "invalid_request_error", Message: err.Error()})
+		return
+	}
+
+	if forwarder, isForwarded := c.agent.client.(Forwarder); isForwarded {
+		answer, isAnswered := forward(w, r, forwarder, "/completions", body)
+		engine, request, err := decodeProxyCompletion(body)
+		if !isAnswered || err != nil {
+			return
+		}
+
+		resp := &gpt3.CompletionResponse{}
+		if request.Stream {
+			readProxyStream(answer, func(data []byte) {
+				var out gpt3.CompletionResponse
+				if json.Unmarshal(data, &out) == nil {
+					appendCompletionChunk(resp, &out)
+				}
+			})
+		} else {
+			json.Unmarshal(answer, resp)
+		}
+		if resp.Choices != nil {
+			c.recordCompletion(engine, request, resp)
+		}
+		return
+	}
+
+	engine, request, err := decodeProxyCompletion(body)
+	if err != nil {
+		writeProxyError(w, gpt3.APIError{StatusCode: http.StatusBadRequest, Type: "invalid_request_error", Message: err.Error()})
+		return
+	}
+
+	if !request.Stream {
+		resp, err := c.agent.client.Completion(r.Context(), engine, request)
+		if err != nil {
+			writeProxyError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+		c.recordCompletion(engine, request, resp)
+		return
+	}
+
+	resp := &gpt3.CompletionResponse{}
+	events := newProxyWriter(w)
+	err = c.agent.client.CompletionStream(r.Context(), engine, request, func(out *gpt3.CompletionResponse) {
+		events.send(out)
+		appendCompletionChunk(resp, out)
+	})
+	if err != nil {
+		events.fail(err)
+		return
+	}
+	events.done()
+
+	if resp.Choices != nil {
+		c.recordCompletion(engine, request, resp)
+	}
+}
+
+// forward - Relay the raw body and the answer of the backend as they are, the answer is returned for the record
+func forward(w http.ResponseWriter, r *http.Request, forwarder Forwarder, path string, body []byte) ([]byte, bool) {
+	resp, err := forwarder.Forward(r.Context(), path, r.Header, body)
+	if err != nil {
+		writeProxyError(w, err)
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	// Streams are flushed as they arrive
+	var answer bytes.Buffer
+	flusher, _ := w.(http.Flusher)
+	chunk := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(chunk)
+		if n > 0 {
+			w.Write(chunk[:n])
+			answer.Write(chunk[:n])
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	return answer.Bytes(), resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// readProxyStream - Data of each event of a relayed stream
+func readProxyStream(stream []byte, onData func([]byte)) {
+	for _, i := range bytes.Split(stream, []byte("\n")) {
+		line := bytes.TrimSpace(i)
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+
+		line = bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+		if !bytes.Equal(line, []byte("[DONE]")) {
+			onData(line)
+		}
+	}
+}
+
+// appendChatChunk - Join the streamed deltas in the choices of the response
+func appendChatChunk(resp *gpt3.ChatCompletionResponse, out *gpt3.ChatCompletionStreamResponse) {
+	resp.ID, resp.Object, resp.Created, resp.Model = out.ID, "chat.completion", out.Created, out.Model
+	for _, i := range out.Choices {
+		for len(resp.Choices) <= i.Index {
+			resp.Choices = append(resp.Choices, gpt3.ChatCompletionResponseChoice{Index: len(resp.Choices)})
+		}
+		choice := &resp.Choices[i.Index]
+		if i.Delta.Role != "" {
+			choice.Message.Role = i.Delta.Role
+		}
+		choice.Message.Content += i.Delta.Content
+		if i.FinishReason != "" {
+			choice.FinishReason = i.FinishReason
+		}
+	}
+}
+
+// appendCompletionChunk - Join the streamed texts in the choices of the response
+func appendCompletionChunk(resp *gpt3.CompletionResponse, out *gpt3.CompletionResponse) {
+	resp.ID, resp.Object, resp.Created, resp.Model = out.ID, "text_completion", out.Created, out.Model
+	for _, i := range out.Choices {
+		for len(resp.Choices) <= i.Index {
+			resp.Choices = append(resp.Choices, gpt3.CompletionResponseChoice{Index: len(resp.Choices)})
+		}
+		resp.Choices[i.Index].Text += i.Text
+		if i.FinishReason != "" {
+			resp.Choices[i.Index].FinishReason = i.FinishReason
+		}
+	}
+}
+
+// decodeProxyChat - Chat request of a body for the record and the backends without raw requests
+func decodeProxyChat(body []byte) (gpt3.ChatCompletionRequest, error) {
+	var fields proxyChatRequest
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return gpt3.ChatCompletionRequest{}, err
+	}
+
+	request := gpt3.ChatCompletionRequest{
+		Model:            fields.Model,
+		N:                fields.N,
+		Stream:           fields.Stream,
+		Stop:             decodeProxyList(fields.Stop),
+		MaxTokens:        fields.MaxTokens,
+		PresencePenalty:  fields.PresencePenalty,
+		FrequencyPenalty: fields.FrequencyPenalty,
+		User:             fields.User,
+	}
+	if fields.Temperature != nil {
+		request.Temperature = *fields.Temperature
+	}
+	if fields.TopP != nil {
+		request.TopP = *fields.TopP
+	}
+	for _, i := range fields.Messages {
+		request.Messages = append(request.Messages, gpt3.ChatCompletionRequestMessage{Role: i.Role, Content: decodeProxyContent(i.Content)})
+	}
+	return request, nil
+}
+
+// decodeProxyContent - Text of a message content, the text parts of a list are joined
+func decodeProxyContent(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	json.Unmarshal(content, &parts)
+
+	var texts []string
+	for _, i := range parts {
+		if i.Type == "text" {
+			texts = append(texts, i.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// decodeProxyList - Values of a field sent as a single string or as a list
+func decodeProxyList(field json.RawMessage) []string {
+	if len(field) == 0 || string(field) == "null" {
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(field, &value); err == nil {
+		return []string{value}
+	}
+
+	var values []string
+	json.Unmarshal(field, &values)
+	return values
+}
+
+// decodeProxyCompletion - Engine and request of a completion body, a single prompt or stop is sent as a list
+func decodeProxyCompletion(body []byte) (string, gpt3.CompletionRequest, error) {
+	var request gpt3.CompletionRequest
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return "", request, err
+	}
+
+	var engine string
+	json.Unmarshal(fields["model"], &engine)
+
+	for _, i := range []string{"prompt", "stop"} {
+		if values := decodeProxyList(fields[i]); values != nil {
+			fields[i], _ = json.Marshal(values)
+		} else {
+			delete(fields, i)
+		}
+	}
+
+	raw, _ := json.Marshal(fields)
+	if err := json.Unmarshal(raw, &request); err != nil {
+		return "", request, err
+	}
+	if len(request.Prompt) == 0 {
+		return "", request, errProxyPrompt
+	}
+	return engine, request, nil
+}
+
+// getConversation - Session of the messages, a new session when they don't continue a recorded conversation
+func (c *Proxy) getConversation(messages []gpt3.ChatCompletionRequestMessage) *EventManager {
+	key := getConversationKey(messages)
+	if events, isRecorded := c.conversations[key]; isRecorded {
+		delete(c.conversations, key)
+		return events
+	}
+
+	agent := c.agent
+	agent.preferences.IsNewSession = false
+	agent.preferences.Mode = "Turbo"
+	return &EventManager{agent: &agent}
+}
+
+// getConversationKey - Hash of the messages
+func getConversationKey(messages []gpt3.ChatCompletionRequestMessage) string {
+	raw, _ := json.Marshal(messages)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// recordChat - Log the exchange as the next turn of its conversation
+func (c *Proxy) recordChat(request gpt3.ChatCompletionRequest, resp *gpt3.ChatCompletionResponse) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	last := request.Messages[len(request.Messages)-1]
+	history := request.Messages[:len(request.Messages)-1]
+	events := c.getConversation(history)
+
+	var context []string
+	for _, i := range history {
+		context = append(context, fmt.Sprint(i.Role, ": ", i.Content))
+	}
+
+	user := request.User
+	if user == "" {
+		user = c.agent.id
+	}
+
+	header := model.EngineProperties{
+		UserID:           user,
+		Model:            request.Model,
+		Role:             model.Roles(last.Role),
+		Temperature:      request.Temperature,
+		TopP:             request.TopP,
+		PresencePenalty:  request.PresencePenalty,
+		FrequencyPenalty: request.FrequencyPenalty,
+	}
+	body := model.PromptProperties{
+		Input:     []string{last.Content},
+		MaxTokens: request.MaxTokens,
+		Results:   request.N,
+	}
+	events.LogChatCompletion(model.TemplateProperties{Input: context}, header, body, resp, nil)
+	c.appendToTraining(events)
+
+	// The next request of the conversation carries the first answer
+	reply := gpt3.ChatCompletionRequestMessage{Role: string(model.Assistant), Content: resp.Choices[0].Message.Content}
+	c.conversations[getConversationKey(append(append([]gpt3.ChatCompletionRequestMessage{}, request.Messages...), reply))] = events
+}
+
+// recordCompletion - Log the exchange as a new session
+func (c *Proxy) recordCompletion(engine string, request gpt3.CompletionRequest, resp *gpt3.CompletionResponse) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	agent := c.agent
+	agent.preferences.IsNewSession = true
+	agent.preferences.Mode = "Text"
+	events := &EventManager{agent: &agent}
+
+	header := model.EngineProperties{
+		UserID:           c.agent.id,
+		Model:            engine,
+		Role:             model.User,
+		PresencePenalty:  request.PresencePenalty,
+		FrequencyPenalty: request.FrequencyPenalty,
+	}
+	if request.Temperature != nil {
+		header.Temperature = *request.Temperature
+	}
+	if request.TopP != nil {
+		header.TopP = *request.TopP
+	}
+
+	body := model.PromptProperties{Input: request.Prompt}
+	if request.MaxTokens != nil {
+		body.MaxTokens = *request.MaxTokens
+	}
+	if request.N != nil {
+		body.Results = *request.N
+	}
+
+	var choices []string
+	for _, i := range resp.Choices {
+		choices = append(choices, i.Text)
+	}
+	events.LogGeneralCompletion(header, body, choices, resp.ID)
+	c.appendToTraining(events)
+}
+
+// appendToTraining - Append the training session of the latest exchange
+func (c *Proxy) appendToTraining(events *EventManager) {
+	sessions := events.GetPool().TrainingSession
+	if sessions == nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(proxyTrainingPath), 0755); err != nil {
+		return
+	}
+	file, err := os.OpenFile(proxyTrainingPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	raw, _ := json.Marshal(sessions[len(sessions)-1])
+	file.Write(append(raw, '\n'))
+}
+
+// writeProxyError - Write the error in the OpenAI format, backend errors keep their status
+func writeProxyError(w http.ResponseWriter, err error) {
+	var apiErr gpt3.APIError
+	if !errors.As(err, &apiErr) {
+		apiErr = gpt3.APIError{StatusCode: http.StatusBadGateway, Type: "api_error", Message: err.Error()}
+	}
+	if apiErr.StatusCode == 0 {
+		apiErr.StatusCode = http.StatusBadGateway
+	}
+	writeJSON(w, apiErr.StatusCode, gpt3.APIErrorResponse{Error: apiErr})
+}
+
+// proxyWriter - OpenAI stream of the relayed chunks
+type proxyWriter struct {
+	events *eventWriter
+	isSent bool
+}
+
+// newProxyWriter - Stream of the response, the headers are sent with the first chunk
+func newProxyWriter(w http.ResponseWriter) *proxyWriter {
+	return &proxyWriter{events: &eventWriter{w: w}}
+}
+
+// start - Send the stream headers once
+func (c *proxyWriter) start() {
+	if !c.isSent {
+		c.events = newEventWriter(c.events.w)
+		c.isSent = true
+	}
+}
+
+// send - Relay the chunk as a data event
+func (c *proxyWriter) send(chunk interface{}) {
+	c.start()
+	raw, _ := json.Marshal(chunk)
+	c.events.write(fmt.Sprintf("data: %s\n\n", raw))
+}
+
+// done - Close the stream
+func (c *proxyWriter) done() {
+	c.start()
+	c.events.write("data: [DONE]\n\n")
+}
+
+// fail - Answer with the error, or send it in the stream when it already started
+func (c *proxyWriter) fail(err error) {
+	if !c.isSent {
+		writeProxyError(c.events.w, err)
+		return
+	}
+
+	var apiErr gpt3.APIError
+	if !errors.As(err, &apiErr) {
+		apiErr = gpt3.APIError{StatusCode: http.StatusBadGateway, Type: "api_error", Message: err.Error()}
+	}
+	c.send(gpt3.APIErrorResponse{Error: apiErr})
+}
@@ -0,0 +1,309 @@
+// Package service section
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"caos/model"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// ReplayProvider - Offline backend answering prompts with the completions recorded in log sessions
+type ReplayProvider struct {
+	path    string
+	records []model.HistoricalSession
+}
+
+// errNotRecorded - No recorded completion matches the request
+var errNotRecorded = errors.New("replay: no recorded completion matches the prompt")
+
+// NewReplayProvider - Create a replay provider loading every session stored in the log directory
+func NewReplayProvider(path string) *ReplayProvider {
+	provider := &ReplayProvider{
+		path: path,
+	}
+	provider.Load()
+	return provider
+}
+
+// Load - Read the recorded sessions from the log directory
+func (c *ReplayProvider) Load() int {
+	c.records = nil
+
+	files, _ := filepath.Glob(filepath.Join(c.path, "*.json"))
+	for _, i := range files {
+		raw, err := os.ReadFile(i)
+		if err != nil {
+			continue
+		}
+
+		var session model.HistoricalSession
+		if err := json.Unmarshal(raw, &session); err != nil || session.Session == nil {
+			continue
+		}
+
+		c.records = append(c.records, session)
+	}
+
+	return len(c.records)
+}
+
+// GetRecords - Recorded sessions available for replay
+func (c *ReplayProvider) GetRecords() []model.HistoricalSession {
+	return c.records
+}
+
+// replayContexts - Beginning of the contextual information appended after the user input
+var replayContexts = []string{chatContextPrompt, knowledgeContextPrompt}
+
+// normalizeReplay - Text compared without the surrounding and repeated whitespace
+func normalizeReplay(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// getReplayInput - User input of a chat message without the appended contextual information
+func getReplayInput(message string) string {
+	for _, i := range replayContexts {
+		if index := strings.Index(message, i); index >= 0 {
+			message = message[:index]
+		}
+	}
+	return message
+}
+
+// match - Find the latest recorded event whose input equals the prompt, text prompts can start with the template
+func (c *ReplayProvider) match(prompt string, instruction string, isTemplated bool) (string, model.HistoricalEvent, error) {
+	var id string
+	var event model.HistoricalEvent
+	isMatched := false
+
+	prompt = normalizeReplay(prompt)
+	for _, i := range c.records {
+		for _, j := range i.Session {
+			if j.Event.Body.Input == nil || j.Event.Body.Content == nil {
+				continue
+			}
+
+			input := normalizeReplay(j.Event.Body.Input[0])
+			if input == "" || (prompt != input && (!isTemplated || !strings.HasSuffix(prompt, " "+input))) {
+				continue
+			}
+
+			if instruction != "" &&
+				(j.Event.Body.Instruction == nil || normalizeReplay(j.Event.Body.Instruction[0]) != normalizeReplay(instruction)) {
+				continue
+			}
+
+			if !isMatched || j.Timestamp > event.Timestamp {
+				id, event, isMatched = i.ID, j, true
+			}
+		}
+	}
+
+	if !isMatched {
+		return "", event, errNotRecorded
+	}
+	return id, event, nil
+}
+
+// splitReplayDeltas - Split a recorded completion into streaming deltas
+func splitReplayDeltas(text string) []string {
+	var deltas []string
+	words := strings.SplitAfter(text, " ")
+	for _, i := range words {
+		if i != "" {
+			deltas = append(deltas, i)
+		}
+	}
+	return deltas
+}
+
+// lastUserMessage - Content of the last message sent by the user
+func lastUserMessage(messages []gpt3.ChatCompletionRequestMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != string(model.System) {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+// ChatCompletion - Answer a chat completion request with the recorded completion
+func (c *ReplayProvider) ChatCompletion(ctx context.Context, request gpt3.ChatCompletionRequest) (*gpt3.ChatCompletionResponse, error) {
+	id, event, err := c.match(getReplayInput(lastUserMessage(request.Messages)), "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gpt3.ChatCompletionResponse{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: int(time.Now().Unix()),
+		Model:   event.Event.Header.Model,
+		Choices: []gpt3.ChatCompletionResponseChoice{
+			{
+				FinishReason: "stop",
+				Message: gpt3.ChatCompletionResponseMessage{
+					Role:    string(model.Assistant),
+					Content: strings.Join(event.Event.Body.Content, ""),
+				},
+			},
+		},
+	}, nil
+}
+
+// ChatCompletionStream - Stream the recorded completion through onData
+func (c *ReplayProvider) ChatCompletionStream(ctx context.Context, request gpt3.ChatCompletionRequest, onData func(*gpt3.ChatCompletionStreamResponse)) error {
+	id, event, err := c.match(getReplayInput(lastUserMessage(request.Messages)), "", false)
+	if err != nil {
+		return err
+	}
+
+	deltas := splitReplayDeltas(strings.Join(event.Event.Body.Content, ""))
+	for i := range deltas {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var reason string
+		if i == len(deltas)-1 {
+			reason = "stop"
+		}
+
+		onData(&gpt3.ChatCompletionStreamResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: int(time.Now().Unix()),
+			Model:   event.Event.Header.Model,
+			Choices: []gpt3.ChatCompletionStreamResponseChoice{
+				{
+					FinishReason: reason,
+					Delta: gpt3.ChatCompletionResponseMessage{
+						Role:    string(model.Assistant),
+						Content: deltas[i],
+					},
+				},
+			},
+		})
+	}
+	return nil
+}
+
+// Completion - Answer a completion request with the recorded completion
+func (c *ReplayProvider) Completion(ctx context.Context, engine string, request gpt3.CompletionRequest) (*gpt3.CompletionResponse, error) {
+	id, event, err := c.match(strings.Join(request.Prompt, ""), "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gpt3.CompletionResponse{
+		ID:      id,
+		Object:  "text_completion",
+		Created: int(time.Now().Unix()),
+		Model:   engine,
+		Choices: []gpt3.CompletionResponseChoice{
+			{
+				Text:         strings.Join(event.Event.Body.Content, ""),
+				FinishReason: "stop",
+			},
+		},
+	}, nil
+}
+
+// CompletionStream - Stream the recorded completion through onData
+func (c *ReplayProvider) CompletionStream(ctx context.Context, engine string, request gpt3.CompletionRequest, onData func(*gpt3.CompletionResponse)) error {
+	id, event, err := c.match(strings.Join(request.Prompt, ""), "", true)
+	if err != nil {
+		return err
+	}
+
+	deltas := splitReplayDeltas(strings.Join(event.Event.Body.Content, ""))
+	for i := range deltas {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var reason string
+		if i == len(deltas)-1 {
+			reason = "stop"
+		}
+
+		onData(&gpt3.CompletionResponse{
+			ID:      id,
+			Object:  "text_completion",
+			Created: int(time.Now().Unix()),
+			Model:   engine,
+			Choices: []gpt3.CompletionResponseChoice{
+				{
+					Text:         deltas[i],
+					FinishReason: reason,
+				},
+			},
+		})
+	}
+	return nil
+}
+
+// Edit - Answer an edit request with the recorded completion for the same input and instruction
+func (c *ReplayProvider) Edit(ctx context.Context, request gpt3.EditsRequest) (*gpt3.EditsResponse, error) {
+	_, event, err := c.match(request.Input, request.Instruction, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gpt3.EditsResponse{
+		Object:  "edit",
+		Created: int(time.Now().Unix()),
+		Choices: []gpt3.EditsResponseChoice{
+			{
+				Text: strings.Join(event.Event.Body.Content, ""),
+			},
+		},
+	}, nil
+}
+
+// Embedding - Embedding vectors are not recorded in the log sessions
+func (c *ReplayProvider) Embedding(ctx context.Context, request gpt3.EmbeddingsRequest) (*gpt3.EmbeddingsResponse, error) {
+	return nil, errors.New("replay: embedding vectors are not recorded")
+}
+
+// ListModels - Models found in the recorded sessions
+func (c *ReplayProvider) ListModels(ctx context.Context) (*gpt3.EnginesResponse, error) {
+	var models []string
+	for _, i := range c.records {
+		for _, j := range i.Session {
+			isListed := false
+			for _, k := range models {
+				if k == j.Event.Header.Model {
+					isListed = true
+				}
+			}
+
+			if !isListed && j.Event.Header.Model != "" {
+				models = append(models, j.Event.Header.Model)
+			}
+		}
+	}
+	sort.Strings(models)
+
+	resp := &gpt3.EnginesResponse{
+		Object: "list",
+	}
+	for _, i := range models {
+		resp.Data = append(resp.Data, gpt3.EngineObject{
+			ID:     i,
+			Object: "model",
+			Owner:  "replay",
+			Ready:  true,
+		})
+	}
+	return resp, nil
+}
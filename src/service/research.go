@@ -0,0 +1,287 @@
+// Package service section
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"caos/model"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// Research agent limits
+const (
+	researchWords  = 300
+	researchTokens = 1024
+)
+
+// Research agent actions
+const (
+	searchAction = "search"
+	openAction   = "open"
+	answerAction = "answer"
+)
+
+// Research errors
+var (
+	errResearchEmpty  = errors.New("research: the response doesn't contain choices")
+	errResearchSource = errors.New("research: the source isn't listed in the search results")
+	errResearchLimits = errors.New("research: the steps and the token budget must be greater than 0")
+)
+
+// Researcher - Plan, search, read and answer loop over the chain transformer and the chat endpoint
+type Researcher struct {
+	MaxSteps int
+	Budget   int
+	// Search - Urls and text of the search results
+	Search func(query string) model.ChainPrompt
+	// Open - Text of a source url
+	Open func(url string) model.ChainPrompt
+	// OnStep - Called after each step with the partial report
+	OnStep func(report model.ResearchReport)
+}
+
+// researchAction - Step requested by the model
+type researchAction struct {
+	Action  string      `json:"action"`
+	Thought string      `json:"thought"`
+	Query   string      `json:"query"`
+	Source  interface{} `json:"source"`
+	Report  string      `json:"report"`
+}
+
+// NewResearcher - Research agent with the limits of the agent preferences and the web chain
+func NewResearcher(service Agent) *Researcher {
+	return &Researcher{
+		MaxSteps: service.preferences.ResearchSteps,
+		Budget:   service.preferences.ResearchBudget,
+		Search: func(query string) model.ChainPrompt {
+			var chain Chain
+			chain.ExecuteSearchJob(service, query)
+			return chain.Transform
+		},
+		Open: func(url string) model.ChainPrompt {
+			var chain Chain
+			chain.ExecuteReadJob(service, url)
+			return chain.Transform
+		},
+	}
+}
+
+// getResearchMessage - System message with the research protocol
+func getResearchMessage() gpt3.ChatCompletionRequestMessage {
+	return gpt3.ChatCompletionRequestMessage{
+		Role: string(model.System),
+		Content: fmt.Sprint(
+			"You are a research agent, plan the research, search the web, read the most relevant sources and answer when you have enough evidence.",
+			"\nReply ONLY with one JSON object per step, include your plan for the step in \"thought\":",
+			"\n{\"action\": \"search\", \"thought\": \"...\", \"query\": \"<search terms>\"} to search the web, the results list numbered sources",
+			"\n{\"action\": \"open\", \"thought\": \"...\", \"source\": <number>} to read one of the listed sources",
+			"\n{\"action\": \"answer\", \"report\": \"...\"} to finish with a detailed report citing the sources you read as [number]"),
+	}
+}
+
+// parseResearchAction - Action requested in the model reply
+func parseResearchAction(content string) (researchAction, bool) {
+	var action researchAction
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return action, false
+	}
+
+	if err := json.Unmarshal([]byte(content[start:end+1]), &action); err != nil || action.Action == "" {
+		return action, false
+	}
+	return action, true
+}
+
+// truncateWords - First words of a text
+func truncateWords(text string, words int) string {
+	fields := strings.Fields(text)
+	if len(fields) <= words {
+		return strings.Join(fields, " ")
+	}
+	return fmt.Sprint(strings.Join(fields[:words], " "), "...")
+}
+
+// addSource - Number of the url in the report sources, listed when it's new
+func addSource(report *model.ResearchReport, url string) int {
+	for _, i := range report.Sources {
+		if i.URL == url {
+			return i.ID
+		}
+	}
+
+	id := len(report.Sources) + 1
+	report.Sources = append(report.Sources, model.ResearchSource{ID: id, URL: url})
+	return id
+}
+
+// getSource - Listed source by number or url
+func getSource(report *model.ResearchReport, source interface{}) (*model.ResearchSource, error) {
+	reference := strings.Trim(fmt.Sprint(source), "[] ")
+	id, _ := strconv.Atoi(reference)
+	for i := range report.Sources {
+		if report.Sources[i].ID == id || report.Sources[i].URL == reference {
+			return &report.Sources[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", errResearchSource, reference)
+}
+
+// runAction - Execute a search or open action and describe the observation for the model
+func (c *Researcher) runAction(report *model.ResearchReport, action researchAction, step *model.ResearchStep) string {
+	switch action.Action {
+	case searchAction:
+		step.Input = action.Query
+		results := c.Search(action.Query)
+
+		var listed []string
+		for _, i := range results.Source {
+			listed = append(listed, fmt.Sprintf("[%v] %v", addSource(report, i), i))
+		}
+		step.Result = fmt.Sprintf("%v sources", len(listed))
+
+		return fmt.Sprint("Search results for ", action.Query, ":\n", strings.Join(listed, "\n"),
+			"\nSummary: ", truncateWords(strings.Join(results.Context, " "), researchWords))
+	case openAction:
+		source, err := getSource(report, action.Source)
+		if err != nil {
+			step.Input = fmt.Sprint(action.Source)
+			step.Result = err.Error()
+			return err.Error()
+		}
+
+		step.Input = source.URL
+		page := c.Open(source.URL)
+		source.Read = true
+		source.Excerpt = truncateWords(strings.Join(page.Context, " "), researchWords)
+		step.Result = fmt.Sprintf("%v words read", len(strings.Fields(source.Excerpt)))
+
+		return fmt.Sprintf("Source [%v] %v:\n%v", source.ID, source.URL, source.Excerpt)
+	}
+
+	step.Result = fmt.Sprintf("unknown action %v", action.Action)
+	return fmt.Sprintf("Unknown action %v, use search, open or answer.", action.Action)
+}
+
+// Run - Research the question until the model answers or the steps or token budget are exhausted
+func (c *Researcher) Run(service Agent, question string) (model.ResearchReport, error) {
+	report := model.ResearchReport{Question: question}
+	if c.MaxSteps < 1 || c.Budget < 1 {
+		return report, errResearchLimits
+	}
+
+	messages := []gpt3.ChatCompletionRequestMessage{
+		getResearchMessage(),
+		{Role: string(model.User), Content: question},
+	}
+
+	for isFinal := false; ; {
+		if !isFinal && (len(report.Steps) >= c.MaxSteps || report.Tokens >= c.Budget) {
+			isFinal = true
+			report.Stopped = "steps"
+			if report.Tokens >= c.Budget {
+				report.Stopped = "budget"
+			}
+
+			messages = append(messages, gpt3.ChatCompletionRequestMessage{
+				Role:    string(model.User),
+				Content: "The research limit was reached, reply now with the answer action using the evidence you have.",
+			})
+		}
+
+		resp, err := service.client.ChatCompletion(service.ctx, gpt3.ChatCompletionRequest{
+			Model:       service.EngineProperties.Model,
+			User:        service.id,
+			Messages:    messages,
+			MaxTokens:   researchTokens,
+			Temperature: *gpt3.Float32Ptr(service.EngineProperties.Temperature),
+		})
+		if err != nil {
+			return report, err
+		}
+		if resp == nil || len(resp.Choices) == 0 {
+			return report, errResearchEmpty
+		}
+
+		content := resp.Choices[0].Message.Content
+		step := model.ResearchStep{
+			Step:   len(report.Steps) + 1,
+			Tokens: resp.Usage.TotalTokens,
+		}
+		report.Tokens += resp.Usage.TotalTokens
+
+		action, isAction := parseResearchAction(content)
+		step.Action, step.Thought = action.Action, action.Thought
+		if !isAction || action.Action == answerAction || isFinal {
+			// Replies without the protocol are the final answer
+			step.Action = answerAction
+			report.Report = strings.TrimSpace(content)
+			if isAction && action.Report != "" {
+				report.Report = action.Report
+			} else if isAction {
+				report.Report = "The research limit was reached before the answer, these are the sources found so far."
+			}
+
+			report.Steps = append(report.Steps, step)
+			c.onStep(report)
+			return report, nil
+		}
+
+		observation := c.runAction(&report, action, &step)
+		report.Steps = append(report.Steps, step)
+		c.onStep(report)
+
+		messages = append(messages,
+			gpt3.ChatCompletionRequestMessage{Role: string(model.Assistant), Content: content},
+			gpt3.ChatCompletionRequestMessage{Role: string(model.User), Content: observation})
+	}
+}
+
+// onStep - Notify the partial report
+func (c *Researcher) onStep(report model.ResearchReport) {
+	if c.OnStep != nil {
+		c.OnStep(report)
+	}
+}
+
+// FormatResearchSteps - Step log of the research
+func FormatResearchSteps(report model.ResearchReport) string {
+	out := []string{fmt.Sprintf("Research: %v\nTokens: %v", report.Question, report.Tokens)}
+	for _, i := range report.Steps {
+		line := fmt.Sprintf("%v. %v %v", i.Step, i.Action, i.Input)
+		if i.Result != "" {
+			line = fmt.Sprint(line, " -> ", i.Result)
+		}
+		if i.Thought != "" {
+			line = fmt.Sprint(line, "\n   ", i.Thought)
+		}
+		out = append(out, line)
+	}
+
+	if report.Stopped != "" {
+		out = append(out, fmt.Sprintf("Stopped by the %v limit", report.Stopped))
+	}
+	return strings.Join(out, "\n")
+}
+
+// FormatResearchReport - Final report with the cited sources
+func FormatResearchReport(report model.ResearchReport) string {
+	var sources []string
+	for _, i := range report.Sources {
+		if i.Read || strings.Contains(report.Report, fmt.Sprintf("[%v]", i.ID)) {
+			sources = append(sources, fmt.Sprintf("[%v] %v", i.ID, i.URL))
+		}
+	}
+
+	if sources == nil {
+		return report.Report
+	}
+	return fmt.Sprint(report.Report, "\n\nSources:\n", strings.Join(sources, "\n"))
+}
@@ -0,0 +1,277 @@
+// Package service section
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"caos/model"
+	"caos/util"
+)
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+// RPC errors
+var (
+	errRPCVersion = errors.New("rpc: use jsonrpc 2.0 with a method")
+	errRPCMethod  = errors.New("rpc: unknown method")
+	errRPCParams  = errors.New("rpc: invalid params")
+	errRPCPrompt  = errors.New("rpc: the prompt is empty")
+)
+
+// RPC - Line delimited JSON-RPC 2.0 of the controller for editor integrations, requests are answered in order
+type RPC struct {
+	agent  Agent
+	client *Headless
+	out    io.Writer
+	mutex  sync.Mutex
+}
+
+// rpcPromptParams - Prompt of prompt/send and templates/render, streamed by default
+type rpcPromptParams struct {
+	Prompt    string            `json:"prompt"`
+	Template  string            `json:"template,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+	Stream    *bool             `json:"stream,omitempty"`
+}
+
+// rpcEditParams - Selection and instruction of edit/apply
+type rpcEditParams struct {
+	Selection   string `json:"selection"`
+	Instruction string `json:"instruction"`
+}
+
+// rpcExportParams - Format of session/export
+type rpcExportParams struct {
+	Format string `json:"format,omitempty"`
+}
+
+// NewRPC - JSON-RPC of the agent writing its responses and notifications to the output
+func NewRPC(agent Agent, out io.Writer) (*RPC, error) {
+	// Only the streamed prompts write to the client, the output is kept for the protocol
+	client, err := NewHeadless(agent, HeadlessOptions{}, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	return &RPC{agent: agent, client: client, out: out}, nil
+}
+
+// ServeRPC - Answer the requests of the standard input with the agent of the profile
+func ServeRPC(in io.Reader, out io.Writer) error {
+	rpc, err := NewRPC(AttachHeadlessProfile(), out)
+	if err != nil {
+		return err
+	}
+	return rpc.Serve(in)
+}
+
+// Serve - Answer each request line until the input is closed
+func (c *RPC) Serve(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), batchLineBytes)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var request model.RPCRequest
+		if err := json.Unmarshal(line, &request); err != nil {
+			c.reply(json.RawMessage("null"), nil, &model.RPCError{Code: rpcParseError, Message: err.Error()})
+			continue
+		}
+		if request.JSONRPC != "2.0" || request.Method == "" {
+			c.reply(request.ID, nil, &model.RPCError{Code: rpcInvalidRequest, Message: errRPCVersion.Error()})
+			continue
+		}
+
+		result, err := c.call(request)
+		// Notifications don't have a response
+		if request.ID == nil {
+			continue
+		}
+		if err != nil {
+			c.reply(request.ID, nil, &model.RPCError{Code: getRPCCode(err), Message: err.Error()})
+			continue
+		}
+		c.reply(request.ID, result, nil)
+	}
+	return scanner.Err()
+}
+
+// call - Run the method of the request
+func (c *RPC) call(request model.RPCRequest) (interface{}, error) {
+	switch request.Method {
+	case "session/new":
+		var options HeadlessOptions
+		if err := decodeParams(request.Params, &options); err != nil {
+			return nil, err
+		}
+
+		client, err := NewHeadless(c.agent, options, io.Discard)
+		if err != nil {
+			return nil, err
+		}
+		c.client = client
+		return c.client.describe("rpc"), nil
+	case "templates/list":
+		var templates []model.RPCTemplate
+		for i := range c.agent.templateID {
+			variables, defaults := util.GetTemplateVariables(c.agent.templateCtx[i])
+			templates = append(templates, model.RPCTemplate{
+				Name:      c.agent.templateID[i],
+				Context:   c.agent.templateCtx[i],
+				Variables: variables,
+				Defaults:  defaults,
+			})
+		}
+		return templates, nil
+	case "templates/render":
+		var params rpcPromptParams
+		if err := decodeParams(request.Params, &params); err != nil {
+			return nil, err
+		}
+
+		agent, err := c.renderAgent(params)
+		if err != nil {
+			return nil, err
+		}
+		return agent.SetTemplate("", params.Prompt)[0], nil
+	case "models/list":
+		return c.client.ListModels()
+	case "prompt/send":
+		var params rpcPromptParams
+		if err := decodeParams(request.Params, &params); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(params.Prompt) == "" {
+			return nil, errRPCPrompt
+		}
+
+		// The template of the request doesn't change the one of the session
+		rendered, err := c.renderAgent(params)
+		if err != nil {
+			return nil, err
+		}
+		agent := c.client.getAgent()
+		session := *agent
+		agent.applyTemplate(rendered)
+		defer agent.applyTemplate(session)
+
+		// Streamed tokens are sent as prompt/token notifications of the request
+		c.client.getAgent().preferences.IsPromptStreaming = params.Stream == nil || *params.Stream
+		c.client.out = &rpcWriter{rpc: c, id: request.ID}
+		defer func() {
+			c.client.out = io.Discard
+		}()
+
+		choices, _, err := c.client.Complete(params.Prompt)
+		if err != nil {
+			return nil, err
+		}
+		return model.PromptResponse{Mode: "chat", Choices: choices}, nil
+	case "edit/apply":
+		var params rpcEditParams
+		if err := decodeParams(request.Params, &params); err != nil {
+			return nil, err
+		}
+
+		choices, err := c.client.Revise(params.Selection, params.Instruction)
+		if err != nil {
+			return nil, err
+		}
+		return model.PromptResponse{Mode: "edit", Choices: choices}, nil
+	case "session/export":
+		var params rpcExportParams
+		if err := decodeParams(request.Params, &params); err != nil {
+			return nil, err
+		}
+		return c.client.Export(c.client.getAgent().preferences.CurrentID, params.Format)
+	}
+	return nil, fmt.Errorf("%w: %v", errRPCMethod, request.Method)
+}
+
+// renderAgent - Copy of the session agent with the template and variables of the request
+func (c *RPC) renderAgent(params rpcPromptParams) (Agent, error) {
+	agent := *c.client.getAgent()
+	if !agent.selectTemplate(params.Template) {
+		return agent, fmt.Errorf("%w: %v", errHeadlessTemplate, params.Template)
+	}
+	if params.Template != "" || params.Variables != nil {
+		agent.SetTemplateVariables(params.Variables)
+	}
+	return agent, nil
+}
+
+// reply - Write the response of the request
+func (c *RPC) reply(id json.RawMessage, result interface{}, err *model.RPCError) {
+	c.write(model.RPCResponse{JSONRPC: "2.0", ID: id, Result: result, Error: err})
+}
+
+// notify - Write a notification
+func (c *RPC) notify(method string, params interface{}) error {
+	return c.write(model.RPCNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// write - Write the message in a single line
+func (c *RPC) write(message interface{}) error {
+	raw, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, err = c.out.Write(append(raw, '\n'))
+	return err
+}
+
+// decodeParams - Decode the params of the request, missing params keep the defaults
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return fmt.Errorf("%w: %v", errRPCParams, err)
+	}
+	return nil
+}
+
+// getRPCCode - Invalid methods and params are client errors, the rest failed on the backend
+func getRPCCode(err error) int {
+	switch {
+	case errors.Is(err, errRPCMethod):
+		return rpcMethodNotFound
+	case errors.Is(err, errRPCParams), errors.Is(err, errRPCPrompt), errors.Is(err, errHeadlessTemplate),
+		errors.Is(err, errHeadlessRole), errors.Is(err, errHeadlessInstruction), errors.Is(err, errHeadlessFormat):
+		return rpcInvalidParams
+	}
+	return rpcServerError
+}
+
+// rpcWriter - Streamed tokens of a request sent as notifications
+type rpcWriter struct {
+	rpc *RPC
+	id  json.RawMessage
+}
+
+// Write - Send the text as a prompt/token notification
+func (c *rpcWriter) Write(p []byte) (int, error) {
+	if err := c.rpc.notify("prompt/token", model.RPCToken{ID: c.id, Text: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
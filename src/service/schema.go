@@ -0,0 +1,84 @@
+// Package service section
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"caos/model"
+	"caos/util"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// loadSchema - Parse the JSON Schema file attached to the prompt
+func loadSchema(path string) (*util.Schema, string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	schema, err := util.ParseSchema(raw)
+	if err != nil {
+		return nil, "", err
+	}
+	return schema, string(raw), nil
+}
+
+// insertSystemMessage - Add the message after the template and summary messages of the history
+func insertSystemMessage(history []gpt3.ChatCompletionRequestMessage, message gpt3.ChatCompletionRequestMessage) []gpt3.ChatCompletionRequestMessage {
+	system := 0
+	for system < len(history) && history[system].Role == string(model.System) {
+		system++
+	}
+
+	messages := append([]gpt3.ChatCompletionRequestMessage{}, history[:system]...)
+	messages = append(messages, message)
+	return append(messages, history[system:]...)
+}
+
+// getSchemaMessage - System message requesting output conforming to the schema
+func getSchemaMessage(schema string) gpt3.ChatCompletionRequestMessage {
+	return gpt3.ChatCompletionRequestMessage{
+		Role: string(model.System),
+		Content: fmt.Sprint(
+			"Reply ONLY with a JSON value that conforms to the following JSON Schema, without explanations or markdown:\n",
+			schema),
+	}
+}
+
+// getSchemaRepairMessage - Validation errors sent back to the model
+func getSchemaRepairMessage(errs []string) gpt3.ChatCompletionRequestMessage {
+	return gpt3.ChatCompletionRequestMessage{
+		Role: string(model.User),
+		Content: fmt.Sprint(
+			"The output doesn't conform to the JSON Schema:\n- ",
+			strings.Join(errs, "\n- "),
+			"\nReply ONLY with the corrected JSON value."),
+	}
+}
+
+// validateSchemaOutput - Indented JSON value of the output and its validation errors
+func validateSchemaOutput(schema *util.Schema, content string) (string, []string) {
+	value, err := util.ExtractJSON(content)
+	if err != nil {
+		return "", []string{err.Error()}
+	}
+
+	if errs := schema.Validate(value); errs != nil {
+		return "", errs
+	}
+
+	raw, _ := json.MarshalIndent(value, "", "\u0009")
+	return string(raw), nil
+}
+
+// FormatSchemaErrors - Validation result of the structured output
+func FormatSchemaErrors(errs []string) string {
+	if errs == nil {
+		return "Schema: the output conforms to the JSON Schema\n"
+	}
+	return fmt.Sprint("Schema: the output doesn't conform to the JSON Schema\n- ", strings.Join(errs, "\n- "), "\n")
+}
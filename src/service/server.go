@@ -0,0 +1,361 @@
+// Package service section
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"caos/model"
+)
+
+// DefaultServerAddress - Local address of the API server
+const DefaultServerAddress = "127.0.0.1:8080"
+
+// Server errors
+var (
+	errServerSession = errors.New("server: unknown session")
+	errServerMode    = errors.New("server: unknown mode, use chat, edit, embed or predict")
+	errServerPrompt  = errors.New("server: the prompt is empty")
+	errServerRoute   = errors.New("server: unknown route")
+	errServerMethod  = errors.New("server: method not allowed")
+)
+
+// Server - REST API of the controller, each session keeps its own conversation
+type Server struct {
+	agent    Agent
+	mutex    sync.Mutex
+	sessions map[string]*serverSession
+	count    int
+}
+
+// serverSession - Open conversation, its prompts are sent one at a time
+type serverSession struct {
+	mutex  sync.Mutex
+	id     string
+	index  int
+	client *Headless
+}
+
+// NewServer - API server creating its sessions from the agent
+func NewServer(agent Agent) *Server {
+	return &Server{
+		agent:    agent,
+		sessions: make(map[string]*serverSession),
+	}
+}
+
+// Serve - Listen on the address with the agent of the profile
+func Serve(address string) error {
+	if address == "" {
+		address = DefaultServerAddress
+	}
+	return http.ListenAndServe(address, NewServer(AttachHeadlessProfile()))
+}
+
+// ServeHTTP - Route the request
+func (c *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(route) == 1 && route[0] == "sessions" && r.Method == http.MethodGet:
+		c.listSessions(w)
+	case len(route) == 1 && route[0] == "sessions" && r.Method == http.MethodPost:
+		c.createSession(w, r)
+	case len(route) == 1 && route[0] == "history" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, FilterSessions(ListSessions(sessionPath), r.URL.Query().Get("query")))
+	case len(route) == 1 && route[0] == "models" && r.Method == http.MethodGet:
+		c.listModels(w)
+	case len(route) == 1 && route[0] == "predict" && r.Method == http.MethodPost:
+		c.predict(w, r)
+	case len(route) == 2 && route[0] == "sessions" && r.Method == http.MethodDelete:
+		c.closeSession(w, route[1])
+	case len(route) == 3 && route[0] == "sessions" && route[2] == "prompts" && r.Method == http.MethodPost:
+		c.sendPrompt(w, r, route[1])
+	case len(route) == 3 && route[0] == "sessions" && route[2] == "export" && r.Method == http.MethodGet:
+		c.exportSession(w, r, route[1])
+	case len(route) >= 1 && (route[0] == "sessions" || route[0] == "history" || route[0] == "models" || route[0] == "predict"):
+		writeError(w, http.StatusMethodNotAllowed, errServerMethod)
+	default:
+		writeError(w, http.StatusNotFound, errServerRoute)
+	}
+}
+
+// createSession - Open a conversation with the engine, template, role and sampling parameters of the body
+func (c *Server) createSession(w http.ResponseWriter, r *http.Request) {
+	var options HeadlessOptions
+	if err := decodeBody(r, &options); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	client, err := NewHeadless(c.agent, options, io.Discard)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	c.mutex.Lock()
+	c.count++
+	session := &serverSession{
+		id:     fmt.Sprint("session-", time.Now().UnixMilli(), "-", c.count),
+		index:  c.count,
+		client: client,
+	}
+	c.sessions[session.id] = session
+	c.mutex.Unlock()
+
+	writeJSON(w, http.StatusCreated, session.describe())
+}
+
+// getSession - Open session by id
+func (c *Server) getSession(id string) (*serverSession, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	session, isOpen := c.sessions[id]
+	return session, isOpen
+}
+
+// listSessions - Open sessions from the oldest
+func (c *Server) listSessions(w http.ResponseWriter) {
+	c.mutex.Lock()
+	var sessions []*serverSession
+	for _, i := range c.sessions {
+		sessions = append(sessions, i)
+	}
+	c.mutex.Unlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].index < sessions[j].index
+	})
+
+	out := []model.ServerSession{}
+	for _, i := range sessions {
+		i.mutex.Lock()
+		out = append(out, i.describe())
+		i.mutex.Unlock()
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// closeSession - Forget the conversation, its logs are kept
+func (c *Server) closeSession(w http.ResponseWriter, id string) {
+	c.mutex.Lock()
+	_, isOpen := c.sessions[id]
+	delete(c.sessions, id)
+	c.mutex.Unlock()
+
+	if !isOpen {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %v", errServerSession, id))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendPrompt - Send the prompt in its mode, streamed prompts answer with Server-Sent Events
+func (c *Server) sendPrompt(w http.ResponseWriter, r *http.Request, id string) {
+	session, isOpen := c.getSession(id)
+	if !isOpen {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %v", errServerSession, id))
+		return
+	}
+
+	var request model.PromptRequest
+	if err := decodeBody(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(request.Prompt) == "" {
+		writeError(w, http.StatusBadRequest, errServerPrompt)
+		return
+	}
+
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	isStreaming := request.Stream || strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	session.client.getAgent().preferences.IsPromptStreaming = isStreaming
+	if !isStreaming {
+		resp, err := session.send(request)
+		if err != nil {
+			writeError(w, getServerStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	events := newEventWriter(w)
+	session.client.out = events
+	defer func() {
+		session.client.out = io.Discard
+	}()
+
+	resp, err := session.send(request)
+	if err != nil {
+		events.send("error", model.ServerError{Error: err.Error()})
+		return
+	}
+	events.send("done", resp)
+}
+
+// exportSession - Logged events, training sessions or transcript of the conversation
+func (c *Server) exportSession(w http.ResponseWriter, r *http.Request, id string) {
+	session, isOpen := c.getSession(id)
+	if !isOpen {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %v", errServerSession, id))
+		return
+	}
+
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	exported, err := session.client.Export(session.id, r.URL.Query().Get("format"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if text, isText := exported.(string); isText {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, text)
+		return
+	}
+	writeJSON(w, http.StatusOK, exported)
+}
+
+// listModels - Models available on the backend
+func (c *Server) listModels(w http.ResponseWriter) {
+	client, _ := NewHeadless(c.agent, HeadlessOptions{}, io.Discard)
+	models, err := client.ListModels()
+	if err != nil {
+		writeError(w, getServerStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, models)
+}
+
+// predict - Probabilities of the prompt being generated without opening a session
+func (c *Server) predict(w http.ResponseWriter, r *http.Request) {
+	var request model.PromptRequest
+	if err := decodeBody(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(request.Prompt) == "" {
+		writeError(w, http.StatusBadRequest, errServerPrompt)
+		return
+	}
+
+	client, _ := NewHeadless(c.agent, HeadlessOptions{}, io.Discard)
+	resp, err := client.Prediction(request.Prompt)
+	if err != nil {
+		writeError(w, getServerStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, model.PromptResponse{Mode: "predict", Predict: resp})
+}
+
+// send - Send the prompt through the request of its mode
+func (c *serverSession) send(request model.PromptRequest) (model.PromptResponse, error) {
+	resp := model.PromptResponse{Session: c.id, Mode: strings.ToLower(request.Mode)}
+
+	var err error
+	switch resp.Mode {
+	case "", "chat":
+		resp.Mode = "chat"
+		resp.Choices, _, err = c.client.Complete(request.Prompt)
+	case "edit":
+		resp.Choices, err = c.client.Revise(request.Prompt, request.Instruction)
+	case "embed":
+		resp.Embedding, err = c.client.Embedding(request.Prompt)
+	case "predict":
+		resp.Predict, err = c.client.Prediction(request.Prompt)
+	default:
+		err = fmt.Errorf("%w: %v", errServerMode, request.Mode)
+	}
+	return resp, err
+}
+
+// describe - Engine, template and turns of the session
+func (c *serverSession) describe() model.ServerSession {
+	return c.client.describe(c.id)
+}
+
+// getServerStatus - Invalid requests are client errors, the rest failed on the backend
+func getServerStatus(err error) int {
+	switch {
+	case errors.Is(err, errServerMode), errors.Is(err, errHeadlessInstruction):
+		return http.StatusBadRequest
+	}
+	return http.StatusBadGateway
+}
+
+// decodeBody - Decode the JSON body, an empty body keeps the defaults
+func decodeBody(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// writeJSON - Write the value as the JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError - Write the error as the JSON response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, model.ServerError{Error: err.Error()})
+}
+
+// eventWriter - Server-Sent Events stream, each write is sent as a token event
+type eventWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newEventWriter - Start the event stream of the response
+func newEventWriter(w http.ResponseWriter) *eventWriter {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	return &eventWriter{w: w, flusher: flusher}
+}
+
+// Write - Send the streamed text as a token event
+func (c *eventWriter) Write(p []byte) (int, error) {
+	if err := c.send("token", string(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// send - Send the value as a JSON event
+func (c *eventWriter) send(event string, v interface{}) error {
+	raw, _ := json.Marshal(v)
+	return c.write(fmt.Sprintf("event: %v\ndata: %s\n\n", event, raw))
+}
+
+// write - Write the event as is and flush it
+func (c *eventWriter) write(event string) error {
+	if _, err := io.WriteString(c.w, event); err != nil {
+		return err
+	}
+	if c.flusher != nil {
+		c.flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+// Package service section
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"caos/model"
+)
+
+// errEmptySession - Log file without events
+var errEmptySession = errors.New("session: the log file doesn't contain events")
+
+// readLogSession - Read a session stored by saveLogSession
+func readLogSession(path string) (model.HistoricalSession, error) {
+	var session model.HistoricalSession
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return session, err
+	}
+
+	if err := json.Unmarshal(raw, &session); err != nil {
+		return session, err
+	}
+
+	if len(session.Session) == 0 {
+		return session, errEmptySession
+	}
+	return session, nil
+}
+
+// LoadSession - Rebuild the pool with the conversation of a log file, including the branches stored next to it
+func (c *EventManager) LoadSession(path string) error {
+	selected, err := readLogSession(path)
+	if err != nil {
+		return err
+	}
+
+	type record struct {
+		id      string
+		summary string
+		event   model.HistoricalEvent
+	}
+
+	target := selected.Session[len(selected.Session)-1]
+	records := []record{}
+	for _, i := range selected.Session {
+		records = append(records, record{selected.ID, selected.Summary, i})
+	}
+
+	// Turns of the same conversation are stored in separated log files
+	if target.ID != "" {
+		current, _ := filepath.Abs(path)
+		files, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.json"))
+		for _, i := range files {
+			if file, _ := filepath.Abs(i); file == current {
+				continue
+			}
+
+			session, err := readLogSession(i)
+			if err != nil {
+				continue
+			}
+
+			for _, j := range session.Session {
+				if j.ID != "" {
+					records = append(records, record{session.ID, session.Summary, j})
+				}
+			}
+		}
+	}
+
+	parents := make(map[string]string)
+	for _, i := range records {
+		if i.event.ID != "" {
+			parents[i.event.ID] = i.event.ParentID
+		}
+	}
+
+	getRoot := func(id string) string {
+		visited := make(map[string]bool)
+		for {
+			parent, isListed := parents[id]
+			if !isListed || parent == "" || visited[parent] {
+				return id
+			}
+			visited[id] = true
+			id = parent
+		}
+	}
+
+	root := getRoot(target.ID)
+	sort.SliceStable(records, func(a, b int) bool {
+		return records[a].event.Timestamp < records[b].event.Timestamp
+	})
+
+	c.clearSession()
+	isLoaded := make(map[string]bool)
+	for _, i := range records {
+		if i.event.ID != "" && (isLoaded[i.event.ID] || getRoot(i.event.ID) != root) {
+			continue
+		}
+
+		if i.event.ID == "" {
+			// Sessions recorded before the turn tree follow the previous event
+			i.event.ID = fmt.Sprint(i.id, "-", len(c.pool.Turns))
+			i.event.ParentID = c.pool.CurrentTurn
+		}
+		isLoaded[i.event.ID] = true
+
+		train := model.TrainingEvent{
+			Timestamp: i.event.Timestamp,
+			Event: model.TrainingPrompt{
+				Prompt:     i.event.Event.Body.Input,
+				Completion: i.event.Event.Body.Content,
+			},
+		}
+
+		c.pool.Event = append(c.pool.Event, i.event)
+		c.pool.Session = append(c.pool.Session, model.HistoricalSession{
+			ID:      i.id,
+			Session: []model.HistoricalEvent{i.event},
+			Summary: i.summary,
+		})
+		c.pool.TrainingEvent = append(c.pool.TrainingEvent, train)
+		c.pool.TrainingSession = append(c.pool.TrainingSession, model.TrainingSession{
+			ID:      i.id,
+			Session: []model.TrainingEvent{train},
+		})
+		c.pool.Turns = append(c.pool.Turns, model.TurnEvent{
+			SessionID: i.id,
+			Event:     i.event,
+			Training:  train,
+		})
+		c.pool.CurrentTurn = i.event.ID
+	}
+
+	if target.ID != "" {
+		c.pool.CurrentTurn = target.ID
+	}
+	return nil
+}
+
+// sessionPath - Directory of the log sessions
+const sessionPath = "log"
+
+// ListSessions - Conversations stored in the log directory, latest first
+func ListSessions(path string) []model.StoredSession {
+	type record struct {
+		file    string
+		session model.HistoricalSession
+		event   model.HistoricalEvent
+	}
+
+	var records []record
+	parents := make(map[string]string)
+
+	files, _ := filepath.Glob(filepath.Join(path, "*.json"))
+	for _, i := range files {
+		session, err := readLogSession(i)
+		if err != nil {
+			continue
+		}
+
+		for _, j := range session.Session {
+			records = append(records, record{i, session, j})
+			if j.ID != "" {
+				parents[j.ID] = j.ParentID
+			}
+		}
+	}
+
+	sort.SliceStable(records, func(a, b int) bool {
+		return records[a].event.Timestamp < records[b].event.Timestamp
+	})
+
+	// Turns are grouped by the first turn of the conversation, sessions without turns are listed by file
+	getGroup := func(i record) string {
+		if i.event.ID == "" {
+			return i.file
+		}
+
+		id := i.event.ID
+		visited := make(map[string]bool)
+		for parents[id] != "" && !visited[id] {
+			if _, isListed := parents[parents[id]]; !isListed {
+				break
+			}
+			visited[id] = true
+			id = parents[id]
+		}
+		return id
+	}
+
+	var sessions []model.StoredSession
+	groups := make(map[string]int)
+	for _, i := range records {
+		group := getGroup(i)
+		index, isListed := groups[group]
+		if !isListed {
+			index = len(sessions)
+			groups[group] = index
+
+			stored := model.StoredSession{
+				Timestamp: i.event.Timestamp,
+				Model:     i.event.Event.Header.Model,
+				Template:  i.event.Event.Template.Name,
+			}
+			if i.event.Event.Body.Input != nil {
+				stored.Prompt = i.event.Event.Body.Input[0]
+			}
+			sessions = append(sessions, stored)
+		}
+
+		stored := &sessions[index]
+		stored.Path = i.file
+		stored.Turns++
+		if i.session.Name != "" {
+			stored.Name = i.session.Name
+		}
+
+		isAdded := false
+		for _, j := range stored.Files {
+			isAdded = isAdded || j == i.file
+		}
+		if !isAdded {
+			stored.Files = append(stored.Files, i.file)
+		}
+	}
+
+	sort.SliceStable(sessions, func(a, b int) bool {
+		return sessions[a].Timestamp > sessions[b].Timestamp
+	})
+	return sessions
+}
+
+// FilterSessions - Sessions containing the query in the name, prompt, model or template
+func FilterSessions(sessions []model.StoredSession, query string) []model.StoredSession {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return sessions
+	}
+
+	var filtered []model.StoredSession
+	for _, i := range sessions {
+		text := strings.ToLower(strings.Join([]string{i.Name, i.Prompt, i.Model, i.Template}, " "))
+		if strings.Contains(text, query) {
+			filtered = append(filtered, i)
+		}
+	}
+	return filtered
+}
+
+// RenameSession - Store the name in every log file of the session
+func RenameSession(stored model.StoredSession, name string) error {
+	for _, i := range stored.Files {
+		session, err := readLogSession(i)
+		if err != nil {
+			return err
+		}
+
+		session.Name = strings.TrimSpace(name)
+		raw, _ := json.MarshalIndent(session, "", "\u0009")
+		if err := os.WriteFile(i, raw, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteSession - Remove every log file of the session
+func DeleteSession(stored model.StoredSession) error {
+	for _, i := range stored.Files {
+		if err := os.Remove(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatTimestamp - Readable UTC date of a timestamp in milliseconds
+func formatTimestamp(timestamp string) string {
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return timestamp
+	}
+	return time.UnixMilli(ms).UTC().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,300 @@
+// Package service section
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"caos/model"
+	"caos/util"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// Tool loop limits
+const (
+	maxToolCalls    = 6
+	toolResultBytes = 4000
+)
+
+// toolLimitAnswer - Final answer of a tool loop stopped before the model answered
+const toolLimitAnswer = "The tool limit was reached before a final answer, try a more specific prompt."
+
+// toolPrivateDirs - Folders of the sessions, training files, indexes and exports that can't be read by the tools
+var toolPrivateDirs = []string{sessionPath, "training", "index", "arena", "export"}
+
+// Tool errors
+var (
+	errToolArgument = errors.New("tool: missing argument")
+	errToolPath     = errors.New("tool: only files inside the working directory can be read")
+	errToolPrivate  = errors.New("tool: hidden files and the caos data folders can't be read")
+	errToolReplay   = errors.New("tool: web search isn't available with the replay backend")
+	errToolUnknown  = errors.New("tool: unknown tool")
+)
+
+// Tool - Function the chat models can request during a conversation
+type Tool struct {
+	Name        string
+	Description string
+	Arguments   map[string]string
+	run         func(service Agent, arguments map[string]string) (string, error)
+}
+
+// tools - Tools declared to the chat models
+var tools = []Tool{
+	{
+		Name:        "web_search",
+		Description: "Search the web and return the result urls and their text",
+		Arguments:   map[string]string{"query": "search terms"},
+		run:         runWebSearch,
+	},
+	{
+		Name:        "read_file",
+		Description: "Read a local text file inside the working directory, hidden files and the caos data folders aren't available",
+		Arguments:   map[string]string{"path": "relative path of the file"},
+		run:         runReadFile,
+	},
+	{
+		Name:        "calculator",
+		Description: "Evaluate an arithmetic expression with + - * / % ^ and parentheses",
+		Arguments:   map[string]string{"expression": "arithmetic expression"},
+		run:         runCalculator,
+	},
+	{
+		Name:        "current_time",
+		Description: "Current date and time",
+		Arguments:   map[string]string{"timezone": "optional IANA time zone like Europe/Madrid, UTC by default"},
+		run:         runCurrentTime,
+	},
+}
+
+// GetTools - Tools declared to the chat models
+func GetTools() []Tool {
+	return tools
+}
+
+// getTool - Tool by name
+func getTool(name string) (Tool, bool) {
+	for _, i := range tools {
+		if i.Name == name {
+			return i, true
+		}
+	}
+	return Tool{}, false
+}
+
+// runWebSearch - Scrape the search results with the chain transformer
+func runWebSearch(service Agent, arguments map[string]string) (string, error) {
+	if arguments["query"] == "" {
+		return "", fmt.Errorf("%w: query", errToolArgument)
+	}
+
+	if service.preferences.ReplayPath != "" {
+		return "", errToolReplay
+	}
+
+	var chain Chain
+	chain.ExecuteChainJob(service, &model.PromptProperties{Input: []string{arguments["query"]}})
+	return fmt.Sprint(
+		"Urls: ", strings.Join(chain.Transform.Source, " "),
+		"\nContext: ", strings.Join(chain.Transform.Context, " ")), nil
+}
+
+// runReadFile - Content of a file inside the working directory
+func runReadFile(service Agent, arguments map[string]string) (string, error) {
+	if arguments["path"] == "" {
+		return "", fmt.Errorf("%w: path", errToolArgument)
+	}
+
+	path := filepath.Clean(arguments["path"])
+	if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return "", errToolPath
+	}
+
+	// Links can't point outside of the working directory either
+	dir, _ := os.Getwd()
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	absolute, _ := filepath.Abs(resolved)
+	if !strings.HasPrefix(absolute, dir+string(filepath.Separator)) {
+		return "", errToolPath
+	}
+
+	// Keys in .env and the stored conversations aren't sent to the backend
+	relative, _ := filepath.Rel(dir, absolute)
+	for _, i := range []string{path, relative} {
+		if isPrivatePath(i) {
+			return "", errToolPrivate
+		}
+	}
+
+	raw, err := os.ReadFile(resolved)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// isPrivatePath - Evaluates when the relative path is a hidden file or is inside a data folder
+func isPrivatePath(path string) bool {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for _, i := range parts {
+		if strings.HasPrefix(i, ".") && i != "." {
+			return true
+		}
+	}
+
+	for _, i := range toolPrivateDirs {
+		if strings.EqualFold(parts[0], i) {
+			return true
+		}
+	}
+	return false
+}
+
+// getToolLimitResponse - Response with an explicit final answer instead of the pending tool call
+func getToolLimitResponse(resp *gpt3.ChatCompletionResponse) *gpt3.ChatCompletionResponse {
+	limited := *resp
+	limited.Choices = []gpt3.ChatCompletionResponseChoice{
+		{
+			Message:      gpt3.ChatCompletionResponseMessage{Role: string(model.Assistant), Content: toolLimitAnswer},
+			FinishReason: "length",
+		},
+	}
+	return &limited
+}
+
+// runCalculator - Result of the arithmetic expression
+func runCalculator(service Agent, arguments map[string]string) (string, error) {
+	if arguments["expression"] == "" {
+		return "", fmt.Errorf("%w: expression", errToolArgument)
+	}
+
+	result, err := util.Evaluate(arguments["expression"])
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(result, 'f', -1, 64), nil
+}
+
+// runCurrentTime - Current date and time in the time zone
+func runCurrentTime(service Agent, arguments map[string]string) (string, error) {
+	location := time.UTC
+	if arguments["timezone"] != "" {
+		zone, err := time.LoadLocation(arguments["timezone"])
+		if err != nil {
+			return "", err
+		}
+		location = zone
+	}
+	return time.Now().In(location).Format("Monday, 02 January 2006 15:04:05 MST"), nil
+}
+
+// RunTool - Execute the tool call and keep its result or error
+func RunTool(service Agent, call model.ToolCall) model.ToolCall {
+	tool, isListed := getTool(call.Name)
+	if !isListed {
+		call.Error = fmt.Sprintf("%v: %v", errToolUnknown, call.Name)
+		return call
+	}
+
+	result, err := tool.run(service, call.Arguments)
+	if err != nil {
+		call.Error = err.Error()
+		return call
+	}
+
+	call.Result = util.TruncateText(result, toolResultBytes)
+	return call
+}
+
+// getToolMessage - System message declaring the tools and the call format
+func getToolMessage() gpt3.ChatCompletionRequestMessage {
+	var declared []string
+	for _, i := range tools {
+		var arguments []string
+		for name, description := range i.Arguments {
+			arguments = append(arguments, fmt.Sprintf("%q: <%v>", name, description))
+		}
+		sort.Strings(arguments)
+		declared = append(declared, fmt.Sprintf("- %v: %v, arguments {%v}", i.Name, i.Description, strings.Join(arguments, ", ")))
+	}
+
+	return gpt3.ChatCompletionRequestMessage{
+		Role: string(model.System),
+		Content: fmt.Sprint(
+			"You can use the following tools when they are needed to answer:\n",
+			strings.Join(declared, "\n"),
+			"\nTo use a tool reply ONLY with a JSON object like {\"tool\": \"<name>\", \"arguments\": {...}} and wait for the result.",
+			"\nWhen you have enough information reply with the final answer as plain text."),
+	}
+}
+
+// parseToolCall - Tool call requested in the model reply
+func parseToolCall(content string) (model.ToolCall, bool) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return model.ToolCall{}, false
+	}
+
+	var request struct {
+		Tool      string                 `json:"tool"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &request); err != nil || request.Tool == "" {
+		return model.ToolCall{}, false
+	}
+
+	call := model.ToolCall{
+		Name:      request.Tool,
+		Arguments: make(map[string]string),
+	}
+	for name, value := range request.Arguments {
+		call.Arguments[name] = fmt.Sprint(value)
+	}
+	return call, true
+}
+
+// getToolResultMessage - Tool result sent back to the model
+func getToolResultMessage(call model.ToolCall) gpt3.ChatCompletionRequestMessage {
+	content := fmt.Sprintf("Result of %v: %v", call.Name, call.Result)
+	if call.Error != "" {
+		content = fmt.Sprintf("Error of %v: %v", call.Name, call.Error)
+	}
+
+	return gpt3.ChatCompletionRequestMessage{
+		Role:    string(model.User),
+		Content: content,
+	}
+}
+
+// FormatToolCalls - Tool calls with their arguments and results
+func FormatToolCalls(calls []model.ToolCall) string {
+	var out []string
+	for i, j := range calls {
+		var arguments []string
+		for name, value := range j.Arguments {
+			arguments = append(arguments, fmt.Sprint(name, "=", value))
+		}
+		sort.Strings(arguments)
+
+		result := j.Result
+		if j.Error != "" {
+			result = fmt.Sprint("error: ", j.Error)
+		}
+		result = util.TruncateText(result, 200)
+
+		out = append(out, fmt.Sprintf("%v. %v(%v)\n   %v", i+1, j.Name, strings.Join(arguments, ", "), strings.ReplaceAll(result, "\n", " ")))
+	}
+	return strings.Join(out, "\n")
+}
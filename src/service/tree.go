@@ -0,0 +1,99 @@
+// Package service section
+package service
+
+import (
+	"fmt"
+
+	"caos/model"
+)
+
+// appendToTree - Add the event as a new turn following the current turn
+func (c *EventManager) appendToTree(id string, event *model.HistoricalEvent, train model.TrainingEvent) {
+	event.ID = fmt.Sprint(id, "-", len(c.pool.Turns))
+	event.ParentID = c.pool.CurrentTurn
+
+	c.pool.Turns = append(c.pool.Turns, model.TurnEvent{
+		SessionID: id,
+		Event:     *event,
+		Training:  train,
+	})
+	c.pool.CurrentTurn = event.ID
+}
+
+// getTurn - Index of the turn, -1 when it doesn't exist
+func (c *EventManager) getTurn(id string) int {
+	for i := range c.pool.Turns {
+		if c.pool.Turns[i].Event.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// getChildren - Indexes of the turns following the turn in creation order
+func (c *EventManager) getChildren(id string) []int {
+	var children []int
+	for i := range c.pool.Turns {
+		if c.pool.Turns[i].Event.ParentID == id {
+			children = append(children, i)
+		}
+	}
+	return children
+}
+
+// GetBranch - Turns from the first turn of the conversation to the current turn
+func (c *EventManager) GetBranch() []model.TurnEvent {
+	var branch []model.TurnEvent
+	for i := c.getTurn(c.pool.CurrentTurn); i >= 0; i = c.getTurn(c.pool.Turns[i].Event.ParentID) {
+		branch = append([]model.TurnEvent{c.pool.Turns[i]}, branch...)
+	}
+	return branch
+}
+
+// GetBranchTraining - Training sessions of the current branch
+func (c *EventManager) GetBranchTraining() []model.TrainingSession {
+	var sessions []model.TrainingSession
+	for _, i := range c.GetBranch() {
+		sessions = append(sessions, model.TrainingSession{
+			ID:      i.SessionID,
+			Session: []model.TrainingEvent{i.Training},
+		})
+	}
+	return sessions
+}
+
+// ForkTurn - Move to the previous turn, the next prompt starts a new branch from it
+func (c *EventManager) ForkTurn() bool {
+	index := c.getTurn(c.pool.CurrentTurn)
+	if index < 0 {
+		return false
+	}
+
+	c.pool.CurrentTurn = c.pool.Turns[index].Event.ParentID
+	return true
+}
+
+// SwitchBranch - Move to a sibling branch of the current turn and follow its latest turns
+func (c *EventManager) SwitchBranch(offset int) (int, int) {
+	index := c.getTurn(c.pool.CurrentTurn)
+	if index < 0 {
+		return 0, 0
+	}
+
+	siblings := c.getChildren(c.pool.Turns[index].Event.ParentID)
+	var position int
+	for i := range siblings {
+		if siblings[i] == index {
+			position = i
+		}
+	}
+
+	position = ((position+offset)%len(siblings) + len(siblings)) % len(siblings)
+	c.pool.CurrentTurn = c.pool.Turns[siblings[position]].Event.ID
+
+	for children := c.getChildren(c.pool.CurrentTurn); children != nil; children = c.getChildren(c.pool.CurrentTurn) {
+		c.pool.CurrentTurn = c.pool.Turns[children[len(children)-1]].Event.ID
+	}
+
+	return position + 1, len(siblings)
+}
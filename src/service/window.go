@@ -0,0 +1,209 @@
+// Package service section
+package service
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"caos/model"
+	"caos/service/parameters"
+	"caos/util"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// Token overhead of the chat format for each message and for the reply
+const (
+	messageOverhead = 4
+	replyOverhead   = 3
+	passageWords    = 120
+)
+
+// errContextWindow - The prompt and the system messages leave no room for the completion
+var errContextWindow = errors.New("window: the request doesn't fit in the context window")
+
+// ContextWindow - Token budget manager fitting chat requests in the model context limit
+type ContextWindow struct {
+	limit   int
+	reserve int
+	count   func(string) int
+}
+
+// NewContextWindow - Create a context window for the model, limit overrides the known model size when defined
+func NewContextWindow(engine string, limit int) *ContextWindow {
+	if limit <= 0 {
+		limit = getContextLimit(engine)
+	}
+
+	window := &ContextWindow{
+		limit:   limit,
+		reserve: limit / 4,
+		count: func(text string) int {
+			return util.CountTokens(text, engine)
+		},
+	}
+
+	// Tokenizer encodings are downloaded, keep the tests offline
+	if isTestingEnvironment() {
+		window.count = util.EstimateTokens
+	}
+	return window
+}
+
+// getContextLimit - Context window size of the longest matching model prefix
+func getContextLimit(engine string) int {
+	limit := parameters.DefaultContextLimit
+	size := 0
+	for prefix, tokens := range parameters.ContextLimits {
+		if strings.HasPrefix(engine, prefix) && len(prefix) > size {
+			limit, size = tokens, len(prefix)
+		}
+	}
+	return limit
+}
+
+// countMessage - Tokens used by a message in the chat format
+func (c *ContextWindow) countMessage(message gpt3.ChatCompletionRequestMessage) int {
+	return messageOverhead + c.count(message.Role) + c.count(message.Content)
+}
+
+// Fit - Drop the oldest turns and then the lowest ranked context until the request leaves room for the completion, fails when nothing is left to drop
+func (c *ContextWindow) Fit(history []gpt3.ChatCompletionRequestMessage, query string, context []string, compose func([]string) gpt3.ChatCompletionRequestMessage) ([]gpt3.ChatCompletionRequestMessage, model.ContextBudget, error) {
+	budget := model.ContextBudget{
+		Limit: c.limit,
+	}
+
+	// System and summary messages are always kept
+	var system []gpt3.ChatCompletionRequestMessage
+	turns := history
+	for len(turns) > 0 && turns[0].Role == string(model.System) {
+		system, turns = append(system, turns[0]), turns[1:]
+	}
+
+	passages := splitPassages(context)
+	ranking := rankPassages(query, passages)
+	isDropped := make([]bool, len(passages))
+
+	selected := func() []string {
+		var out []string
+		for i := range passages {
+			if !isDropped[i] {
+				out = append(out, passages[i])
+			}
+		}
+		return out
+	}
+
+	// Each message and passage is counted once, the drops subtract their tokens
+	for i := range system {
+		budget.System += c.countMessage(system[i])
+	}
+	counts := make([]int, len(turns))
+	for i := range turns {
+		counts[i] = c.countMessage(turns[i])
+		budget.History += counts[i]
+	}
+	sizes := make([]int, len(passages))
+	for i := range passages {
+		sizes[i] = c.count(passages[i]) + 1
+	}
+	budget.Prompt = c.countMessage(compose(nil))
+	budget.Context = c.countMessage(compose(passages)) - budget.Prompt
+	isExact := true
+
+	for {
+		used := budget.System + budget.History + budget.Prompt + budget.Context + replyOverhead
+		if used+c.reserve <= c.limit {
+			if isExact {
+				break
+			}
+			// The kept passages are composed once to count their exact tokens
+			budget.Context = c.countMessage(compose(selected())) - budget.Prompt
+			isExact = true
+			continue
+		}
+
+		if len(turns) > 0 {
+			// Drop the oldest exchange, user prompt and assistant completion
+			drop := 2
+			if len(turns) < drop {
+				drop = len(turns)
+			}
+			for i := 0; i < drop; i++ {
+				budget.History -= counts[i]
+			}
+			turns, counts = turns[drop:], counts[drop:]
+			budget.DroppedTurns++
+			continue
+		}
+
+		if budget.DroppedContext < len(ranking) {
+			isDropped[ranking[budget.DroppedContext]] = true
+			budget.Context -= sizes[ranking[budget.DroppedContext]]
+			budget.DroppedContext++
+			isExact = false
+			continue
+		}
+		if !isExact {
+			budget.Context = c.countMessage(compose(selected())) - budget.Prompt
+		}
+		break
+	}
+
+	budget.Completion = c.limit - (budget.System + budget.History + budget.Prompt + budget.Context + replyOverhead)
+	if budget.Completion < 1 {
+		budget.Completion = 0
+		return nil, budget, errContextWindow
+	}
+
+	var messages []gpt3.ChatCompletionRequestMessage
+	messages = append(messages, system...)
+	messages = append(messages, turns...)
+	messages = append(messages, compose(selected()))
+	return messages, budget, nil
+}
+
+// splitPassages - Split the scraped context in passages with a bounded amount of words
+func splitPassages(context []string) []string {
+	var passages []string
+	for _, i := range context {
+		words := strings.Fields(i)
+		for j := 0; j < len(words); j += passageWords {
+			end := j + passageWords
+			if end > len(words) {
+				end = len(words)
+			}
+			passages = append(passages, strings.Join(words[j:end], " "))
+		}
+	}
+	return passages
+}
+
+// rankPassages - Passage indexes from the lowest to the highest amount of query terms
+func rankPassages(query string, passages []string) []int {
+	terms := make(map[string]bool)
+	for _, i := range strings.Fields(strings.ToLower(query)) {
+		terms[i] = true
+	}
+
+	scores := make([]int, len(passages))
+	ranking := make([]int, len(passages))
+	for i := range passages {
+		ranking[i] = i
+		for _, j := range strings.Fields(strings.ToLower(passages[i])) {
+			if terms[j] {
+				scores[i]++
+			}
+		}
+	}
+
+	// Ties drop the latest passages first
+	sort.SliceStable(ranking, func(a, b int) bool {
+		if scores[ranking[a]] != scores[ranking[b]] {
+			return scores[ranking[a]] < scores[ranking[b]]
+		}
+		return ranking[a] > ranking[b]
+	})
+	return ranking
+}
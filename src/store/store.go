@@ -0,0 +1,317 @@
+// Package store section
+package store
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"caos/model"
+	"caos/util"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// Metadata keys used by the filters
+const (
+	SourceKey   = "source"
+	TemplateKey = "template"
+	ModelKey    = "model"
+	KindKey     = "kind"
+)
+
+// Store errors
+var (
+	ErrEmptyID     = errors.New("store: the document doesn't have an ID")
+	ErrEmptyVector = errors.New("store: the document doesn't have a vector")
+	ErrDuplicateID = errors.New("store: a document with the same ID already exists")
+)
+
+// Filter - Metadata values and date range the documents must match, empty fields match everything
+type Filter struct {
+	Metadata map[string]string
+	After    time.Time
+	Before   time.Time
+}
+
+// Match - Check the document against the filter
+func (c Filter) Match(document model.VectorDocument) bool {
+	for key, value := range c.Metadata {
+		if document.Metadata[key] != value {
+			return false
+		}
+	}
+
+	if !c.After.IsZero() && document.Date.Before(c.After) {
+		return false
+	}
+	if !c.Before.IsZero() && !document.Date.Before(c.Before) {
+		return false
+	}
+	return true
+}
+
+// VectorStore - Documents and embedding vectors persisted in a single file
+type VectorStore struct {
+	path      string
+	mutex     sync.RWMutex
+	documents []model.VectorDocument
+	positions map[string]int
+}
+
+// file - Stored format of the vector store
+type file struct {
+	Documents []model.VectorDocument `json:"documents"`
+}
+
+// Open - Load the store persisted in path, an empty store is created when it doesn't exist
+func Open(path string) (*VectorStore, error) {
+	store := &VectorStore{
+		path:      path,
+		positions: make(map[string]int),
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stored file
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return nil, err
+	}
+
+	for _, i := range stored.Documents {
+		store.positions[i.ID] = len(store.documents)
+		store.documents = append(store.documents, i)
+	}
+	return store, nil
+}
+
+// Save - Persist the documents, the file is replaced once it's completely written
+func (c *VectorStore) Save() error {
+	c.mutex.RLock()
+	raw, err := json.Marshal(file{Documents: c.documents})
+	c.mutex.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// validate - Check the document can be stored
+func validate(document model.VectorDocument) error {
+	if document.ID == "" {
+		return ErrEmptyID
+	}
+	if len(document.Vector) == 0 {
+		return ErrEmptyVector
+	}
+	return nil
+}
+
+// Add - Store new documents, nothing is stored when one of them is invalid or already exists
+func (c *VectorStore) Add(documents ...model.VectorDocument) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	isAdded := make(map[string]bool)
+	for _, i := range documents {
+		if err := validate(i); err != nil {
+			return err
+		}
+
+		if _, isListed := c.positions[i.ID]; isListed || isAdded[i.ID] {
+			return ErrDuplicateID
+		}
+		isAdded[i.ID] = true
+	}
+
+	for _, i := range documents {
+		c.positions[i.ID] = len(c.documents)
+		c.documents = append(c.documents, i)
+	}
+	return nil
+}
+
+// Upsert - Store the documents replacing the ones with the same ID
+func (c *VectorStore) Upsert(documents ...model.VectorDocument) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, i := range documents {
+		if err := validate(i); err != nil {
+			return err
+		}
+	}
+
+	for _, i := range documents {
+		if position, isListed := c.positions[i.ID]; isListed {
+			c.documents[position] = i
+			continue
+		}
+
+		c.positions[i.ID] = len(c.documents)
+		c.documents = append(c.documents, i)
+	}
+	return nil
+}
+
+// Delete - Remove the documents by ID, returns the amount of removed documents
+func (c *VectorStore) Delete(ids ...string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	isRemoved := make(map[string]bool)
+	for _, i := range ids {
+		if _, isListed := c.positions[i]; isListed {
+			isRemoved[i] = true
+		}
+	}
+	c.remove(func(document model.VectorDocument) bool {
+		return isRemoved[document.ID]
+	})
+	return len(isRemoved)
+}
+
+// DeleteMatching - Remove the documents matching the filter, returns the amount of removed documents
+func (c *VectorStore) DeleteMatching(filter Filter) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.remove(filter.Match)
+}
+
+// remove - Drop the documents selected by the function and rebuild the positions
+func (c *VectorStore) remove(isRemoved func(model.VectorDocument) bool) int {
+	var documents []model.VectorDocument
+	for _, i := range c.documents {
+		if !isRemoved(i) {
+			documents = append(documents, i)
+		}
+	}
+
+	removed := len(c.documents) - len(documents)
+	c.documents = documents
+	c.positions = make(map[string]int)
+	for i := range c.documents {
+		c.positions[c.documents[i].ID] = i
+	}
+	return removed
+}
+
+// Get - Document by ID
+func (c *VectorStore) Get(id string) (model.VectorDocument, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	position, isListed := c.positions[id]
+	if !isListed {
+		return model.VectorDocument{}, false
+	}
+	return c.documents[position], true
+}
+
+// List - Documents matching the filter in insertion order
+func (c *VectorStore) List(filter Filter) []model.VectorDocument {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var documents []model.VectorDocument
+	for _, i := range c.documents {
+		if filter.Match(i) {
+			documents = append(documents, i)
+		}
+	}
+	return documents
+}
+
+// Len - Amount of stored documents
+func (c *VectorStore) Len() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return len(c.documents)
+}
+
+// Search - Top k documents matching the filter ranked by cosine similarity, all of them when k is 0
+func (c *VectorStore) Search(vector []float64, k int, filter Filter) []model.VectorResult {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var results []model.VectorResult
+	for _, i := range c.documents {
+		if len(i.Vector) != len(vector) || !filter.Match(i) {
+			continue
+		}
+
+		results = append(results, model.VectorResult{
+			Document: i,
+			Score:    util.CosineSimilarity(vector, i.Vector),
+		})
+	}
+
+	sort.SliceStable(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].Document.ID < results[b].Document.ID
+	})
+
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// NewID - Stable document ID for a text embedded with a model
+func NewID(engine string, text string) string {
+	hash := sha1.Sum([]byte(engine + "\x00" + text))
+	return hex.EncodeToString(hash[:])
+}
+
+// FromEmbeddings - Documents of an embedding response, each vector keeps its input text and a copy of the metadata
+func FromEmbeddings(resp *gpt3.EmbeddingsResponse, input []string, engine string, metadata map[string]string) []model.VectorDocument {
+	var documents []model.VectorDocument
+	if resp == nil {
+		return documents
+	}
+
+	for _, i := range resp.Data {
+		if i.Index < 0 || i.Index >= len(input) {
+			continue
+		}
+
+		values := map[string]string{ModelKey: engine}
+		for key, value := range metadata {
+			values[key] = value
+		}
+
+		documents = append(documents, model.VectorDocument{
+			ID:       NewID(engine, input[i.Index]),
+			Text:     input[i.Index],
+			Vector:   i.Embedding,
+			Metadata: values,
+			Date:     time.Now().UTC(),
+		})
+	}
+	return documents
+}
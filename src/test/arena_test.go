@@ -0,0 +1,75 @@
+// Test section - Use case
+package caos
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"caos/model"
+	"caos/service"
+)
+
+func TestArenaMatch(t *testing.T) {
+	t.Run("ArenaMatch", func(t *testing.T) {
+		provider := &scriptedProvider{replies: []string{"first answer", "second answer"}}
+
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+		agent.EngineProperties = *engineProperties
+		agent.PromptProperties = *promptProperties
+
+		match, err := service.NewArenaMatch(agent, "Who won the 2022 world cup?", []string{"gpt-3.5-turbo", "gpt-4"})
+		_, same := service.NewArenaMatch(agent, "Who won the 2022 world cup?", []string{"gpt-4", "gpt-4"})
+
+		last := func(i int) string {
+			return provider.requests[i].Messages[len(provider.requests[i].Messages)-1].Content
+		}
+		isSent := len(provider.requests) == 2 &&
+			provider.requests[0].Model == match.Models[0] && provider.requests[1].Model == match.Models[1] &&
+			match.Answers[0] == "first answer" && match.Answers[1] == "second answer" &&
+			last(0) == last(1) && strings.Contains(last(0), "Who won the 2022 world cup?")
+
+		models := service.GetArenaModels([]string{"gpt-4", "text-embedding-ada-002", "text-davinci-edit-001", "zero-gpt", "text-davinci-003"})
+
+		if err != nil || same == nil || !isSent || len(models) != 2 {
+			t.Errorf("Received:%v %v\nExpected:%v\n", match, err, "the same prompt answered by both engines")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestArenaVote(t *testing.T) {
+	t.Run("ArenaVote", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "arena", "leaderboard.json")
+		arena, _ := service.OpenArena(path)
+
+		first := model.ArenaMatch{Template: "Linux Terminal", Models: []string{"gpt-4", "gpt-3.5-turbo"}, Answers: []string{"a", "b"}}
+		arena.Vote(&first, model.VoteA)
+		revoted := arena.Vote(&first, model.VoteB)
+		arena.Save()
+
+		reopened, err := service.OpenArena(path)
+		second := model.ArenaMatch{Template: "Linux Terminal", Models: []string{"gpt-3.5-turbo", "gpt-4"}, Answers: []string{"a", "b"}}
+		unknown := reopened.Vote(&second, "both")
+		reopened.Vote(&second, model.VoteTie)
+
+		board := reopened.Leaderboard("Linux Terminal")
+		expected := 1016 + 32*(0.5-1/(1+math.Pow(10, -32.0/400)))
+		isRated := len(board) == 2 && board[0].Model == "gpt-4" && board[0].Wins == 1 && board[0].Ties == 1 &&
+			math.Abs(board[0].Rating-expected) < 1e-9 && math.Abs(board[0].Rating+board[1].Rating-2000) < 1e-9 &&
+			board[1].Losses == 1 && len(reopened.Leaderboard("Other")) == 0
+
+		if err != nil || revoted == nil || unknown == nil || !isRated {
+			t.Errorf("Received:%v\nExpected:%v\n", board, expected)
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
@@ -0,0 +1,146 @@
+// Test section - Use case
+package caos
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"caos/service"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// echoProvider - Backend answering with the engine and temperature, prompts with fail are rejected
+type echoProvider struct {
+	fakeProvider
+	isFailing bool
+}
+
+func (c *echoProvider) ChatCompletion(ctx gocontext.Context, request gpt3.ChatCompletionRequest) (*gpt3.ChatCompletionResponse, error) {
+	if c.isFailing && strings.Contains(request.Messages[len(request.Messages)-1].Content, "fail") {
+		return nil, errors.New("backend unavailable")
+	}
+
+	return &gpt3.ChatCompletionResponse{
+		ID:    fmt.Sprint("chat-", request.Model),
+		Usage: gpt3.ChatCompletionsResponseUsage{PromptTokens: 20, CompletionTokens: 10, TotalTokens: 30},
+		Choices: []gpt3.ChatCompletionResponseChoice{
+			{Message: gpt3.ChatCompletionResponseMessage{Content: fmt.Sprintf("%v answer at %v", request.Model, request.Temperature)}},
+		},
+	}, nil
+}
+
+func (c *echoProvider) Completion(ctx gocontext.Context, engine string, request gpt3.CompletionRequest) (*gpt3.CompletionResponse, error) {
+	if c.isFailing && strings.Contains(strings.Join(request.Prompt, ""), "fail") {
+		return nil, errors.New("backend unavailable")
+	}
+
+	return &gpt3.CompletionResponse{
+		ID:      fmt.Sprint("completion-", engine),
+		Usage:   gpt3.CompletionResponseUsage{PromptTokens: 5, CompletionTokens: 5, TotalTokens: 10},
+		Choices: []gpt3.CompletionResponseChoice{{Text: fmt.Sprint(engine, " answer")}},
+	}, nil
+}
+
+func TestReadBatch(t *testing.T) {
+	t.Run("ReadBatch", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "prompts.jsonl")
+		os.WriteFile(path, []byte("{\"prompt\": \"What is Go?\", \"engine\": \"gpt-4\", \"temperature\": 0.3}\n\n{\"prompt\": \"\"}\n{\"prompt\": \"Explain channels\", \"template\": \"Linux Terminal\"}\n"), 0644)
+
+		rows, err := service.ReadBatch(path)
+		_, unsupported := service.ReadBatch(filepath.Join(t.TempDir(), "prompts.txt"))
+
+		isRead := len(rows) == 2 && rows[0].Row == 1 && rows[0].Engine == "gpt-4" && *rows[0].Temperature == 0.3 &&
+			rows[1].Row == 4 && rows[1].Template == "Linux Terminal" && rows[1].Temperature == nil
+
+		if err != nil || unsupported == nil || !isRead {
+			t.Errorf("Received:%v %v\nExpected:%v\n", rows, err, "two rows with their options")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestRunBatch(t *testing.T) {
+	t.Run("RunBatch", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "prompts.csv")
+		os.WriteFile(path, []byte(strings.Join([]string{
+			"engine,prompt,temperature,template",
+			"gpt-3.5-turbo,What is Go?,0.2,",
+			"text-davinci-003,\"Explain channels, briefly\",,",
+			",please fail,,",
+			"gpt-3.5-turbo,Unknown template,,Not a template",
+		}, "\n")), 0644)
+
+		provider := &echoProvider{isFailing: true}
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+
+		rows, _ := service.ReadBatch(path)
+		out := service.GetBatchOutput(path)
+		runner := &service.BatchRunner{Workers: 2}
+
+		first, err := runner.Run(agent, rows, out)
+		results, _ := service.ReadBatchResults(out)
+		isRun := first == 4 && len(results) == 4 &&
+			results[1].ID == "chat-gpt-3.5-turbo" && results[1].Completion[0] == "gpt-3.5-turbo answer at 0.2" && results[1].Usage.TotalTokens == 30 &&
+			results[2].ID == "completion-text-davinci-003" && results[2].Completion[0] == "text-davinci-003 answer" && results[2].Usage.TotalTokens == 10 &&
+			results[3].Error == "backend unavailable" && strings.Contains(results[4].Error, "unknown template")
+
+		// Only the failed rows are sent again
+		provider.isFailing = false
+		second, _ := runner.Run(agent, rows, out)
+		results, _ = service.ReadBatchResults(out)
+		isResumed := second == 2 && results[3].Error == "" && results[3].Completion != nil && results[4].Error != ""
+
+		if err != nil || !isRun || !isResumed || out != filepath.Join(dir, "prompts.results.jsonl") {
+			t.Errorf("Received:%v\nExpected:%v\n", results, "four results and the failed row resumed")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestRunBatchConcurrent(t *testing.T) {
+	t.Run("RunBatchConcurrent", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "prompts.csv")
+		lines := []string{"engine,prompt,temperature,template"}
+		for i := 0; i < 6; i++ {
+			lines = append(lines, fmt.Sprint("gpt-3.5-turbo,Question ", i, ",,"))
+		}
+		os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+
+		agent := controller.AttachProfile()
+		agent.SetProvider(&echoProvider{})
+
+		// The chat rows scrape their context at the same time
+		rows, _ := service.ReadBatch(path)
+		out := service.GetBatchOutput(path)
+		sent, err := (&service.BatchRunner{Workers: 4}).Run(agent, rows, out)
+		results, _ := service.ReadBatchResults(out)
+
+		isAnswered := len(results) == len(rows)
+		for _, i := range results {
+			isAnswered = isAnswered && i.Error == "" && len(i.Completion) == 1
+		}
+
+		if err != nil || sent != len(rows) || !isAnswered {
+			t.Errorf("Received:%v %v\nExpected:%v\n", results, err, "every chat row answered")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
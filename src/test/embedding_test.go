@@ -0,0 +1,41 @@
+// Test section - Use case
+package caos
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"caos/service"
+	"caos/test/mock"
+)
+
+func TestSimilarSessions(t *testing.T) {
+	t.Run("SimilarSessions", func(t *testing.T) {
+		logs, _ := newIndexDir(t)
+		path := filepath.Join(t.TempDir(), "vectors.json")
+
+		index, _ := service.OpenEmbeddingIndex(path)
+		updated, err := index.Update(localAgent, "text-embedding-ada-002", logs)
+		saveErr := index.Save()
+
+		stored, _ := service.OpenEmbeddingIndex(path)
+		cached, _ := stored.Update(localAgent, "text-embedding-ada-002", logs)
+		results := stored.Nearest("text-embedding-ada-002", mock.Embed("How do I bake bread with yeast?"), 2)
+		ranked := len(results) == 2 && results[0].Record.SessionID == "cooking" &&
+			results[0].Record.Completion == "Knead the dough and bake it" && results[0].Score > results[1].Score
+
+		os.Remove(filepath.Join(logs, "log-2.json"))
+		removed, _ := stored.Update(localAgent, "text-embedding-ada-002", logs)
+		records := stored.Records("text-embedding-ada-002")
+		dropped := removed == 1 && len(records) == 1 && records[0].SessionID == "kubernetes"
+
+		if updated != 2 || err != nil || saveErr != nil || cached != 0 || !ranked || !dropped {
+			t.Errorf("Received:%v %v %v\nExpected:%v\n", updated, err, results, "cooking session first")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
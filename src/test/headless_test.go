@@ -0,0 +1,221 @@
+// Test section - Use case
+package caos
+
+import (
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"caos/model"
+	"caos/service"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// streamProvider - Backend streaming the answer in two deltas
+type streamProvider struct {
+	echoProvider
+}
+
+func (c *streamProvider) ChatCompletionStream(ctx gocontext.Context, request gpt3.ChatCompletionRequest, onData func(*gpt3.ChatCompletionStreamResponse)) error {
+	for _, i := range []string{"Hello", " world"} {
+		onData(&gpt3.ChatCompletionStreamResponse{
+			ID:      "chat-stream",
+			Choices: []gpt3.ChatCompletionStreamResponseChoice{{Delta: gpt3.ChatCompletionResponseMessage{Content: i}}},
+		})
+	}
+	return nil
+}
+
+func (c *streamProvider) CompletionStream(ctx gocontext.Context, engine string, request gpt3.CompletionRequest, onData func(*gpt3.CompletionResponse)) error {
+	if c.isFailing {
+		return errors.New("stream interrupted")
+	}
+	onData(&gpt3.CompletionResponse{ID: "completion-stream", Choices: []gpt3.CompletionResponseChoice{{Text: "Hello world"}}})
+	return nil
+}
+
+func TestHeadlessAsk(t *testing.T) {
+	t.Run("HeadlessAsk", func(t *testing.T) {
+		provider := &streamProvider{}
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+
+		var streamed bytes.Buffer
+		client, err := service.NewHeadless(agent, service.HeadlessOptions{Engine: "gpt-3.5-turbo", IsStreaming: true}, &streamed)
+		if err == nil {
+			err = client.Ask("Say hello")
+		}
+
+		var answered bytes.Buffer
+		temperature := float32(0.2)
+		texted, _ := service.NewHeadless(agent, service.HeadlessOptions{Engine: "text-davinci-003", Temperature: &temperature}, &answered)
+		texted.Ask("What is Go?")
+
+		provider.isFailing = true
+		failed := texted.Ask("please fail")
+		_, unknown := service.NewHeadless(agent, service.HeadlessOptions{Role: "narrator"}, &answered)
+
+		if err != nil || streamed.String() != "Hello world\n" || answered.String() != "text-davinci-003 answer\n" ||
+			failed == nil || unknown == nil {
+			t.Errorf("Received:%q %q %v\nExpected:%v\n", streamed.String(), answered.String(), err, "the streamed and the completed answers")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestHeadlessChat(t *testing.T) {
+	t.Run("HeadlessChat", func(t *testing.T) {
+		provider := &scriptedProvider{replies: []string{"Go is a language.", "It has goroutines."}}
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+
+		var out bytes.Buffer
+		client, _ := service.NewHeadless(agent, service.HeadlessOptions{Engine: "gpt-3.5-turbo"}, &out)
+		err := client.Chat(strings.NewReader("What is Go?\n\nWhat does it have?\n"))
+
+		// The second turn carries the first one
+		isContinued := len(provider.requests) == 2 && len(provider.requests[1].Messages) > len(provider.requests[0].Messages)
+
+		if err != nil || out.String() != "Go is a language.\nIt has goroutines.\n" || !isContinued {
+			t.Errorf("Received:%q %v\nExpected:%v\n", out.String(), err, "two turns of the same conversation")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestHeadlessExport(t *testing.T) {
+	t.Run("HeadlessExport", func(t *testing.T) {
+		agent := controller.AttachProfile()
+		agent.SetProvider(&echoProvider{})
+
+		var out bytes.Buffer
+		client, _ := service.NewHeadless(agent, service.HeadlessOptions{}, &out)
+		_, eerr := client.Embedding("x")
+		err := client.Chat(strings.NewReader("hi\nhi again\n"))
+
+		// The engine of the embedding doesn't stay for the next requests
+		exported, _ := client.Export("log", "log")
+		events := exported.(model.HistoricalSession).Session
+		training, _ := client.Export("log", "training")
+		branch := training.([]model.TrainingSession)
+
+		var turns []model.HistoricalEvent
+		for _, i := range events {
+			if len(i.Event.Body.Input) > 0 && strings.HasPrefix(i.Event.Body.Input[0], "hi") {
+				turns = append(turns, i)
+			}
+		}
+
+		isLogged := len(turns) == 2 && turns[1].ParentID != "" && turns[1].ParentID == turns[0].ID && len(branch) >= 2
+		if eerr != nil || err != nil || out.String() != "text-davinci-003 answer\ntext-davinci-003 answer\n" || !isLogged {
+			t.Errorf("Received:%q %v %v %v\nExpected:%v\n", out.String(), events, eerr, err, "two turns of the profile engine in the export")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestHeadlessRequests(t *testing.T) {
+	t.Run("HeadlessRequests", func(t *testing.T) {
+		agent := controller.AttachProfile()
+		agent.SetProvider(&fakeProvider{})
+
+		var out bytes.Buffer
+		client, _ := service.NewHeadless(agent, service.HeadlessOptions{}, &out)
+		errs := []error{
+			client.Edit("fix teh typo", "Fix the spelling"),
+			client.Embed("similar text"),
+			client.Models(),
+		}
+		missing := client.Edit("fix teh typo", "")
+
+		if errs[0] != nil || errs[1] != nil || errs[2] != nil || missing == nil ||
+			out.String() != "fix teh typo\n[0.1,0.2]\nfake-model\n" {
+			t.Errorf("Received:%q %v\nExpected:%v\n", out.String(), errs, "the edit, the vector and the models")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestHeadlessStreamFailure(t *testing.T) {
+	t.Run("HeadlessStreamFailure", func(t *testing.T) {
+		provider := &streamProvider{}
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+
+		var out bytes.Buffer
+		client, _ := service.NewHeadless(agent, service.HeadlessOptions{Engine: "text-davinci-003", IsStreaming: true}, &out)
+		streamed := client.Ask("Say hello")
+
+		// A stream failing before the first token doesn't leave any choice
+		provider.isFailing = true
+		failed := client.Ask("Say hello")
+
+		if streamed != nil || failed == nil || !strings.HasPrefix(out.String(), "Hello world") {
+			t.Errorf("Received:%q %v %v\nExpected:%v\n", out.String(), streamed, failed, "the stream error returned to the caller")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestHeadlessResume(t *testing.T) {
+	t.Run("HeadlessResume", func(t *testing.T) {
+		dir := newSessionDir(t)
+		// The second turn was logged after summarizing the first one
+		path := filepath.Join(dir, "log-2.json")
+		var session model.HistoricalSession
+		raw, _ := os.ReadFile(path)
+		json.Unmarshal(raw, &session)
+		session.Summary = "The user asked what caos is"
+		raw, _ = json.Marshal(session)
+		os.WriteFile(path, raw, 0644)
+
+		provider := &scriptedProvider{replies: []string{"GPT models"}}
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+
+		var out bytes.Buffer
+		client, err := service.NewHeadless(agent, service.HeadlessOptions{Resume: path}, &out)
+		if err == nil {
+			err = client.Ask("Which models?")
+		}
+		_, missing := service.NewHeadless(agent, service.HeadlessOptions{Resume: filepath.Join(dir, "missing.json")}, &out)
+
+		var sent []string
+		if len(provider.requests) == 1 {
+			for _, i := range provider.requests[0].Messages {
+				sent = append(sent, i.Content)
+			}
+		}
+		joined := strings.Join(sent, "\n")
+
+		if err != nil || missing == nil || len(provider.requests) != 1 || provider.requests[0].Model != "gpt-3.5-turbo-16k" ||
+			!strings.Contains(joined, "The user asked what caos is") || !strings.Contains(joined, "Which services?") {
+			t.Errorf("Received:%q %v\nExpected:%v\n", sent, err, "the resumed turns and summary")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
@@ -0,0 +1,117 @@
+// Test section - Use case
+package caos
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"caos/model"
+	"caos/service"
+)
+
+// newIndexDir - Temporal log and training directories with stored sessions
+func newIndexDir(t *testing.T) (string, string) {
+	logs := filepath.Join(t.TempDir(), "log")
+	training := filepath.Join(t.TempDir(), "training")
+	os.MkdirAll(logs, 0755)
+	os.MkdirAll(training, 0755)
+
+	write := func(name string, id string, input string, content string, context string, source string) {
+		session := model.HistoricalSession{
+			ID: id,
+			Session: []model.HistoricalEvent{
+				{
+					Timestamp: "1689910077911",
+					Event: model.HistoricalPrompt{
+						Body: model.PromptProperties{
+							Input:   []string{input},
+							Content: []string{content},
+						},
+						Template: model.TemplateProperties{
+							PromptValidated: model.ChainPrompt{
+								Context: []string{context},
+								Source:  []string{source},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		raw, _ := json.MarshalIndent(session, "", "\u0009")
+		os.WriteFile(filepath.Join(logs, name), raw, 0644)
+	}
+
+	write("log-1.json", "kubernetes", "How do I scale a deployment?", "Use kubectl scale on the deployment", "kubernetes deployment replicas", "https://kubernetes.io/docs")
+	write("log-2.json", "cooking", "How do I bake bread?", "Knead the dough and bake it", "bread flour water yeast", "https://example.com/bread")
+
+	raw, _ := json.Marshal([]model.TrainingSession{
+		{
+			ID: "training",
+			Session: []model.TrainingEvent{
+				{Event: model.TrainingPrompt{Prompt: []string{"Which deployment strategy?"}, Completion: []string{"Rolling updates"}}},
+			},
+		},
+	})
+	os.WriteFile(filepath.Join(training, "training-1.json"), raw, 0644)
+	return logs, training
+}
+
+func TestSearchIndex(t *testing.T) {
+	t.Run("SearchIndex", func(t *testing.T) {
+		logs, training := newIndexDir(t)
+		path := filepath.Join(t.TempDir(), "search.json")
+
+		index := service.OpenSearchIndex(path)
+		updated := index.Update(logs, training)
+		saveErr := index.Save()
+
+		results := service.OpenSearchIndex(path).Search("kubernetes deployment", 5)
+		ranked := len(results) == 2 && results[0].Document.SessionID == "kubernetes" &&
+			results[1].Document.Kind == "training" && strings.Contains(results[0].Snippet, "deployment")
+
+		sourced := len(index.Search("kubernetes.io", 5)) == 1
+
+		os.Remove(filepath.Join(logs, "log-2.json"))
+		removed := index.Update(logs, training) == 1 && len(index.Search("bread", 5)) == 0
+
+		if updated != 3 || saveErr != nil || !ranked || !sourced || !removed {
+			t.Errorf("Received:%v %v\nExpected:%v\n", updated, results, "ranked kubernetes sessions")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestHeadlessIndex(t *testing.T) {
+	t.Run("HeadlessIndex", func(t *testing.T) {
+		agent := controller.AttachProfile()
+		agent.SetProvider(&scriptedProvider{replies: []string{"The quokka lives on Rottnest island"}})
+
+		var out bytes.Buffer
+		client, err := service.NewHeadless(agent, service.HeadlessOptions{Engine: "gpt-3.5-turbo"}, &out)
+		if err == nil {
+			err = client.Ask("Where does the quokka live?")
+		}
+
+		// The headless sessions are indexed and the index is stored on exit instead of on each session
+		path := filepath.Join("index", "search.json")
+		pending := len(service.OpenSearchIndex(path).Search("quokka", 5))
+		saveErr := service.SaveSessionIndex()
+		stored := service.OpenSearchIndex(path).Search("quokka", 5)
+
+		if err != nil || saveErr != nil || pending != 0 || len(stored) != 1 || stored[0].Document.Kind != "log" {
+			t.Errorf("Received:%v %v %v %v\nExpected:%v\n", pending, stored, err, saveErr, "the headless session stored in the index")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
@@ -0,0 +1,86 @@
+// Test section - Use case
+package caos
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"caos/model"
+	"caos/service"
+)
+
+// newKnowledgeDir - Temporal directory with local documents
+func newKnowledgeDir(t *testing.T) string {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "guides"), 0755)
+
+	os.WriteFile(filepath.Join(dir, "vault.md"), []byte("# Vault\nRotate the vault secrets every month with the rotation script."), 0644)
+	os.WriteFile(filepath.Join(dir, "guides", "deploy.html"), []byte("<html><script>var rotate = 1;</script><body><p>Deploy the service with the release pipeline.</p></body></html>"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\n// main - Start the billing worker\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "diagram.png"), []byte("rotate vault secrets"), 0644)
+	return dir
+}
+
+func TestKnowledgeBase(t *testing.T) {
+	t.Run("KnowledgeBase", func(t *testing.T) {
+		dir := newKnowledgeDir(t)
+		path := filepath.Join(t.TempDir(), "vectors.json")
+
+		base, _ := service.OpenKnowledgeBase(dir, path)
+		updated, err := base.Update(localAgent, "text-embedding-ada-002")
+		saveErr := base.Save()
+
+		stored, _ := service.OpenKnowledgeBase(dir, path)
+		cached, _ := stored.Update(localAgent, "text-embedding-ada-002")
+		results, _ := stored.Retrieve(localAgent, "text-embedding-ada-002", "How do I rotate the vault secrets?", 2)
+		retrieved := len(results) == 2 && strings.HasSuffix(results[0].Document.Metadata["source"], "vault.md")
+
+		html, _ := stored.Retrieve(localAgent, "text-embedding-ada-002", "deploy with the release pipeline", 1)
+		extracted := len(html) == 1 && !strings.Contains(html[0].Document.Text, "var rotate")
+
+		os.Remove(filepath.Join(dir, "main.go"))
+		removed, _ := stored.Update(localAgent, "text-embedding-ada-002")
+		remaining, _ := stored.Retrieve(localAgent, "text-embedding-ada-002", "billing worker", 5)
+
+		if updated != 3 || err != nil || saveErr != nil || cached != 0 || !retrieved || !extracted || removed != 1 || len(remaining) != 2 {
+			t.Errorf("Received:%v %v %v\nExpected:%v\n", updated, err, results, "vault passage first")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestHeadlessKnowledge(t *testing.T) {
+	t.Run("HeadlessKnowledge", func(t *testing.T) {
+		t.Setenv("KNOWLEDGE_PATH", newKnowledgeDir(t))
+		agent := controller.AttachProfile()
+
+		var out bytes.Buffer
+		client, err := service.NewHeadless(agent, service.HeadlessOptions{Engine: "gpt-3.5-turbo"}, &out)
+		if err == nil {
+			err = client.Ask("How do I rotate the vault secrets?")
+		}
+
+		// The logged template keeps the passages sent with the question
+		var validated model.ChainPrompt
+		if exported, eerr := client.Export("log", "log"); eerr == nil {
+			events := exported.(model.HistoricalSession).Session
+			validated = events[len(events)-1].Event.Template.PromptValidated
+		}
+
+		isLogged := len(validated.Context) > 0 && strings.Contains(validated.Context[0], "Rotate the vault secrets") &&
+			len(validated.Source) > 0 && strings.HasSuffix(validated.Source[0], "vault.md")
+		if err != nil || !isLogged {
+			t.Errorf("Received:%v %v\nExpected:%v\n", validated, err, "the vault passage in the logged template")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
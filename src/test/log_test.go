@@ -0,0 +1,70 @@
+// Test section - Use case
+package caos
+
+import (
+	"encoding/json"
+	"testing"
+
+	"caos/model"
+	"caos/service"
+)
+
+// scrubSession - Remove the timestamps from the last session to keep it deterministic
+func scrubSession(pool model.PoolProperties) model.HistoricalSession {
+	session := pool.Session[len(pool.Session)-1]
+	for i := range session.Session {
+		session.Session[i].Timestamp = ""
+	}
+	return session
+}
+
+func TestLogChatCompletion(t *testing.T) {
+	t.Run("LogChatCompletion", func(t *testing.T) {
+		engineProperties.Model = "gpt-3.5-turbo"
+		initializeAgent()
+
+		var events service.EventManager
+		resp, _ := prompter.SendChatCompletionPrompt(localAgent)
+		if resp == nil {
+			t.Fatalf("Received:%v", resp)
+		}
+
+		events.LogChatCompletion(localAgent.TemplateProperties, localAgent.EngineProperties, localAgent.PromptProperties, nil, resp)
+		raw, _ := json.MarshalIndent(scrubSession(events.GetPool()), "", "\u0009")
+
+		checkGolden(t, "log_chat_completion.golden", raw)
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestVisualLogCompletion(t *testing.T) {
+	t.Run("VisualLogCompletion", func(t *testing.T) {
+		engineProperties.Model = "gpt-3.5-turbo"
+		initializeAgent()
+
+		var events service.EventManager
+		resp, _ := prompter.SendChatCompletionPrompt(localAgent)
+		if resp == nil {
+			t.Fatalf("Received:%v", resp)
+		}
+
+		checkGolden(t, "visual_log_chat_stream.golden", []byte(events.VisualLogCompletion(nil, nil, resp)))
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestVisualLogTextCompletion(t *testing.T) {
+	t.Run("VisualLogTextCompletion", func(t *testing.T) {
+		engineProperties.Model = "text-davinci-003"
+		initializeAgent()
+
+		var events service.EventManager
+		resp := prompter.SendCompletionPrompt(localAgent)
+		if resp == nil {
+			t.Fatalf("Received:%v", resp)
+		}
+
+		checkGolden(t, "visual_log_text_completion.golden", []byte(events.VisualLogCompletion(resp, nil, nil)))
+		t.Log("Test - FINISHED")
+	})
+}
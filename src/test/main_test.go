@@ -0,0 +1,69 @@
+// Test section - Use case
+package caos
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"caos/test/mock"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+var goldenPath string
+
+// TestMain - Run the suite against the fake server unless LIVE_TEST is defined
+func TestMain(m *testing.M) {
+	flag.Parse()
+	os.Exit(runSuite(m))
+}
+
+// runSuite - Prepare the environment and run the suite
+func runSuite(m *testing.M) int {
+
+	dir, _ := os.Getwd()
+	goldenPath = filepath.Join(dir, "golden")
+
+	if os.Getenv("LIVE_TEST") == "" {
+		server := mock.NewServer()
+		defer server.Close()
+
+		os.Setenv("API_KEY", mock.Key)
+		os.Setenv("ZERO_API_KEY", mock.Key)
+		os.Setenv("OPENAI_BASE_URL", fmt.Sprint(server.URL, "/v1"))
+		os.Setenv("ZERO_BASE_URL", fmt.Sprint(server.URL, "/v2/predict/text"))
+		os.Setenv("SEARCH_BASE_URL", fmt.Sprint(server.URL, "/search?q="))
+		os.Unsetenv("BASE_URL")
+		os.Unsetenv("REPLAY_PATH")
+		os.Unsetenv("RESUME_SESSION")
+	}
+
+	// Logs, exports and cookies are stored outside of the repository
+	tmp, _ := os.MkdirTemp("", "caos-test")
+	defer os.RemoveAll(tmp)
+	os.Chdir(tmp)
+
+	localAgent = controller.AttachProfile()
+
+	return m.Run()
+}
+
+// checkGolden - Compare the output with the stored golden file
+func checkGolden(t *testing.T, name string, out []byte) {
+	path := filepath.Join(goldenPath, name)
+	if *update {
+		os.MkdirAll(goldenPath, 0755)
+		os.WriteFile(path, out, 0644)
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil || string(expected) != string(out) {
+		t.Errorf("Received:%s\nExpected:%s\n", out, expected)
+		t.Log("Test - FAILED")
+	} else {
+		t.Log("Test - PASSED")
+	}
+}
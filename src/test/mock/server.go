@@ -0,0 +1,382 @@
+// Package mock section
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"caos/model"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// Fixed response values to keep the outputs deterministic
+const (
+	ID         = "mock-response"
+	Created    = 1689910077
+	Key        = "mock-key"
+	Dimensions = 64
+)
+
+// ChatContent - Content answered by the chat completion route
+const ChatContent = "Question: mock question\nResponse: mock response from the fake server\nSource: https://example.com/caos"
+
+// TextContent - Content answered by the completion route
+const TextContent = "mock completion from the fake server"
+
+// Models - Models listed by the engines route
+var Models = []string{"gpt-3.5-turbo", "gpt-3.5-turbo-16k", "text-davinci-003", "text-davinci-edit-001", "text-embedding-ada-002"}
+
+// NewServer - Hermetic stand-in for the OpenAI, GPTZero and search endpoints
+func NewServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/engines", authorize(onEngines))
+	mux.HandleFunc("/v1/models", authorize(onEngines))
+	mux.HandleFunc("/v1/engines/", authorize(onCompletion))
+	mux.HandleFunc("/v1/completions", authorize(onCompletion))
+	mux.HandleFunc("/v1/chat/completions", authorize(onChatCompletion))
+	mux.HandleFunc("/v1/edits", authorize(onEdit))
+	mux.HandleFunc("/v1/embeddings", authorize(onEmbedding))
+	mux.HandleFunc("/v2/predict/text", onPredict)
+	mux.HandleFunc("/search", onSearch)
+	return httptest.NewServer(mux)
+}
+
+// authorize - Validate the bearer key like the OpenAI API
+func authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != fmt.Sprint("Bearer ", Key) {
+			onError(w, http.StatusUnauthorized, "invalid_request_error", "Incorrect API key provided")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// onError - Write an OpenAI error response
+func onError(w http.ResponseWriter, status int, kind string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(gpt3.APIErrorResponse{
+		Error: gpt3.APIError{
+			Message: message,
+			Type:    kind,
+		},
+	})
+}
+
+// onJSON - Write a JSON response
+func onJSON(w http.ResponseWriter, out interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// onStream - Write a list of server-sent events terminated by the done sequence
+func onStream(w http.ResponseWriter, events []interface{}) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+	for _, i := range events {
+		raw, _ := json.Marshal(i)
+		fmt.Fprintf(w, "data: %s\n\n", raw)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// split - Split the content in streaming deltas
+func split(text string) []string {
+	return strings.SplitAfter(text, " ")
+}
+
+// results - Amount of choices requested
+func results(n *int) int {
+	if n == nil || *n < 1 {
+		return 1
+	}
+	return *n
+}
+
+// onEngines - List available models
+func onEngines(w http.ResponseWriter, r *http.Request) {
+	resp := gpt3.EnginesResponse{
+		Object: "list",
+	}
+	for _, i := range Models {
+		resp.Data = append(resp.Data, gpt3.EngineObject{
+			ID:     i,
+			Object: "engine",
+			Owner:  "openai",
+			Ready:  true,
+		})
+	}
+	onJSON(w, resp)
+}
+
+// onChatCompletion - Chat completion route with streaming support
+func onChatCompletion(w http.ResponseWriter, r *http.Request) {
+	var req gpt3.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) == 0 {
+		onError(w, http.StatusBadRequest, "invalid_request_error", "messages is a required property")
+		return
+	}
+
+	usage := gpt3.ChatCompletionsResponseUsage{
+		PromptTokens:     len(req.Messages),
+		CompletionTokens: len(split(ChatContent)),
+		TotalTokens:      len(req.Messages) + len(split(ChatContent)),
+	}
+
+	if req.Stream {
+		var events []interface{}
+		deltas := split(ChatContent)
+		for i := range deltas {
+			var reason string
+			if i == len(deltas)-1 {
+				reason = "stop"
+			}
+			events = append(events, gpt3.ChatCompletionStreamResponse{
+				ID:      ID,
+				Object:  "chat.completion.chunk",
+				Created: Created,
+				Model:   req.Model,
+				Choices: []gpt3.ChatCompletionStreamResponseChoice{
+					{
+						FinishReason: reason,
+						Delta: gpt3.ChatCompletionResponseMessage{
+							Role:    string(model.Assistant),
+							Content: deltas[i],
+						},
+					},
+				},
+				Usage: usage,
+			})
+		}
+		onStream(w, events)
+		return
+	}
+
+	resp := gpt3.ChatCompletionResponse{
+		ID:      ID,
+		Object:  "chat.completion",
+		Created: Created,
+		Model:   req.Model,
+		Usage:   usage,
+	}
+	for i := 0; i < results(&req.N); i++ {
+		resp.Choices = append(resp.Choices, gpt3.ChatCompletionResponseChoice{
+			Index:        i,
+			FinishReason: "stop",
+			Message: gpt3.ChatCompletionResponseMessage{
+				Role:    string(model.Assistant),
+				Content: ChatContent,
+			},
+		})
+	}
+	onJSON(w, resp)
+}
+
+// onCompletion - Completion route for engines and models with streaming support
+func onCompletion(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+		gpt3.CompletionRequest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Prompt) == 0 {
+		onError(w, http.StatusBadRequest, "invalid_request_error", "prompt is a required property")
+		return
+	}
+
+	engine := req.Model
+	if strings.HasPrefix(r.URL.Path, "/v1/engines/") {
+		engine = strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/engines/"), "/completions")
+	}
+
+	usage := gpt3.CompletionResponseUsage{
+		PromptTokens:     len(split(strings.Join(req.Prompt, ""))),
+		CompletionTokens: len(split(TextContent)),
+		TotalTokens:      len(split(strings.Join(req.Prompt, ""))) + len(split(TextContent)),
+	}
+
+	if req.Stream {
+		var events []interface{}
+		deltas := split(TextContent)
+		for i := range deltas {
+			var reason string
+			if i == len(deltas)-1 {
+				reason = "stop"
+			}
+			events = append(events, gpt3.CompletionResponse{
+				ID:      ID,
+				Object:  "text_completion",
+				Created: Created,
+				Model:   engine,
+				Choices: []gpt3.CompletionResponseChoice{
+					{
+						Text:         deltas[i],
+						FinishReason: reason,
+						LogProbs: gpt3.LogprobResult{
+							Tokens:        []string{deltas[i]},
+							TokenLogprobs: []float32{-0.5},
+							TextOffset:    []int{i},
+						},
+					},
+				},
+				Usage: usage,
+			})
+		}
+		onStream(w, events)
+		return
+	}
+
+	resp := gpt3.CompletionResponse{
+		ID:      ID,
+		Object:  "text_completion",
+		Created: Created,
+		Model:   engine,
+		Usage:   usage,
+	}
+	for i := 0; i < results(req.N); i++ {
+		resp.Choices = append(resp.Choices, gpt3.CompletionResponseChoice{
+			Text:         TextContent,
+			Index:        i,
+			FinishReason: "stop",
+		})
+	}
+	onJSON(w, resp)
+}
+
+// onEdit - Edit route
+func onEdit(w http.ResponseWriter, r *http.Request) {
+	var req gpt3.EditsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Instruction == "" {
+		onError(w, http.StatusBadRequest, "invalid_request_error", "instruction is a required property")
+		return
+	}
+
+	resp := gpt3.EditsResponse{
+		Object:  "edit",
+		Created: Created,
+		Usage: gpt3.EditsResponseUsage{
+			PromptTokens:     len(split(req.Input)),
+			CompletionTokens: len(split(req.Input)) + len(split(req.Instruction)),
+			TotalTokens:      2*len(split(req.Input)) + len(split(req.Instruction)),
+		},
+	}
+	for i := 0; i < results(req.N); i++ {
+		resp.Choices = append(resp.Choices, gpt3.EditsResponseChoice{
+			Text:  fmt.Sprint(req.Input, "\n", req.Instruction),
+			Index: i,
+		})
+	}
+	onJSON(w, resp)
+}
+
+// Embed - Deterministic bag of words vector, texts sharing words are close by cosine similarity
+func Embed(text string) []float64 {
+	vector := make([]float64, Dimensions)
+	for _, i := range strings.Fields(strings.ToLower(text)) {
+		word := strings.Trim(i, ".,;:!?\"'()[]{}")
+		if word == "" {
+			continue
+		}
+		hash := fnv.New32a()
+		hash.Write([]byte(word))
+		vector[hash.Sum32()%Dimensions]++
+	}
+
+	var norm float64
+	for _, i := range vector {
+		norm += i * i
+	}
+	if norm > 0 {
+		for i := range vector {
+			vector[i] /= math.Sqrt(norm)
+		}
+	}
+	return vector
+}
+
+// onEmbedding - Embedding route
+func onEmbedding(w http.ResponseWriter, r *http.Request) {
+	var req gpt3.EmbeddingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Input) == 0 {
+		onError(w, http.StatusBadRequest, "invalid_request_error", "input is a required property")
+		return
+	}
+
+	resp := gpt3.EmbeddingsResponse{
+		Object: "list",
+	}
+	for i := range req.Input {
+		resp.Data = append(resp.Data, gpt3.EmbeddingsResult{
+			Object:    "embedding",
+			Embedding: Embed(req.Input[i]),
+			Index:     i,
+		})
+		resp.Usage.PromptTokens += len(split(req.Input[i]))
+	}
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens
+	onJSON(w, resp)
+}
+
+// onPredict - GPTZero prediction route
+func onPredict(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Api-Key") != Key {
+		onError(w, http.StatusUnauthorized, "unauthorized", "Invalid API key")
+		return
+	}
+
+	var req model.PredictRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Document == "" {
+		onError(w, http.StatusBadRequest, "invalid_request_error", "document is a required property")
+		return
+	}
+
+	var sentences []model.Sentence
+	for _, i := range strings.Split(req.Document, ".") {
+		if strings.TrimSpace(i) != "" {
+			sentences = append(sentences, model.Sentence{
+				Sentence:      strings.TrimSpace(i),
+				Perplexity:    42,
+				GeneratedProb: 0.25,
+			})
+		}
+	}
+
+	onJSON(w, model.PredictResponse{
+		Documents: []model.Predict{
+			{
+				AverageProb:       0.25,
+				CompletelyProb:    0.1,
+				OverallBurstiness: 12,
+				Sentences:         sentences,
+				Paragraphs: []model.Paragraph{
+					{
+						Index:           0,
+						NumberSentences: len(sentences),
+						CompletelyProb:  0.1,
+					},
+				},
+			},
+		},
+	})
+}
+
+// onSearch - Search results page scraped by the chain
+func onSearch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprintf(w, `<html><body>
+<div><a href="/url?q=https://example.com/caos&amp;sa=U">Conversational assistant</a></div>
+<div><a href="/url?q=https://example.org/openai&amp;sa=U">OpenAI services</a></div>
+<div>Results for %s</div>
+<div>caos is a conversational assistant for OpenAI services</div>
+</body></html>`, html.EscapeString(r.URL.Query().Get("q")))
+}
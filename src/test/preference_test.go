@@ -0,0 +1,74 @@
+// Test section - Use case
+package caos
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"caos/model"
+	"caos/service"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+func TestRateChoices(t *testing.T) {
+	t.Run("RateChoices", func(t *testing.T) {
+		initializeAgent()
+		os.Remove(filepath.Join("training", "ratings.json"))
+
+		resp := newChatResponse("choices", "first choice")
+		resp.Choices = append(resp.Choices,
+			gpt3.ChatCompletionResponseChoice{Message: gpt3.ChatCompletionResponseMessage{Content: "second choice"}},
+			gpt3.ChatCompletionResponseChoice{Message: gpt3.ChatCompletionResponseMessage{Content: "third choice"}})
+
+		var events service.EventManager
+		events.LogChatCompletion(localAgent.TemplateProperties, localAgent.EngineProperties, localAgent.PromptProperties, newChatResponse("single", "only choice"), nil)
+		events.LogChatCompletion(localAgent.TemplateProperties, localAgent.EngineProperties, localAgent.PromptProperties, resp, nil)
+
+		rating, isRated := events.GetRating()
+		pending := isRated && len(events.GetPool().Ratings) == 1 && len(rating.Choices) == 3 &&
+			rating.Best == -1 && rating.Worst == -1 && service.GetPreferencePairs(events.GetPool().Ratings) == nil
+
+		outOfRange := events.RateChoice(3, true)
+		events.RateChoice(2, true)
+		events.RateChoice(0, false)
+		events.RateChoice(1, true)
+		events.RateChoice(2, false)
+
+		expected := []model.PreferencePair{
+			{Prompt: rating.Prompt[0], Chosen: "second choice", Rejected: "first choice"},
+			{Prompt: rating.Prompt[0], Chosen: "first choice", Rejected: "third choice"},
+			{Prompt: rating.Prompt[0], Chosen: "second choice", Rejected: "third choice"},
+		}
+
+		// The ratings are read from the training directory by a new conversation
+		var exporter service.EventManager
+		var exported []model.PreferencePair
+		file, err := os.Open(exporter.ExportPreferences())
+		if err == nil {
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				var pair model.PreferencePair
+				json.Unmarshal(scanner.Bytes(), &pair)
+				exported = append(exported, pair)
+			}
+			file.Close()
+		}
+
+		isExported := len(exported) == len(expected)
+		for i := 0; isExported && i < len(expected); i++ {
+			isExported = exported[i] == expected[i]
+		}
+
+		if !pending || outOfRange == nil || !isExported {
+			t.Errorf("Received:%v\nExpected:%v\n", exported, expected)
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
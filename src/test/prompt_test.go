@@ -2,6 +2,7 @@
 package caos
 
 import (
+	"strings"
 	"testing"
 
 	"caos/model"
@@ -11,7 +12,7 @@ import (
 var controller = &service.Controller{}
 var prompter = &service.Prompt{}
 
-var localAgent = controller.AttachProfile()
+var localAgent service.Agent
 
 var engineProperties = &model.EngineProperties{
 	UserID:           "test_user",
@@ -203,3 +204,54 @@ func BenchmarkGetListModels(t *testing.B) {
 	})
 	t.Log("Test - FINISHED")
 }
+
+func TestSendChatCompletionHistory(t *testing.T) {
+	t.Run("SendChatCompletionHistory", func(t *testing.T) {
+		engineProperties.Model = "gpt-3.5-turbo"
+		initializeAgent()
+
+		agent := localAgent
+		agent.ClearMessages()
+		first, _ := prompter.SendChatCompletionPrompt(agent)
+
+		agent.AppendMessages(promptProperties.Input[0], "UML generated")
+		second, _ := prompter.SendChatCompletionPrompt(agent)
+
+		if first == nil || second == nil ||
+			len(agent.GetMessages()) != 2 || second.Usage.PromptTokens != first.Usage.PromptTokens+2 {
+			t.Errorf("Received:%v %v\nExpected:%v\n", first, second, "history sent with the prompt")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestSendSummary(t *testing.T) {
+	t.Run("SendSummary", func(t *testing.T) {
+		engineProperties.Model = "gpt-3.5-turbo"
+		initializeAgent()
+
+		agent := localAgent
+		agent.ClearMessages()
+		agent.SetSummaryPreferences(2, "gpt-3.5-turbo-16k")
+		for i := 0; i < 3; i++ {
+			agent.AppendMessages(promptProperties.Input[0], "UML generated")
+		}
+
+		expired := agent.ExpiredMessages()
+		summary := prompter.SendSummaryPrompt(agent, expired)
+		agent.SummarizeMessages(summary, len(expired))
+
+		messages := agent.GetMessages()
+		if len(expired) != 4 || summary == "" || agent.GetSummary() != summary ||
+			len(messages) != 3 || !strings.Contains(messages[0].Content, summary) || agent.ExpiredMessages() != nil {
+			t.Errorf("Received:%v %v\nExpected:%v\n", summary, messages, "summarized history")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
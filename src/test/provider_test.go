@@ -0,0 +1,66 @@
+// Test section - Use case
+package caos
+
+import (
+	gocontext "context"
+	"testing"
+
+	"caos/service"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// fakeProvider - Static backend used to validate the provider interface
+type fakeProvider struct{}
+
+func (c *fakeProvider) ChatCompletion(ctx gocontext.Context, request gpt3.ChatCompletionRequest) (*gpt3.ChatCompletionResponse, error) {
+	return &gpt3.ChatCompletionResponse{ID: "fake-chat"}, nil
+}
+
+func (c *fakeProvider) ChatCompletionStream(ctx gocontext.Context, request gpt3.ChatCompletionRequest, onData func(*gpt3.ChatCompletionStreamResponse)) error {
+	return nil
+}
+
+func (c *fakeProvider) Completion(ctx gocontext.Context, engine string, request gpt3.CompletionRequest) (*gpt3.CompletionResponse, error) {
+	return &gpt3.CompletionResponse{ID: "fake-completion"}, nil
+}
+
+func (c *fakeProvider) CompletionStream(ctx gocontext.Context, engine string, request gpt3.CompletionRequest, onData func(*gpt3.CompletionResponse)) error {
+	return nil
+}
+
+func (c *fakeProvider) Edit(ctx gocontext.Context, request gpt3.EditsRequest) (*gpt3.EditsResponse, error) {
+	return &gpt3.EditsResponse{Choices: []gpt3.EditsResponseChoice{{Text: request.Input}}}, nil
+}
+
+func (c *fakeProvider) Embedding(ctx gocontext.Context, request gpt3.EmbeddingsRequest) (*gpt3.EmbeddingsResponse, error) {
+	return &gpt3.EmbeddingsResponse{Data: []gpt3.EmbeddingsResult{{Embedding: []float64{0.1, 0.2}}}}, nil
+}
+
+func (c *fakeProvider) ListModels(ctx gocontext.Context) (*gpt3.EnginesResponse, error) {
+	return &gpt3.EnginesResponse{Data: []gpt3.EngineObject{{ID: "fake-model"}}}, nil
+}
+
+func TestSetProvider(t *testing.T) {
+	t.Run("SetProvider", func(t *testing.T) {
+		var provider service.Provider = &fakeProvider{}
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+		agent.EngineProperties = *engineProperties
+		agent.PromptProperties = *promptProperties
+
+		edit := prompter.SendEditPrompt(agent)
+		models := prompter.GetListModels(agent)
+
+		if agent.GetProvider() != provider ||
+			edit == nil || edit.Choices[0].Text != promptProperties.Input[0] ||
+			models == nil || models.Data[0].ID != "fake-model" {
+			t.Errorf("Received:%v\nExpected:%v\n", edit, promptProperties.Input[0])
+			t.Errorf("Received:%v\nExpected:%v\n", models, "fake-model")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
@@ -0,0 +1,135 @@
+// Test section - Use case
+package caos
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"caos/model"
+	"caos/service"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// newReplayDir - Temporal log directory with a recorded session
+func newReplayDir(t *testing.T) string {
+	dir := t.TempDir()
+	session := model.HistoricalSession{
+		ID: "chatcmpl-recorded",
+		Session: []model.HistoricalEvent{
+			{
+				Timestamp: "1689910077911",
+				Event: model.HistoricalPrompt{
+					Header: model.EngineProperties{Model: "gpt-3.5-turbo"},
+					Body: model.PromptProperties{
+						Input:   []string{"What is caos?"},
+						Content: []string{"A conversational assistant for OpenAI services"},
+					},
+				},
+			},
+		},
+	}
+
+	raw, _ := json.MarshalIndent(session, "", "\u0009")
+	os.WriteFile(filepath.Join(dir, "log-recorded.json"), raw, 0644)
+	os.WriteFile(filepath.Join(dir, "log-invalid.json"), []byte("{"), 0644)
+	return dir
+}
+
+func TestReplayChatCompletion(t *testing.T) {
+	t.Run("ReplayChatCompletion", func(t *testing.T) {
+		provider := service.NewReplayProvider(newReplayDir(t))
+		req := gpt3.ChatCompletionRequest{
+			Messages: []gpt3.ChatCompletionRequestMessage{
+				{Role: string(model.System), Content: "I want you to act as an assistant"},
+				{Role: string(model.User), Content: "What is caos?\nNow you have real-time access to the internet"},
+			},
+		}
+
+		resp, err := provider.ChatCompletion(gocontext.Background(), req)
+
+		var stream []string
+		serr := provider.ChatCompletionStream(gocontext.Background(), req, func(out *gpt3.ChatCompletionStreamResponse) {
+			stream = append(stream, out.Choices[0].Delta.Content)
+		})
+
+		expected := "A conversational assistant for OpenAI services"
+		if len(provider.GetRecords()) != 1 ||
+			err != nil || resp.ID != "chatcmpl-recorded" || resp.Choices[0].Message.Content != expected ||
+			serr != nil || len(stream) < 2 || strings.Join(stream, "") != expected {
+			t.Errorf("Received:%v %v\nExpected:%v\n", resp, err, expected)
+			t.Errorf("Received:%v %v\nExpected:%v\n", stream, serr, expected)
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestReplayNotRecorded(t *testing.T) {
+	t.Run("ReplayNotRecorded", func(t *testing.T) {
+		provider := service.NewReplayProvider(newReplayDir(t))
+		req := gpt3.CompletionRequest{
+			Prompt: []string{"Unknown prompt"},
+		}
+
+		resp, err := provider.Completion(gocontext.Background(), "text-davinci-003", req)
+		if err == nil || resp != nil {
+			t.Errorf("Received:%v\nExpected:%v\n", resp, "error")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestReplayMatchInput(t *testing.T) {
+	t.Run("ReplayMatchInput", func(t *testing.T) {
+		provider := service.NewReplayProvider(newReplayDir(t))
+		ask := func(content string) error {
+			_, err := provider.ChatCompletion(gocontext.Background(), gpt3.ChatCompletionRequest{
+				Messages: []gpt3.ChatCompletionRequestMessage{{Role: string(model.User), Content: content}},
+			})
+			return err
+		}
+
+		// Only the same input is replayed, a prompt containing it is a different question
+		spaced := ask("  What is\n caos? ")
+		contained := ask("Tell me What is caos? and how to install it")
+		templated, terr := provider.Completion(gocontext.Background(), "text-davinci-003", gpt3.CompletionRequest{
+			Prompt: []string{"I want you to act as an assistant: What is caos?"},
+		})
+
+		if spaced != nil || contained == nil || terr != nil || templated.Choices[0].Text != "A conversational assistant for OpenAI services" {
+			t.Errorf("Received:%v %v %v\nExpected:%v\n", spaced, contained, terr, "the recorded input matched by equality")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestReplayProfileMode(t *testing.T) {
+	t.Run("ReplayProfileMode", func(t *testing.T) {
+		os.Setenv("REPLAY_PATH", newReplayDir(t))
+		agent := service.AttachHeadlessProfile()
+		os.Unsetenv("REPLAY_PATH")
+
+		// The recorded chat models are answered in chat mode
+		status := agent.GetStatus()
+		if status.Engine != "gpt-3.5-turbo" || status.Mode != "Turbo" {
+			t.Errorf("Received:%v %v\nExpected:%v\n", status.Engine, status.Mode, "gpt-3.5-turbo Turbo")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
@@ -0,0 +1,104 @@
+// Test section - Use case
+package caos
+
+import (
+	"strings"
+	"testing"
+
+	"caos/model"
+	"caos/service"
+)
+
+func TestResearcher(t *testing.T) {
+	t.Run("Researcher", func(t *testing.T) {
+		provider := &scriptedProvider{replies: []string{
+			`{"action": "search", "thought": "Find what caos is", "query": "caos assistant"}`,
+			`{"action": "open", "thought": "Read the project page", "source": 1}`,
+			`{"action": "answer", "report": "caos is a conversational assistant [1]"}`,
+		}}
+
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+		agent.EngineProperties = *engineProperties
+
+		var steps int
+		researcher := service.NewResearcher(agent)
+		researcher.Open = func(url string) model.ChainPrompt {
+			return model.ChainPrompt{Source: []string{url}, Context: []string{"caos is a conversational assistant for OpenAI services"}}
+		}
+		researcher.OnStep = func(report model.ResearchReport) {
+			steps = len(report.Steps)
+		}
+
+		report, err := researcher.Run(agent, "What is caos?")
+		out := service.FormatResearchReport(report)
+
+		searched := len(report.Steps) == 3 && report.Steps[0].Action == "search" && len(report.Sources) > 0 &&
+			report.Sources[0].Read && strings.Contains(report.Sources[0].Excerpt, "conversational")
+		cited := strings.Contains(out, "caos is a conversational assistant [1]") &&
+			strings.Contains(out, "Sources:\n[1] "+report.Sources[0].URL)
+		fed := len(provider.requests) == 3 && strings.Contains(provider.requests[2].Messages[len(provider.requests[2].Messages)-1].Content, "Source [1]")
+
+		if err != nil || !searched || !cited || !fed || steps != 3 {
+			t.Errorf("Received:%v %v\nExpected:%v\n", err, out, "report citing the opened source")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestResearcherLimit(t *testing.T) {
+	t.Run("ResearcherLimit", func(t *testing.T) {
+		provider := &scriptedProvider{replies: []string{
+			`{"action": "search", "query": "caos"}`,
+		}}
+
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+		agent.EngineProperties = *engineProperties
+
+		researcher := &service.Researcher{
+			MaxSteps: 2,
+			Budget:   1000,
+			Search: func(query string) model.ChainPrompt {
+				return model.ChainPrompt{Source: []string{"https://example.com/" + query}}
+			},
+		}
+
+		report, err := researcher.Run(agent, "What is caos?")
+		if err != nil || len(report.Steps) != 3 || report.Stopped != "steps" || report.Report == "" || len(report.Sources) != 1 {
+			t.Errorf("Received:%v %v\nExpected:%v\n", err, report, "research stopped by the steps limit")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestResearcherInvalidLimits(t *testing.T) {
+	t.Run("ResearcherInvalidLimits", func(t *testing.T) {
+		provider := &scriptedProvider{replies: []string{`{"action": "answer", "report": "caos"}`}}
+
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+		agent.EngineProperties = *engineProperties
+
+		// The run doesn't start without steps or budget
+		var errs []error
+		for _, i := range []service.Researcher{{MaxSteps: 0, Budget: 1000}, {MaxSteps: 2, Budget: -1}} {
+			_, err := i.Run(agent, "What is caos?")
+			errs = append(errs, err)
+		}
+
+		if errs[0] == nil || errs[1] == nil || len(provider.requests) != 0 {
+			t.Errorf("Received:%v %v\nExpected:%v\n", errs, len(provider.requests), "the research rejected before any request")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
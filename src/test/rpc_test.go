@@ -0,0 +1,184 @@
+// Test section - Use case
+package caos
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"caos/model"
+	"caos/service"
+)
+
+// readRPC - Messages written by the JSON-RPC, one per line
+func readRPC(out string) []map[string]json.RawMessage {
+	var messages []map[string]json.RawMessage
+	for _, i := range strings.Split(strings.TrimSpace(out), "\n") {
+		var message map[string]json.RawMessage
+		json.Unmarshal([]byte(i), &message)
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func TestRPCPrompt(t *testing.T) {
+	t.Run("RPCPrompt", func(t *testing.T) {
+		agent := controller.AttachProfile()
+		agent.SetProvider(&streamProvider{})
+
+		var out bytes.Buffer
+		rpc, _ := service.NewRPC(agent, &out)
+		err := rpc.Serve(strings.NewReader(strings.Join([]string{
+			`{"jsonrpc": "2.0", "id": 1, "method": "session/new", "params": {"engine": "gpt-3.5-turbo"}}`,
+			`{"jsonrpc": "2.0", "id": 2, "method": "prompt/send", "params": {"prompt": "Say hello"}}`,
+			`{"jsonrpc": "2.0", "id": 3, "method": "session/export", "params": {"format": "training"}}`,
+		}, "\n")))
+
+		messages := readRPC(out.String())
+		var token model.RPCToken
+		var answer model.PromptResponse
+		var training []model.TrainingSession
+		if len(messages) == 5 {
+			json.Unmarshal(messages[1]["params"], &token)
+			json.Unmarshal(messages[3]["result"], &answer)
+			json.Unmarshal(messages[4]["result"], &training)
+		}
+
+		isStreamed := len(messages) == 5 && string(messages[1]["method"]) == `"prompt/token"` && string(token.ID) == "2" && token.Text == "Hello" &&
+			string(messages[3]["id"]) == "2" && len(answer.Choices) == 1 && answer.Choices[0] == "Hello world"
+
+		if err != nil || !isStreamed || len(training) != 1 || training[0].Session[0].Event.Completion[0] != "Hello world" {
+			t.Errorf("Received:%v %v\nExpected:%v\n", out.String(), err, "the tokens, the answer and the export")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestRPCMethods(t *testing.T) {
+	t.Run("RPCMethods", func(t *testing.T) {
+		agent := controller.AttachProfile()
+		agent.SetProvider(&fakeProvider{})
+
+		var out bytes.Buffer
+		rpc, _ := service.NewRPC(agent, &out)
+		rpc.Serve(strings.NewReader(strings.Join([]string{
+			`{"jsonrpc": "2.0", "id": "a", "method": "templates/list"}`,
+			`{"jsonrpc": "2.0", "id": "b", "method": "templates/render", "params": {"template": "assistant", "prompt": "Hi"}}`,
+			`{"jsonrpc": "2.0", "id": "c", "method": "edit/apply", "params": {"selection": "fix teh typo", "instruction": "Fix the spelling"}}`,
+			`{"jsonrpc": "2.0", "id": "d", "method": "models/list"}`,
+			`{"jsonrpc": "2.0", "method": "prompt/cancel"}`,
+			`{"jsonrpc": "2.0", "id": "e", "method": "unknown"}`,
+			`not json`,
+		}, "\n")))
+
+		messages := readRPC(out.String())
+		var templates []model.RPCTemplate
+		var rendered string
+		var edited model.PromptResponse
+		var unknown, invalid model.RPCError
+		if len(messages) == 6 {
+			json.Unmarshal(messages[0]["result"], &templates)
+			json.Unmarshal(messages[1]["result"], &rendered)
+			json.Unmarshal(messages[2]["result"], &edited)
+			json.Unmarshal(messages[4]["error"], &unknown)
+			json.Unmarshal(messages[5]["error"], &invalid)
+		}
+
+		isAnswered := len(messages) == 6 && len(templates) > 1 && templates[1].Name == "assistant" && strings.HasSuffix(rendered, "Hi") &&
+			strings.HasPrefix(rendered, templates[1].Context) && len(edited.Choices) == 1 && edited.Choices[0] == "fix teh typo" &&
+			string(messages[3]["result"]) == `["fake-model"]` && unknown.Code == -32601 && invalid.Code == -32700
+
+		if !isAnswered {
+			t.Errorf("Received:%v\nExpected:%v\n", out.String(), "a response for each request with an id")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestRPCPromptTemplate(t *testing.T) {
+	t.Run("RPCPromptTemplate", func(t *testing.T) {
+		provider := &scriptedProvider{replies: []string{"Bonjour", "Hello"}}
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+
+		var out bytes.Buffer
+		rpc, _ := service.NewRPC(agent, &out)
+		rpc.Serve(strings.NewReader(strings.Join([]string{
+			`{"jsonrpc": "2.0", "id": 1, "method": "session/new", "params": {"engine": "gpt-3.5-turbo"}}`,
+			`{"jsonrpc": "2.0", "id": 2, "method": "prompt/send", "params": {"prompt": "Hello", "template": "translator", "variables": {"language": "French"}, "stream": false}}`,
+			`{"jsonrpc": "2.0", "id": 3, "method": "prompt/send", "params": {"prompt": "Hello", "stream": false}}`,
+			`{"jsonrpc": "2.0", "id": 4, "method": "prompt/send", "params": {"prompt": "Hello", "template": "unknown"}}`,
+		}, "\n")))
+
+		var systems []string
+		for _, i := range provider.requests {
+			if len(i.Messages) > 0 {
+				systems = append(systems, i.Messages[0].Content)
+			}
+		}
+
+		// Only the request with the template is translated, the unknown template is rejected
+		messages := readRPC(out.String())
+		var unknown model.RPCError
+		if len(messages) == 4 {
+			json.Unmarshal(messages[3]["error"], &unknown)
+		}
+
+		if len(systems) != 2 || !strings.Contains(systems[0], "into French") || strings.Contains(systems[1], "into French") || unknown.Code != -32602 {
+			t.Errorf("Received:%v %v\nExpected:%v\n", systems, out.String(), "the template applied to its request only")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestRPCEditThenSend(t *testing.T) {
+	t.Run("RPCEditThenSend", func(t *testing.T) {
+		agent := controller.AttachProfile()
+		agent.SetProvider(&echoProvider{})
+
+		var out bytes.Buffer
+		rpc, _ := service.NewRPC(agent, &out)
+		rpc.Serve(strings.NewReader(strings.Join([]string{
+			`{"jsonrpc": "2.0", "id": 1, "method": "edit/apply", "params": {"selection": "fix teh typo", "instruction": "Fix the spelling"}}`,
+			`{"jsonrpc": "2.0", "id": 2, "method": "prompt/send", "params": {"prompt": "Hello", "stream": false}}`,
+			`{"jsonrpc": "2.0", "id": 3, "method": "prompt/send", "params": {"prompt": "Hello again", "stream": false}}`,
+			`{"jsonrpc": "2.0", "id": 4, "method": "session/export", "params": {"format": "log"}}`,
+		}, "\n")))
+
+		messages := readRPC(out.String())
+		var first, second model.PromptResponse
+		var exported model.HistoricalSession
+		if len(messages) == 4 {
+			json.Unmarshal(messages[1]["result"], &first)
+			json.Unmarshal(messages[2]["result"], &second)
+			json.Unmarshal(messages[3]["result"], &exported)
+		}
+
+		// The prompts after the edit keep the profile engine and are turns of the same session
+		var turns int
+		for _, i := range exported.Session {
+			if len(i.Event.Body.Input) > 0 && strings.HasPrefix(i.Event.Body.Input[0], "Hello") {
+				turns++
+			}
+		}
+
+		if len(first.Choices) != 1 || first.Choices[0] != "text-davinci-003 answer" ||
+			len(second.Choices) != 1 || second.Choices[0] != "text-davinci-003 answer" || turns != 2 {
+			t.Errorf("Received:%v\nExpected:%v\n", out.String(), "two turns of the profile engine after the edit")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
@@ -0,0 +1,123 @@
+// Test section - Use case
+package caos
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"caos/util"
+)
+
+// recipeSchema - JSON Schema of the structured output tests
+const recipeSchema = `{
+	"type": "object",
+	"required": ["name", "servings", "steps"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 1},
+		"servings": {"type": "integer", "minimum": 1},
+		"difficulty": {"enum": ["easy", "hard"]},
+		"steps": {"type": "array", "minItems": 1, "items": {"$ref": "#/definitions/step"}}
+	},
+	"definitions": {
+		"step": {"type": "string"}
+	}
+}`
+
+func TestValidateSchema(t *testing.T) {
+	t.Run("ValidateSchema", func(t *testing.T) {
+		schema, err := util.ParseSchema([]byte(recipeSchema))
+
+		valid, _ := util.ExtractJSON("```json\n{\"name\": \"Bread\", \"servings\": 2, \"steps\": [\"Knead\"]}\n```")
+		invalid, _ := util.ExtractJSON(`{"name": "", "servings": 1.5, "difficulty": "medium", "steps": [3], "notes": "none"}`)
+
+		errs := schema.Validate(invalid)
+		expected := []string{"$.name", "$.servings", "$.difficulty", "$.steps[0]", "unexpected property notes"}
+		isReported := len(errs) == len(expected)
+		for i := range expected {
+			isReported = isReported && strings.Contains(strings.Join(errs, "\n"), expected[i])
+		}
+
+		if err != nil || schema.Validate(valid) != nil || !isReported {
+			t.Errorf("Received:%v\nExpected:%v\n", errs, expected)
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestParseSchemaUnsupported(t *testing.T) {
+	t.Run("ParseSchemaUnsupported", func(t *testing.T) {
+		rejected := []string{
+			`{"type": "object", "properties": {"tags": {"type": "array", "uniqueItems": true}}}`,
+			`{"not": {"type": "string"}}`,
+			`{"definitions": {"even": {"type": "integer", "multipleOf": 2}}}`,
+			`{"anyOf": [{"type": "string", "format": "email"}]}`,
+			`{"type": "number", "minimum": 0, "exclusiveMinimum": true}`,
+			`{"type": "array", "items": [{"type": "string"}]}`,
+			`{"type": "string", "pattern": "("}`,
+			`{"definitions":{"a":{"$ref":"#/definitions/a"}},"$ref":"#/definitions/a"}`,
+			`{"definitions": {"a": {"allOf": [{"$ref": "#/definitions/b"}]}, "b": {"anyOf": [{"$ref": "#/definitions/a"}]}}}`,
+		}
+
+		var accepted []string
+		for _, i := range rejected {
+			if _, err := util.ParseSchema([]byte(i)); err == nil {
+				accepted = append(accepted, i)
+			}
+		}
+
+		// Recursive references through a nested value stay valid
+		_, err := util.ParseSchema([]byte(`{"definitions": {"node": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/definitions/node"}}}}}, "$ref": "#/definitions/node"}`))
+
+		if len(accepted) > 0 || err != nil {
+			t.Errorf("Received:%v %v\nExpected:%v\n", accepted, err, "the unsupported schemas rejected")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestSendSchemaCompletion(t *testing.T) {
+	t.Run("SendSchemaCompletion", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "recipe.json")
+		os.WriteFile(path, []byte(recipeSchema), 0644)
+
+		os.Setenv("SCHEMA_PATH", path)
+		agent := controller.AttachProfile()
+		os.Unsetenv("SCHEMA_PATH")
+
+		provider := &scriptedProvider{replies: []string{
+			`Here you go: {"name": "Bread", "servings": "two"}`,
+			`{"name": "Bread", "servings": 2, "steps": ["Knead", "Bake"]}`,
+		}}
+		agent.SetProvider(provider)
+		agent.EngineProperties = *engineProperties
+		agent.PromptProperties = *promptProperties
+
+		resp, errs := prompter.SendSchemaCompletionPrompt(agent)
+
+		repaired := len(provider.requests) == 2 &&
+			strings.Contains(provider.requests[0].Messages[0].Content, "JSON Schema") &&
+			strings.Contains(provider.requests[1].Messages[len(provider.requests[1].Messages)-1].Content, "missing required property steps")
+		formatted := resp != nil && strings.HasPrefix(resp.Choices[0].Message.Content, "{\n\t\"name\": \"Bread\"")
+
+		provider.replies = []string{`{"name": "Bread"}`}
+		provider.requests = nil
+		_, failed := prompter.SendSchemaCompletionPrompt(agent)
+
+		if errs != nil || !repaired || !formatted || failed == nil || len(provider.requests) != 4 {
+			t.Errorf("Received:%v %v\nExpected:%v\n", resp, errs, "repaired structured output")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
@@ -0,0 +1,158 @@
+// Test section - Use case
+package caos
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"caos/model"
+	"caos/service"
+)
+
+func TestServerSession(t *testing.T) {
+	t.Run("ServerSession", func(t *testing.T) {
+		agent := controller.AttachProfile()
+		agent.SetProvider(&streamProvider{})
+		server := httptest.NewServer(service.NewServer(agent))
+		defer server.Close()
+
+		resp, err := http.Post(server.URL+"/sessions", "application/json", strings.NewReader(`{"engine": "gpt-3.5-turbo", "temperature": 0.3}`))
+		var session model.ServerSession
+		if err == nil {
+			json.NewDecoder(resp.Body).Decode(&session)
+			resp.Body.Close()
+		}
+
+		var answer model.PromptResponse
+		resp, err = http.Post(server.URL+"/sessions/"+session.ID+"/prompts", "application/json", strings.NewReader(`{"prompt": "What is Go?"}`))
+		if err == nil {
+			json.NewDecoder(resp.Body).Decode(&answer)
+			resp.Body.Close()
+		}
+
+		var exported model.HistoricalSession
+		resp, err = http.Get(server.URL + "/sessions/" + session.ID + "/export")
+		if err == nil {
+			json.NewDecoder(resp.Body).Decode(&exported)
+			resp.Body.Close()
+		}
+
+		missing, _ := http.Post(server.URL+"/sessions/unknown/prompts", "application/json", strings.NewReader(`{"prompt": "What is Go?"}`))
+		invalid, _ := http.Post(server.URL+"/sessions", "application/json", strings.NewReader(`{"role": "narrator"}`))
+
+		isAnswered := answer.Mode == "chat" && len(answer.Choices) == 1 && answer.Choices[0] == "gpt-3.5-turbo answer at 0.3"
+		if err != nil || session.Engine != "gpt-3.5-turbo" || !isAnswered || len(exported.Session) != 1 ||
+			missing.StatusCode != http.StatusNotFound || invalid.StatusCode != http.StatusBadRequest {
+			t.Errorf("Received:%v %v %v\nExpected:%v\n", session, answer, err, "a session answering and exporting its turn")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestServerStream(t *testing.T) {
+	t.Run("ServerStream", func(t *testing.T) {
+		agent := controller.AttachProfile()
+		agent.SetProvider(&streamProvider{})
+		server := service.NewServer(agent)
+
+		created := httptest.NewRecorder()
+		server.ServeHTTP(created, httptest.NewRequest(http.MethodPost, "/sessions", strings.NewReader(`{"engine": "gpt-3.5-turbo"}`)))
+		var session model.ServerSession
+		json.Unmarshal(created.Body.Bytes(), &session)
+
+		streamed := httptest.NewRecorder()
+		server.ServeHTTP(streamed, httptest.NewRequest(http.MethodPost, "/sessions/"+session.ID+"/prompts", strings.NewReader(`{"prompt": "Say hello", "stream": true}`)))
+		body := streamed.Body.String()
+
+		isStreamed := strings.HasPrefix(streamed.Header().Get("Content-Type"), "text/event-stream") &&
+			strings.Contains(body, "event: token\ndata: \"Hello\"\n\n") && strings.Contains(body, "event: token\ndata: \" world\"\n\n") &&
+			strings.Contains(body, "event: done\ndata: {\"session\":\""+session.ID+"\",\"mode\":\"chat\",\"choices\":[\"Hello world\"]}")
+
+		if !isStreamed {
+			t.Errorf("Received:%q\nExpected:%v\n", body, "the tokens and the done event")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestServerParallelSessions(t *testing.T) {
+	t.Run("ServerParallelSessions", func(t *testing.T) {
+		agent := controller.AttachProfile()
+		agent.SetProvider(&streamProvider{})
+		server := httptest.NewServer(service.NewServer(agent))
+		defer server.Close()
+
+		post := func(path string, body string, v interface{}) error {
+			resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			return json.NewDecoder(resp.Body).Decode(v)
+		}
+
+		// Each session embeds and then chats twice while the others do the same
+		var group sync.WaitGroup
+		answers := make([][]model.PromptResponse, 3)
+		exports := make([]model.HistoricalSession, 3)
+		for i := range answers {
+			group.Add(1)
+			go func(i int) {
+				defer group.Done()
+				// Chat sessions scrape their context, the profile engine isn't replaced by the embedding one
+				options := `{}`
+				if i%2 == 0 {
+					options = `{"engine": "gpt-3.5-turbo"}`
+				}
+				var session model.ServerSession
+				post("/sessions", options, &session)
+
+				var embedded model.PromptResponse
+				post("/sessions/"+session.ID+"/prompts", `{"prompt": "x", "mode": "embed"}`, &embedded)
+				for _, j := range []string{"What is Go?", "What else?"} {
+					var answer model.PromptResponse
+					post("/sessions/"+session.ID+"/prompts", `{"prompt": "`+j+`"}`, &answer)
+					answers[i] = append(answers[i], answer)
+				}
+
+				if resp, err := http.Get(server.URL + "/sessions/" + session.ID + "/export"); err == nil {
+					json.NewDecoder(resp.Body).Decode(&exports[i])
+					resp.Body.Close()
+				}
+			}(i)
+		}
+		group.Wait()
+
+		isAnswered := true
+		for i := range answers {
+			var turns int
+			for _, j := range exports[i].Session {
+				if len(j.Event.Body.Input) > 0 && j.Event.Body.Input[0] != "x" {
+					turns++
+				}
+			}
+			isAnswered = isAnswered && len(answers[i]) == 2 && turns == 2
+			for _, j := range answers[i] {
+				isAnswered = isAnswered && len(j.Choices) == 1 && !strings.Contains(j.Choices[0], "embedding")
+			}
+		}
+
+		if !isAnswered {
+			t.Errorf("Received:%v %v\nExpected:%v\n", answers, exports, "two chat turns in the export of each session")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
@@ -0,0 +1,136 @@
+// Test section - Use case
+package caos
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"caos/model"
+	"caos/service"
+)
+
+// newSessionDir - Temporal log directory with a branched conversation and an unrelated session
+func newSessionDir(t *testing.T) string {
+	dir := t.TempDir()
+	write := func(name string, id string, turn string, parent string, timestamp string, input string, content string) {
+		session := model.HistoricalSession{
+			ID: id,
+			Session: []model.HistoricalEvent{
+				{
+					ID:        turn,
+					ParentID:  parent,
+					Timestamp: timestamp,
+					Event: model.HistoricalPrompt{
+						Header: model.EngineProperties{Model: "gpt-3.5-turbo-16k"},
+						Body: model.PromptProperties{
+							Input:   []string{input},
+							Content: []string{content},
+						},
+					},
+				},
+			},
+		}
+
+		raw, _ := json.MarshalIndent(session, "", "\u0009")
+		os.WriteFile(filepath.Join(dir, name), raw, 0644)
+	}
+
+	write("log-1.json", "first", "first-0", "", "1689910077001", "What is caos?", "A conversational assistant")
+	write("log-2.json", "second", "second-1", "first-0", "1689910077002", "Which services?", "OpenAI services")
+	write("log-3.json", "retry", "retry-2", "first-0", "1689910077003", "Which models?", "Turbo models")
+	write("log-4.json", "other", "other-0", "", "1689910077004", "Unrelated", "Unrelated")
+	return dir
+}
+
+func TestResumeSession(t *testing.T) {
+	t.Run("ResumeSession", func(t *testing.T) {
+		dir := newSessionDir(t)
+
+		resumed := &service.Controller{}
+		err := resumed.ResumeSession(filepath.Join(dir, "log-2.json"))
+
+		agent := resumed.GetAgent()
+		messages := agent.GetMessages()
+		if err != nil || len(messages) != 4 || messages[2].Content != "Which services?" ||
+			agent.GetStatus().Engine != "gpt-3.5-turbo-16k" {
+			t.Errorf("Received:%v %v\nExpected:%v\n", messages, err, "two resumed turns")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestLoadSession(t *testing.T) {
+	t.Run("LoadSession", func(t *testing.T) {
+		dir := newSessionDir(t)
+
+		var events service.EventManager
+		err := events.LoadSession(filepath.Join(dir, "log-2.json"))
+		position, total := events.SwitchBranch(1)
+
+		pool := events.GetPool()
+		if err != nil || len(pool.Turns) != 3 || len(pool.TrainingSession) != 3 ||
+			position != 2 || total != 2 || pool.CurrentTurn != "retry-2" ||
+			events.LoadSession(filepath.Join(dir, "missing.json")) == nil {
+			t.Errorf("Received:%v %v\nExpected:%v\n", pool.Turns, err, "conversation tree without the unrelated session")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestLoadEmptySession(t *testing.T) {
+	t.Run("LoadEmptySession", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "log-empty.json")
+		os.WriteFile(path, []byte(`{"id": "empty", "session": []}`), 0644)
+
+		var events service.EventManager
+		err := events.LoadSession(path)
+
+		agent := controller.AttachProfile()
+		agent.SetProvider(&fakeProvider{})
+		_, resumed := service.NewHeadless(agent, service.HeadlessOptions{Resume: path}, io.Discard)
+
+		if err == nil || resumed == nil {
+			t.Errorf("Received:%v %v\nExpected:%v\n", err, resumed, "the empty session rejected")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestListSessions(t *testing.T) {
+	t.Run("ListSessions", func(t *testing.T) {
+		dir := newSessionDir(t)
+		os.WriteFile(filepath.Join(dir, "log-invalid.json"), []byte("{"), 0644)
+
+		sessions := service.ListSessions(dir)
+		listed := len(sessions) == 2 && sessions[0].Prompt == "Unrelated" &&
+			sessions[1].Prompt == "What is caos?" && sessions[1].Turns == 3 && len(sessions[1].Files) == 3 &&
+			filepath.Base(sessions[1].Path) == "log-3.json"
+
+		renameErr := service.RenameSession(sessions[1], "Research on caos")
+		filtered := service.FilterSessions(service.ListSessions(dir), "research")
+		renamed := len(filtered) == 1 && filtered[0].Name == "Research on caos"
+
+		deleteErr := service.DeleteSession(sessions[0])
+		deleted := len(service.ListSessions(dir)) == 1
+
+		if !listed || renameErr != nil || !renamed || deleteErr != nil || !deleted {
+			t.Errorf("Received:%v\nExpected:%v\n", sessions, "conversation and unrelated session")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
@@ -0,0 +1,76 @@
+// Test section - Use case
+package caos
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"caos/model"
+	"caos/store"
+	"caos/test/mock"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// newVectorDocument - Document embedded with the mock vectors
+func newVectorDocument(id string, text string, source string, template string, date time.Time) model.VectorDocument {
+	return model.VectorDocument{
+		ID:     id,
+		Text:   text,
+		Vector: mock.Embed(text),
+		Metadata: map[string]string{
+			store.SourceKey:   source,
+			store.TemplateKey: template,
+		},
+		Date: date,
+	}
+}
+
+func TestVectorStore(t *testing.T) {
+	t.Run("VectorStore", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "index", "vectors.json")
+		date := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+
+		vectors, openErr := store.Open(path)
+		addErr := vectors.Add(
+			newVectorDocument("deploy", "scale the kubernetes deployment replicas", "docs", "DevOps", date),
+			newVectorDocument("bread", "knead the bread dough with yeast", "recipes", "Chef", date.AddDate(0, 1, 0)),
+			newVectorDocument("pods", "restart the kubernetes pods", "log", "DevOps", date.AddDate(0, 2, 0)),
+		)
+		duplicated := vectors.Add(newVectorDocument("bread", "bread", "recipes", "Chef", date)) == store.ErrDuplicateID
+		invalid := vectors.Upsert(model.VectorDocument{ID: "empty"}) == store.ErrEmptyVector
+		upsertErr := vectors.Upsert(newVectorDocument("pods", "restart the kubernetes pods and services", "log", "DevOps", date.AddDate(0, 2, 0)))
+		saveErr := vectors.Save()
+
+		stored, _ := store.Open(path)
+		query := mock.Embed("kubernetes deployment")
+		top := stored.Search(query, 2, store.Filter{})
+		ranked := len(top) == 2 && top[0].Document.ID == "deploy" && top[1].Document.ID == "pods"
+
+		sourced := stored.Search(query, 5, store.Filter{Metadata: map[string]string{store.SourceKey: "log"}})
+		templated := stored.Search(query, 5, store.Filter{Metadata: map[string]string{store.TemplateKey: "Chef"}})
+		dated := stored.Search(query, 5, store.Filter{After: date.AddDate(0, 0, 1), Before: date.AddDate(0, 2, 0)})
+		filtered := len(sourced) == 1 && sourced[0].Document.ID == "pods" &&
+			len(templated) == 1 && templated[0].Document.ID == "bread" &&
+			len(dated) == 1 && dated[0].Document.ID == "bread"
+
+		upserted, _ := stored.Get("pods")
+		deleted := stored.Delete("bread", "missing") == 1 && stored.Len() == 2
+
+		resp := &gpt3.EmbeddingsResponse{Data: []gpt3.EmbeddingsResult{{Embedding: mock.Embed("hello"), Index: 0}}}
+		documents := store.FromEmbeddings(resp, []string{"hello"}, "text-embedding-ada-002", map[string]string{store.SourceKey: "prompt"})
+		embedded := len(documents) == 1 && documents[0].Text == "hello" &&
+			documents[0].Metadata[store.ModelKey] == "text-embedding-ada-002" &&
+			documents[0].ID == store.NewID("text-embedding-ada-002", "hello")
+
+		if openErr != nil || addErr != nil || upsertErr != nil || saveErr != nil || !duplicated || !invalid ||
+			!ranked || !filtered || upserted.Text != "restart the kubernetes pods and services" || !deleted || !embedded {
+			t.Errorf("Received:%v %v %v\nExpected:%v\n", top, sourced, dated, "ranked and filtered documents")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
@@ -0,0 +1,72 @@
+// Test section - Use case
+package caos
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"caos/model"
+	"caos/service"
+	"caos/util"
+)
+
+func TestTemplateVariables(t *testing.T) {
+	t.Run("TemplateVariables", func(t *testing.T) {
+		template := "Translate to {{language|English}} for a {{ audience }} audience, in {{language}}: "
+		variables, defaults := util.GetTemplateVariables(template)
+		filled := util.FillTemplate(template, map[string]string{"audience": "young"})
+		empty := util.FillTemplate(template, nil)
+
+		if len(variables) != 2 || variables[0] != "language" || variables[1] != "audience" || defaults["language"] != "English" ||
+			filled != "Translate to English for a young audience, in English: " ||
+			empty != "Translate to English for a  audience, in English: " {
+			t.Errorf("Received:%v %v %q\nExpected:%v\n", variables, defaults, filled, "the placeholders filled with the values or their defaults")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestTemplateFill(t *testing.T) {
+	t.Run("TemplateFill", func(t *testing.T) {
+		provider := &scriptedProvider{replies: []string{"Bonjour"}}
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+
+		var out bytes.Buffer
+		client, err := service.NewHeadless(agent, service.HeadlessOptions{
+			Engine:    "gpt-3.5-turbo",
+			Template:  "translator",
+			Variables: map[string]string{"language": "French"},
+		}, &out)
+		if err == nil {
+			err = client.Ask("Hello")
+		}
+
+		var system string
+		if len(provider.requests) == 1 && len(provider.requests[0].Messages) > 0 {
+			system = provider.requests[0].Messages[0].Content
+		}
+
+		// The filled values are recorded with the template
+		var template model.TemplateProperties
+		if err == nil {
+			exported, _ := client.Export("template", "log")
+			if events := exported.(model.HistoricalSession).Session; len(events) > 0 {
+				template = events[len(events)-1].Event.Template
+			}
+		}
+
+		if err != nil || !strings.Contains(system, "into French for a general audience") || strings.Contains(system, "{{") ||
+			template.Variables["language"] != "French" || template.Variables["audience"] != "general" {
+			t.Errorf("Received:%q %v %v\nExpected:%v\n", system, template.Variables, err, "the template filled with the variables")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
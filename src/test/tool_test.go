@@ -0,0 +1,145 @@
+// Test section - Use case
+package caos
+
+import (
+	gocontext "context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"caos/model"
+	"caos/service"
+	"caos/util"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// scriptedProvider - Backend replying with the queued chat contents
+type scriptedProvider struct {
+	fakeProvider
+	replies  []string
+	requests []gpt3.ChatCompletionRequest
+}
+
+func (c *scriptedProvider) ChatCompletion(ctx gocontext.Context, request gpt3.ChatCompletionRequest) (*gpt3.ChatCompletionResponse, error) {
+	c.requests = append(c.requests, request)
+	content := c.replies[0]
+	if len(c.replies) > 1 {
+		c.replies = c.replies[1:]
+	}
+
+	return &gpt3.ChatCompletionResponse{
+		ID: "scripted-chat",
+		Choices: []gpt3.ChatCompletionResponseChoice{
+			{Message: gpt3.ChatCompletionResponseMessage{Role: string(model.Assistant), Content: content}},
+		},
+	}, nil
+}
+
+func TestSendToolCompletion(t *testing.T) {
+	t.Run("SendToolCompletion", func(t *testing.T) {
+		provider := &scriptedProvider{replies: []string{
+			`{"tool": "calculator", "arguments": {"expression": "2 * (3 + 4)"}}`,
+			"Let me check the date first {\"tool\": \"current_time\", \"arguments\": {\"timezone\": \"UTC\"}}",
+			`{"tool": "read_file", "arguments": {"path": "../secrets.txt"}}`,
+			"The result is 14",
+		}}
+
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+		agent.EngineProperties = *engineProperties
+		agent.PromptProperties = *promptProperties
+
+		resp, calls := prompter.SendToolCompletionPrompt(agent)
+
+		declared := len(provider.requests) == 4 && strings.Contains(provider.requests[0].Messages[0].Content, "web_search")
+		called := len(calls) == 3 && calls[0].Name == "calculator" && calls[0].Result == "14" &&
+			strings.HasSuffix(calls[1].Result, "UTC") && calls[2].Error != ""
+		fed := declared && strings.Contains(provider.requests[1].Messages[len(provider.requests[1].Messages)-1].Content, "Result of calculator: 14")
+
+		if resp == nil || resp.Choices[0].Message.Content != "The result is 14" || !declared || !called || !fed {
+			t.Errorf("Received:%v %v\nExpected:%v\n", resp, calls, "The result is 14")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestSendToolCompletionLimit(t *testing.T) {
+	t.Run("SendToolCompletionLimit", func(t *testing.T) {
+		provider := &scriptedProvider{replies: []string{`{"tool": "calculator", "arguments": {"expression": "1 + 1"}}`}}
+
+		agent := controller.AttachProfile()
+		agent.SetProvider(provider)
+		agent.EngineProperties = *engineProperties
+		agent.PromptProperties = *promptProperties
+
+		resp, calls := prompter.SendToolCompletionPrompt(agent)
+
+		// The pending tool call isn't returned as the answer
+		if resp == nil || len(calls) != 6 || strings.Contains(resp.Choices[0].Message.Content, "calculator") ||
+			!strings.Contains(resp.Choices[0].Message.Content, "tool limit") {
+			t.Errorf("Received:%v %v\nExpected:%v\n", resp, len(calls), "the tool limit answer")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestRunTool(t *testing.T) {
+	t.Run("RunTool", func(t *testing.T) {
+		os.WriteFile("notes.txt", []byte("release on friday"), 0644)
+		defer os.Remove("notes.txt")
+		os.WriteFile(".env", []byte("API_KEY=secret"), 0644)
+		defer os.Remove(".env")
+		os.MkdirAll("training", 0755)
+		os.WriteFile("training/notes.txt", []byte("release on friday"), 0644)
+		defer os.RemoveAll("training")
+
+		read := service.RunTool(localAgent, model.ToolCall{Name: "read_file", Arguments: map[string]string{"path": "notes.txt"}})
+		absolute := service.RunTool(localAgent, model.ToolCall{Name: "read_file", Arguments: map[string]string{"path": "/etc/hostname"}})
+		unknown := service.RunTool(localAgent, model.ToolCall{Name: "shell"})
+		hidden := service.RunTool(localAgent, model.ToolCall{Name: "read_file", Arguments: map[string]string{"path": "./.env"}})
+		private := service.RunTool(localAgent, model.ToolCall{Name: "read_file", Arguments: map[string]string{"path": "training/../training/notes.txt"}})
+
+		power, _ := util.Evaluate("-2 ^ 2 + 10 % 4 / 0.5 + 2 ^ 3 ^ 0")
+		_, division := util.Evaluate("1 / (2 - 2)")
+		_, syntax := util.Evaluate("3 +")
+
+		if read.Result != "release on friday" || absolute.Error == "" || unknown.Error == "" ||
+			hidden.Error == "" || strings.Contains(hidden.Result, "secret") || private.Error == "" ||
+			power != 2 || division == nil || syntax == nil {
+			t.Errorf("Received:%v %v %v %v\nExpected:%v\n", read, absolute, unknown, power, "tool results")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestRunToolMultibyte(t *testing.T) {
+	t.Run("RunToolMultibyte", func(t *testing.T) {
+		os.WriteFile("accents.txt", []byte(fmt.Sprint("a", strings.Repeat("é", 2500))), 0644)
+		defer os.Remove("accents.txt")
+
+		// The cuts fall in the middle of a character
+		call := service.RunTool(localAgent, model.ToolCall{Name: "read_file", Arguments: map[string]string{"path": "accents.txt"}})
+		out := service.FormatToolCalls([]model.ToolCall{call})
+
+		if call.Error != "" || !strings.HasSuffix(call.Result, "é...") || len(call.Result) > 4003 ||
+			!utf8.ValidString(call.Result) || !utf8.ValidString(out) || !strings.HasSuffix(out, "é...") {
+			t.Errorf("Received:%v %q\nExpected:%v\n", len(call.Result), out, "results cut on a character boundary")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
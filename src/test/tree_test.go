@@ -0,0 +1,49 @@
+// Test section - Use case
+package caos
+
+import (
+	"testing"
+
+	"caos/service"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+// newChatResponse - Chat response with a single choice
+func newChatResponse(id string, content string) *gpt3.ChatCompletionResponse {
+	return &gpt3.ChatCompletionResponse{
+		ID: id,
+		Choices: []gpt3.ChatCompletionResponseChoice{
+			{Message: gpt3.ChatCompletionResponseMessage{Content: content}},
+		},
+	}
+}
+
+func TestConversationBranch(t *testing.T) {
+	t.Run("ConversationBranch", func(t *testing.T) {
+		initializeAgent()
+
+		var events service.EventManager
+		events.LogChatCompletion(localAgent.TemplateProperties, localAgent.EngineProperties, localAgent.PromptProperties, newChatResponse("first", "first answer"), nil)
+		events.LogChatCompletion(localAgent.TemplateProperties, localAgent.EngineProperties, localAgent.PromptProperties, newChatResponse("second", "second answer"), nil)
+
+		isForked := events.ForkTurn()
+		events.LogChatCompletion(localAgent.TemplateProperties, localAgent.EngineProperties, localAgent.PromptProperties, newChatResponse("retry", "retried answer"), nil)
+
+		branch := events.GetBranch()
+		retried := len(branch) == 2 && branch[1].Event.ParentID == branch[0].Event.ID && branch[1].SessionID == "retry"
+
+		position, total := events.SwitchBranch(-1)
+		training := events.GetBranchTraining()
+		switched := position == 1 && total == 2 && len(training) == 2 &&
+			training[1].ID == "second" && training[1].Session[0].Event.Completion[0] == "second answer"
+
+		if !isForked || !retried || !switched || len(events.GetPool().Turns) != 3 {
+			t.Errorf("Received:%v\nExpected:%v\n", events.GetPool().Turns, "two branches after the first turn")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
@@ -0,0 +1,110 @@
+// Test section - Use case
+package caos
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"caos/model"
+	"caos/service"
+
+	"github.com/PullRequestInc/go-gpt3"
+)
+
+func TestContextWindowFit(t *testing.T) {
+	t.Run("ContextWindowFit", func(t *testing.T) {
+		history := []gpt3.ChatCompletionRequestMessage{
+			{Role: string(model.System), Content: "I want you to act as an assistant"},
+		}
+		for i := 0; i < 10; i++ {
+			history = append(history,
+				gpt3.ChatCompletionRequestMessage{Role: string(model.User), Content: fmt.Sprint("question ", i, strings.Repeat(" filler", 40))},
+				gpt3.ChatCompletionRequestMessage{Role: string(model.Assistant), Content: fmt.Sprint("answer ", i, strings.Repeat(" filler", 40))})
+		}
+
+		context := []string{
+			strings.Repeat("unrelated words about cooking ", 60),
+			"caos is a conversational assistant for openai services",
+		}
+
+		compose := func(ctx []string) gpt3.ChatCompletionRequestMessage {
+			return gpt3.ChatCompletionRequestMessage{
+				Role:    string(model.User),
+				Content: fmt.Sprint("what is caos ", ctx),
+			}
+		}
+
+		window := service.NewContextWindow("gpt-3.5-turbo", 400)
+		messages, budget, err := window.Fit(history, "what is caos", context, compose)
+
+		last := messages[len(messages)-1].Content
+		used := budget.System + budget.History + budget.Prompt + budget.Context
+		if err != nil || messages[0].Role != string(model.System) ||
+			budget.DroppedTurns != 10 || budget.DroppedContext == 0 ||
+			!strings.Contains(last, "conversational assistant") ||
+			used+budget.Completion > budget.Limit || budget.Completion < budget.Limit/4 {
+			t.Errorf("Received:%v %v\nExpected:%v\n", budget, last, "fitted request")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func TestContextWindowOverflow(t *testing.T) {
+	t.Run("ContextWindowOverflow", func(t *testing.T) {
+		prompt := fmt.Sprint("what is caos", strings.Repeat(" filler", 200))
+		compose := func(ctx []string) gpt3.ChatCompletionRequestMessage {
+			return gpt3.ChatCompletionRequestMessage{Role: string(model.User), Content: prompt}
+		}
+
+		// Nothing is left to drop when the prompt alone exceeds the limit
+		window := service.NewContextWindow("gpt-3.5-turbo", 100)
+		messages, budget, err := window.Fit(nil, prompt, nil, compose)
+
+		t.Setenv("CONTEXT_LIMIT", "100")
+		agent := controller.AttachProfile()
+		provider := &scriptedProvider{replies: []string{"caos"}}
+		agent.SetProvider(provider)
+
+		var out bytes.Buffer
+		client, _ := service.NewHeadless(agent, service.HeadlessOptions{Engine: "gpt-3.5-turbo"}, &out)
+		askErr := client.Ask(prompt)
+
+		if err == nil || messages != nil || budget.Completion != 0 || askErr == nil || len(provider.requests) != 0 {
+			t.Errorf("Received:%v %v %v %v\nExpected:%v\n", budget, err, askErr, len(provider.requests), "the request rejected before it's sent")
+			t.Log("Test - FAILED")
+		} else {
+			t.Log("Test - PASSED")
+		}
+		t.Log("Test - FINISHED")
+	})
+}
+
+func BenchmarkContextWindowFit(t *testing.B) {
+	t.Run("ContextWindowFit", func(t *testing.B) {
+		var history []gpt3.ChatCompletionRequestMessage
+		for i := 0; i < 500; i++ {
+			history = append(history,
+				gpt3.ChatCompletionRequestMessage{Role: string(model.User), Content: fmt.Sprint("question ", i, strings.Repeat(" filler", 40))},
+				gpt3.ChatCompletionRequestMessage{Role: string(model.Assistant), Content: fmt.Sprint("answer ", i, strings.Repeat(" filler", 40))})
+		}
+
+		compose := func(ctx []string) gpt3.ChatCompletionRequestMessage {
+			return gpt3.ChatCompletionRequestMessage{Role: string(model.User), Content: fmt.Sprint("what is caos ", ctx)}
+		}
+
+		// Long conversations drop most of their turns
+		window := service.NewContextWindow("gpt-3.5-turbo", 4096)
+		for i := 0; i < t.N; i++ {
+			_, budget, err := window.Fit(history, "what is caos", nil, compose)
+			if err != nil || budget.DroppedTurns == 0 {
+				t.Fatalf("Received:%v\nExpected:%v\n", budget, "dropped turns")
+			}
+		}
+		t.Log("Test - FINISHED")
+	})
+}
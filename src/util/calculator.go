@@ -0,0 +1,146 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode"
+)
+
+// errExpression - Arithmetic expression that can't be evaluated
+var errExpression = errors.New("calculator: invalid expression")
+
+// calculator - Recursive descent parser of an arithmetic expression
+type calculator struct {
+	input    []rune
+	position int
+}
+
+// Evaluate - Result of an arithmetic expression with + - * / % ^ and parentheses
+func Evaluate(expression string) (float64, error) {
+	c := &calculator{input: []rune(expression)}
+	result, err := c.parseSum()
+	if err != nil {
+		return 0, err
+	}
+
+	c.skipSpaces()
+	if c.position < len(c.input) {
+		return 0, fmt.Errorf("%w: unexpected %q", errExpression, c.input[c.position])
+	}
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, fmt.Errorf("%w: the result is not a finite number", errExpression)
+	}
+	return result, nil
+}
+
+// skipSpaces - Move after the blank characters
+func (c *calculator) skipSpaces() {
+	for c.position < len(c.input) && unicode.IsSpace(c.input[c.position]) {
+		c.position++
+	}
+}
+
+// next - Consume the operator when it's the next character
+func (c *calculator) next(operator rune) bool {
+	c.skipSpaces()
+	if c.position < len(c.input) && c.input[c.position] == operator {
+		c.position++
+		return true
+	}
+	return false
+}
+
+// parseSum - Additions and subtractions
+func (c *calculator) parseSum() (float64, error) {
+	result, err := c.parseProduct()
+	for err == nil {
+		var value float64
+		if c.next('+') {
+			value, err = c.parseProduct()
+			result += value
+		} else if c.next('-') {
+			value, err = c.parseProduct()
+			result -= value
+		} else {
+			break
+		}
+	}
+	return result, err
+}
+
+// parseProduct - Multiplications, divisions and remainders
+func (c *calculator) parseProduct() (float64, error) {
+	result, err := c.parseUnary()
+	for err == nil {
+		var value float64
+		if c.next('*') {
+			value, err = c.parseUnary()
+			result *= value
+		} else if c.next('/') {
+			value, err = c.parseUnary()
+			if err == nil && value == 0 {
+				err = fmt.Errorf("%w: division by zero", errExpression)
+			}
+			result /= value
+		} else if c.next('%') {
+			value, err = c.parseUnary()
+			if err == nil && value == 0 {
+				err = fmt.Errorf("%w: division by zero", errExpression)
+			}
+			result = math.Mod(result, value)
+		} else {
+			break
+		}
+	}
+	return result, err
+}
+
+// parseUnary - Signed values, the sign applies after the exponentiation
+func (c *calculator) parseUnary() (float64, error) {
+	if c.next('-') {
+		value, err := c.parseUnary()
+		return -value, err
+	}
+	if c.next('+') {
+		return c.parseUnary()
+	}
+	return c.parsePower()
+}
+
+// parsePower - Right associative exponentiation
+func (c *calculator) parsePower() (float64, error) {
+	base, err := c.parseValue()
+	if err != nil || !c.next('^') {
+		return base, err
+	}
+
+	exponent, err := c.parseUnary()
+	return math.Pow(base, exponent), err
+}
+
+// parseValue - Numbers and parenthesized expressions
+func (c *calculator) parseValue() (float64, error) {
+	if c.next('(') {
+		value, err := c.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		if !c.next(')') {
+			return 0, fmt.Errorf("%w: missing closing parenthesis", errExpression)
+		}
+		return value, nil
+	}
+
+	c.skipSpaces()
+	start := c.position
+	for c.position < len(c.input) && (unicode.IsDigit(c.input[c.position]) || c.input[c.position] == '.') {
+		c.position++
+	}
+
+	if start == c.position {
+		return 0, fmt.Errorf("%w: expected a number at position %v", errExpression, start+1)
+	}
+	return strconv.ParseFloat(string(c.input[start:c.position]), 64)
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // ParseFloat32 - Parse string to float32
@@ -89,6 +90,19 @@ func RemoveWrapper(text string) string {
 	return out
 }
 
+// TruncateText - Cut the text to a maximum of bytes without splitting a character, an ellipsis marks the cut
+func TruncateText(text string, size int) string {
+	if len(text) <= size {
+		return text
+	}
+
+	end := size
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return fmt.Sprint(text[:end], "...")
+}
+
 // ConstructTsPathFileTo - Initialize a directory for further storage in a TXT or JSON file includes a timestamp
 func ConstructTsPathFileTo(path string, format string) *os.File {
 	var dir string
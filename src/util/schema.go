@@ -0,0 +1,410 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// schemaKeywords - Keywords implemented by the validator, the annotations are accepted and ignored
+var schemaKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true, "default": true, "examples": true,
+	"definitions": true, "$defs": true, "$ref": true, "type": true, "enum": true, "const": true,
+	"minLength": true, "maxLength": true, "pattern": true,
+	"minimum": true, "maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true,
+	"minItems": true, "maxItems": true, "items": true,
+	"required": true, "properties": true, "additionalProperties": true,
+	"allOf": true, "anyOf": true, "oneOf": true,
+}
+
+// Schema - JSON Schema document validating decoded JSON values
+type Schema struct {
+	root map[string]interface{}
+}
+
+// ParseSchema - Decode a JSON Schema document
+func ParseSchema(raw []byte) (*Schema, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+
+	schema := &Schema{root: root}
+	if err := schema.checkSchema(root, "#"); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// checkSchema - Reject the keywords and forms the validator doesn't implement instead of ignoring them
+func (c *Schema) checkSchema(schema map[string]interface{}, path string) error {
+	keys := make([]string, 0, len(schema))
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !schemaKeywords[key] {
+			return fmt.Errorf("schema: unsupported keyword %v at %v", key, path)
+		}
+	}
+
+	for _, key := range []string{"minLength", "maxLength", "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "minItems", "maxItems"} {
+		if value, isSet := schema[key]; isSet {
+			if _, isNumber := value.(float64); !isNumber {
+				return fmt.Errorf("schema: %v at %v must be a number", key, path)
+			}
+		}
+	}
+
+	if pattern, isSet := schema["pattern"]; isSet {
+		text, isText := pattern.(string)
+		if !isText {
+			return fmt.Errorf("schema: pattern at %v must be a string", path)
+		}
+		if _, err := regexp.Compile(text); err != nil {
+			return fmt.Errorf("schema: pattern at %v: %w", path, err)
+		}
+	}
+
+	if reference, isReference := schema["$ref"].(string); isReference {
+		if err := c.checkReference(reference, map[string]bool{}); err != nil {
+			return fmt.Errorf("%w at %v", err, path)
+		}
+	}
+
+	if items, isSet := schema["items"]; isSet {
+		nested, isSchema := items.(map[string]interface{})
+		if !isSchema {
+			return fmt.Errorf("schema: items at %v must be a schema", path)
+		}
+		if err := c.checkSchema(nested, path+"/items"); err != nil {
+			return err
+		}
+	}
+
+	if additional, isSchema := schema["additionalProperties"].(map[string]interface{}); isSchema {
+		if err := c.checkSchema(additional, path+"/additionalProperties"); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range []string{"properties", "definitions", "$defs"} {
+		nested, _ := schema[key].(map[string]interface{})
+		names := make([]string, 0, len(nested))
+		for name := range nested {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if property, isSchema := nested[name].(map[string]interface{}); isSchema {
+				if err := c.checkSchema(property, path+"/"+key+"/"+name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		options, _ := schema[key].([]interface{})
+		for i := range options {
+			if nested, isSchema := options[i].(map[string]interface{}); isSchema {
+				if err := c.checkSchema(nested, fmt.Sprint(path, "/", key, "/", i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkReference - Reject the references reaching themselves without validating a nested value
+func (c *Schema) checkReference(reference string, visiting map[string]bool) error {
+	if visiting[reference] {
+		return fmt.Errorf("schema: reference cycle through %v", reference)
+	}
+
+	// Unresolved references are reported by the validation
+	resolved, isResolved := c.resolve(reference)
+	if !isResolved {
+		return nil
+	}
+
+	visiting[reference] = true
+	defer delete(visiting, reference)
+	return c.checkLinks(resolved, visiting)
+}
+
+// checkLinks - Follow the reference and the combined schemas validating the same value
+func (c *Schema) checkLinks(schema map[string]interface{}, visiting map[string]bool) error {
+	if reference, isReference := schema["$ref"].(string); isReference {
+		return c.checkReference(reference, visiting)
+	}
+
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		options, _ := schema[key].([]interface{})
+		for _, i := range options {
+			if nested, isSchema := i.(map[string]interface{}); isSchema {
+				if err := c.checkLinks(nested, visiting); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Validate - Errors of the value against the schema, empty when it conforms
+func (c *Schema) Validate(value interface{}) []string {
+	var errs []string
+	c.validate(c.root, value, "$", &errs)
+	return errs
+}
+
+// resolve - Local reference of the schema like #/definitions/name or #/$defs/name
+func (c *Schema) resolve(reference string) (map[string]interface{}, bool) {
+	if !strings.HasPrefix(reference, "#") {
+		return nil, false
+	}
+
+	var current interface{} = c.root
+	for _, i := range strings.Split(strings.TrimPrefix(reference, "#"), "/") {
+		if i == "" {
+			continue
+		}
+
+		object, isObject := current.(map[string]interface{})
+		if !isObject {
+			return nil, false
+		}
+		current = object[strings.ReplaceAll(strings.ReplaceAll(i, "~1", "/"), "~0", "~")]
+	}
+
+	schema, isSchema := current.(map[string]interface{})
+	return schema, isSchema
+}
+
+// getType - JSON type name of a decoded value
+func getType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+// matchType - Check the value against a type name, integers are numbers too
+func matchType(value interface{}, name string) bool {
+	kind := getType(value)
+	return kind == name || (name == "number" && kind == "integer")
+}
+
+// getNumber - Numeric keyword of the schema
+func getNumber(schema map[string]interface{}, key string) (float64, bool) {
+	number, isNumber := schema[key].(float64)
+	return number, isNumber
+}
+
+// validate - Append the errors of the value at the path
+func (c *Schema) validate(schema map[string]interface{}, value interface{}, path string, errs *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, fmt.Sprint(path, ": ", fmt.Sprintf(format, args...)))
+	}
+
+	if reference, isReference := schema["$ref"].(string); isReference {
+		if resolved, isResolved := c.resolve(reference); isResolved {
+			c.validate(resolved, value, path, errs)
+		} else {
+			fail("unresolved reference %v", reference)
+		}
+		return
+	}
+
+	switch types := schema["type"].(type) {
+	case string:
+		if !matchType(value, types) {
+			fail("expected %v, received %v", types, getType(value))
+			return
+		}
+	case []interface{}:
+		var names []string
+		isMatched := false
+		for _, i := range types {
+			names = append(names, fmt.Sprint(i))
+			isMatched = isMatched || matchType(value, fmt.Sprint(i))
+		}
+		if !isMatched {
+			fail("expected %v, received %v", strings.Join(names, " or "), getType(value))
+			return
+		}
+	}
+
+	if options, isEnum := schema["enum"].([]interface{}); isEnum {
+		isListed := false
+		for _, i := range options {
+			isListed = isListed || reflect.DeepEqual(i, value)
+		}
+		if !isListed {
+			raw, _ := json.Marshal(options)
+			fail("expected one of %s", raw)
+		}
+	}
+
+	if constant, isConst := schema["const"]; isConst && !reflect.DeepEqual(constant, value) {
+		raw, _ := json.Marshal(constant)
+		fail("expected %s", raw)
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if minimum, isSet := getNumber(schema, "minLength"); isSet && length < minimum {
+			fail("expected at least %v characters", minimum)
+		}
+		if maximum, isSet := getNumber(schema, "maxLength"); isSet && length > maximum {
+			fail("expected at most %v characters", maximum)
+		}
+		if pattern, isSet := schema["pattern"].(string); isSet {
+			if rule, err := regexp.Compile(pattern); err == nil && !rule.MatchString(v) {
+				fail("expected to match %v", pattern)
+			}
+		}
+	case float64:
+		if minimum, isSet := getNumber(schema, "minimum"); isSet && v < minimum {
+			fail("expected a minimum of %v", minimum)
+		}
+		if maximum, isSet := getNumber(schema, "maximum"); isSet && v > maximum {
+			fail("expected a maximum of %v", maximum)
+		}
+		if minimum, isSet := getNumber(schema, "exclusiveMinimum"); isSet && v <= minimum {
+			fail("expected more than %v", minimum)
+		}
+		if maximum, isSet := getNumber(schema, "exclusiveMaximum"); isSet && v >= maximum {
+			fail("expected less than %v", maximum)
+		}
+	case []interface{}:
+		if minimum, isSet := getNumber(schema, "minItems"); isSet && float64(len(v)) < minimum {
+			fail("expected at least %v items", minimum)
+		}
+		if maximum, isSet := getNumber(schema, "maxItems"); isSet && float64(len(v)) > maximum {
+			fail("expected at most %v items", maximum)
+		}
+		if items, isSet := schema["items"].(map[string]interface{}); isSet {
+			for i := range v {
+				c.validate(items, v[i], fmt.Sprintf("%v[%v]", path, i), errs)
+			}
+		}
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, isSet := schema["required"].([]interface{}); isSet {
+			for _, i := range required {
+				if _, isListed := v[fmt.Sprint(i)]; !isListed {
+					fail("missing required property %v", i)
+				}
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if property, isListed := properties[key].(map[string]interface{}); isListed {
+				c.validate(property, v[key], fmt.Sprint(path, ".", key), errs)
+				continue
+			}
+
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					fail("unexpected property %v", key)
+				}
+			case map[string]interface{}:
+				c.validate(additional, v[key], fmt.Sprint(path, ".", key), errs)
+			}
+		}
+	}
+
+	if all, isSet := schema["allOf"].([]interface{}); isSet {
+		for _, i := range all {
+			if nested, isSchema := i.(map[string]interface{}); isSchema {
+				c.validate(nested, value, path, errs)
+			}
+		}
+	}
+
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		options, isSet := schema[keyword].([]interface{})
+		if !isSet {
+			continue
+		}
+
+		matches := 0
+		for _, i := range options {
+			var nestedErrs []string
+			if nested, isSchema := i.(map[string]interface{}); isSchema {
+				c.validate(nested, value, path, &nestedErrs)
+			}
+			if nestedErrs == nil {
+				matches++
+			}
+		}
+
+		if keyword == "anyOf" && matches == 0 {
+			fail("expected to match at least one of the %v schemas", len(options))
+		}
+		if keyword == "oneOf" && matches != 1 {
+			fail("expected to match exactly one of the %v schemas, matched %v", len(options), matches)
+		}
+	}
+}
+
+// ExtractJSON - JSON value of a text, markdown fences and surrounding sentences are ignored
+func ExtractJSON(text string) (interface{}, error) {
+	var value interface{}
+	trimmed := strings.TrimSpace(text)
+	if err := json.Unmarshal([]byte(trimmed), &value); err == nil {
+		return value, nil
+	}
+
+	start := strings.IndexAny(trimmed, "{[")
+	if start < 0 {
+		return nil, fmt.Errorf("schema: the output doesn't contain a JSON value")
+	}
+
+	closing := "}"
+	if trimmed[start] == '[' {
+		closing = "]"
+	}
+	end := strings.LastIndex(trimmed, closing)
+	if end < start {
+		return nil, fmt.Errorf("schema: the output doesn't contain a complete JSON value")
+	}
+
+	if err := json.Unmarshal([]byte(trimmed[start:end+1]), &value); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	return value, nil
+}
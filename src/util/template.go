@@ -0,0 +1,42 @@
+// Package util section
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// templateVariable - Template placeholder as {{name}} or {{name|default}}
+var templateVariable = regexp.MustCompile(`\{\{\s*([\w\-]+)\s*(?:\|([^}]*))?\}\}`)
+
+// GetTemplateVariables - Placeholders of the template in order of appearance with their defaults
+func GetTemplateVariables(template string) ([]string, map[string]string) {
+	var names []string
+	defaults := make(map[string]string)
+	for _, i := range templateVariable.FindAllStringSubmatch(template, -1) {
+		name, value := i[1], strings.TrimSpace(i[2])
+		if current, isListed := defaults[name]; isListed {
+			// The first default of a repeated placeholder is kept
+			if current == "" {
+				defaults[name] = value
+			}
+			continue
+		}
+
+		names = append(names, name)
+		defaults[name] = value
+	}
+	return names, defaults
+}
+
+// FillTemplate - Replace the placeholders with their values, the empty ones take the default
+func FillTemplate(template string, values map[string]string) string {
+	_, defaults := GetTemplateVariables(template)
+	return templateVariable.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := templateVariable.FindStringSubmatch(placeholder)[1]
+		if value := strings.TrimSpace(values[name]); value != "" {
+			return value
+		}
+		return defaults[name]
+	})
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/pkoukk/tiktoken-go"
 )
@@ -15,3 +16,44 @@ func EncodePromptBytePair(input []string, model string) []int {
 	}
 	return buffer
 }
+
+// encodings - Encodings built for each model, nil when the encoding is not available
+var (
+	encodings      = make(map[string]*tiktoken.Tiktoken)
+	encodingsMutex sync.Mutex
+)
+
+// getEncoding - Encoding of the model or the default one, built once for each model
+func getEncoding(model string) *tiktoken.Tiktoken {
+	encodingsMutex.Lock()
+	defer encodingsMutex.Unlock()
+
+	if enc, isCached := encodings[model]; isCached {
+		return enc
+	}
+
+	enc, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		enc, err = tiktoken.GetEncoding(tiktoken.MODEL_CL100K_BASE)
+	}
+	if err != nil {
+		enc = nil
+	}
+
+	encodings[model] = enc
+	return enc
+}
+
+// CountTokens - Amount of tokens for the model encoding, estimated when the encoding is not available
+func CountTokens(text string, model string) int {
+	enc := getEncoding(model)
+	if enc == nil {
+		return EstimateTokens(text)
+	}
+	return len(enc.Encode(text, nil, nil))
+}
+
+// EstimateTokens - Approximate amount of tokens, around four characters per token
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
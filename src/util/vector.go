@@ -0,0 +1,24 @@
+package util
+
+import (
+	"math"
+)
+
+// CosineSimilarity - Cosine of the angle between two vectors, 0 when the dimensions don't match
+func CosineSimilarity(a []float64, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}